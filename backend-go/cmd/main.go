@@ -1,15 +1,29 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
+	"os"
 	"stock-prediction-backend/internal/api"
 	"stock-prediction-backend/internal/config"
+	"stock-prediction-backend/internal/model"
+	"stock-prediction-backend/internal/service"
 )
 
 func main() {
 	// 加载配置
 	cfg := config.Load()
 
+	// 子命令：backtest 在命令行运行回测后退出
+	if len(os.Args) > 1 && os.Args[1] == "backtest" {
+		if err := runBacktestCommand(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("❌ 回测失败: %v", err)
+		}
+		return
+	}
+
 	// 创建API服务器
 	server := api.NewServer(cfg)
 
@@ -19,3 +33,43 @@ func main() {
 		log.Fatalf("❌ 服务器启动失败: %v", err)
 	}
 }
+
+// runBacktestCommand 命令行回测：backtest -index sh000001 -strategy arima_garch -from 2024-01-01
+func runBacktestCommand(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("backtest", flag.ContinueOnError)
+	var req model.BacktestRequest
+	flags.StringVar(&req.IndexCode, "index", "", "标的代码，如 sh000001")
+	flags.StringVar(&req.Strategy, "strategy", "", "预测模型: random_walk, ema_drift, linear_regression, arima_garch, mean_reversion, momentum, llm（默认 arima_garch）")
+	flags.IntVar(&req.Horizon, "horizon", service.DefaultHorizon, "预测周期（交易日数: 1, 5, 20）")
+	flags.StringVar(&req.From, "from", "", "起始日期 YYYY-MM-DD（默认截止日期前一年）")
+	flags.StringVar(&req.To, "to", "", "截止日期 YYYY-MM-DD（默认今天）")
+	flags.IntVar(&req.Window, "window", 0, "每个交易日可用的历史K线数（默认与线上预测相同）")
+	flags.Float64Var(&req.CostPercent, "cost", 0, "单边交易成本（百分比）")
+	flags.StringVar(&cfg.LLM.Mode, "llm-mode", cfg.LLM.Mode, "大模型调用模式: live, record, replay（replay 只使用录制的响应）")
+	asJSON := flags.Bool("json", false, "以 JSON 输出完整结果（包含逐日明细）")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ds := service.NewDataService(cfg)
+	result, err := ds.RunBacktest(req)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	r := result.Request
+	fmt.Printf("回测 %s [%s, %s] 策略=%s 周期=%dd 历史K线=%d 交易成本=%.2f%%\n",
+		r.IndexCode, r.From, r.To, r.Strategy, r.Horizon, r.Window, r.CostPercent)
+	fmt.Printf("样本: %d（失败 %d）\n", result.Samples, result.Skipped)
+	fmt.Printf("方向准确率: %.2f%%\n", result.DirectionAccuracy)
+	fmt.Printf("MAE: %.2f  RMSE: %.2f  MAPE: %.2f%%\n", result.MAE, result.RMSE, result.MAPE)
+	fmt.Printf("做多/空仓策略收益: %.2f%%（持有不动 %.2f%%）\n", result.TotalReturn, result.BuyHoldReturn)
+	fmt.Printf("最大回撤: %.2f%%  调仓次数: %d  持仓占比: %.2f%%\n", result.MaxDrawdown, result.Trades, result.ExposurePercent)
+	return nil
+}
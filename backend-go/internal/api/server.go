@@ -1,12 +1,17 @@
 package api
 
 import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"stock-prediction-backend/internal/calendar"
 	"stock-prediction-backend/internal/config"
 	"stock-prediction-backend/internal/model"
 	"stock-prediction-backend/internal/service"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -25,6 +30,7 @@ func NewServer(cfg *config.Config) *Server {
 		config:      cfg,
 		dataService: service.NewDataService(cfg),
 	}
+	server.dataService.Start()
 
 	server.setupRouter()
 	return server
@@ -55,6 +61,7 @@ func (s *Server) setupRouter() {
 		// 预测相关
 		v1.GET("/predict/all", s.getAllPredictions)
 		v1.GET("/predict/:index_code", s.getPrediction)
+		v1.GET("/predict/:index_code/explanation", s.getPredictionExplanation)
 
 		// 历史预测数据
 		v1.GET("/predict/history/all", s.getAllHistoricalPredictions)
@@ -63,19 +70,59 @@ func (s *Server) setupRouter() {
 		// 历史数据
 		v1.GET("/history/:index_code", s.getHistoryData)
 
+		// 技术指标时间序列
+		v1.GET("/indicators/:index_code", s.getIndicatorSeries)
+
+		// 分钟K线（交易时段内采集）
+		v1.GET("/minute-bars/:index_code", s.getMinuteBars)
+
+		// 实时报价推送（Server-Sent Events）
+		v1.GET("/quotes/stream", s.streamQuotes)
+
 		// 指数信息
 		v1.GET("/indices/all", s.getAllIndicesInfo)
 		v1.GET("/indices/:index_code", s.getIndexInfo)
 
+		// 标的注册表
+		v1.GET("/indices", s.listInstruments)
+
+		// 交易日历
+		v1.GET("/calendar", s.getTradingCalendar)
+		v1.GET("/calendar/:date", s.getTradingDayInfo)
+
+		// 证券搜索（按代码、名称或拼音首字母）
+		v1.GET("/securities/search", s.searchSecurities)
+
 		// 数据源状态
 		v1.GET("/data-source/status", s.getDataSourceStatus)
 
-		// 预测缓存管理
+		// 预测缓存状态
 		v1.GET("/prediction-cache/status", s.getPredictionCacheStatus)
-		v1.POST("/prediction-cache/refresh", s.refreshPredictionCache)
 
 		// 预测统计信息
 		v1.GET("/prediction-stats", s.getPredictionStats)
+
+		// 回测任务（异步运行，结果保存到数据库）
+		v1.GET("/backtests", s.listBacktests)
+		v1.GET("/backtests/:id", s.getBacktest)
+	}
+
+	// 管理接口：需要 Authorization: Bearer <ADMIN_TOKEN>，未配置令牌时禁用
+	admin := v1.Group("", s.adminAuthMiddleware())
+	{
+		// 标的注册表管理
+		admin.POST("/indices", s.saveInstrument)
+		admin.POST("/indices/:index_code/enable", s.enableInstrument)
+		admin.POST("/indices/:index_code/disable", s.disableInstrument)
+
+		// 更新交易日历节假日
+		admin.POST("/calendar", s.updateTradingCalendar)
+
+		// 回测任务占用较多计算资源，只允许管理员创建
+		admin.POST("/backtests", s.createBacktest)
+
+		// 刷新预测缓存会对所有标的重新调用大模型，只允许管理员触发
+		admin.POST("/prediction-cache/refresh", s.refreshPredictionCache)
 	}
 }
 
@@ -100,6 +147,38 @@ func (s *Server) corsMiddleware() gin.HandlerFunc {
 	}
 }
 
+// adminAuthMiddleware 管理接口鉴权中间件，未配置 ADMIN_TOKEN 时拒绝所有管理请求
+func (s *Server) adminAuthMiddleware() gin.HandlerFunc {
+	if s.config.AdminToken == "" {
+		log.Printf("⚠️ 未配置 ADMIN_TOKEN，管理接口已禁用")
+	}
+
+	return func(c *gin.Context) {
+		if s.config.AdminToken == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, model.APIResponse{
+				Code:      403,
+				Message:   "Admin API disabled: ADMIN_TOKEN not configured",
+				Data:      nil,
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		expected := []byte("Bearer " + s.config.AdminToken)
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), expected) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, model.APIResponse{
+				Code:      401,
+				Message:   "Unauthorized",
+				Data:      nil,
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // healthCheck 健康检查
 func (s *Server) healthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, model.APIResponse{
@@ -116,7 +195,18 @@ func (s *Server) healthCheck(c *gin.Context) {
 
 // getAllPredictions 获取所有预测数据
 func (s *Server) getAllPredictions(c *gin.Context) {
-	predictions, err := s.dataService.GetAllPredictions()
+	horizon, err := service.ParseHorizon(c.Query("horizon"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	predictions, err := s.dataService.GetAllPredictions(horizon)
 	if err != nil {
 		log.Printf("获取所有预测数据失败: %v", err)
 		c.JSON(http.StatusInternalServerError, model.APIResponse{
@@ -140,7 +230,18 @@ func (s *Server) getAllPredictions(c *gin.Context) {
 func (s *Server) getPrediction(c *gin.Context) {
 	indexCode := c.Param("index_code")
 
-	prediction, err := s.dataService.GetPredictionData(indexCode)
+	horizon, err := service.ParseHorizon(c.Query("horizon"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	prediction, err := s.dataService.GetPredictionData(indexCode, horizon)
 	if err != nil {
 		log.Printf("获取预测数据失败 %s: %v", indexCode, err)
 		c.JSON(http.StatusNotFound, model.APIResponse{
@@ -160,6 +261,51 @@ func (s *Server) getPrediction(c *gin.Context) {
 	})
 }
 
+// getPredictionExplanation 获取指定指数今日预测的依据（理由、提示词、模型、耗时和 token 用量）
+func (s *Server) getPredictionExplanation(c *gin.Context) {
+	indexCode := c.Param("index_code")
+
+	horizon, err := service.ParseHorizon(c.Query("horizon"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	explanation, err := s.dataService.GetPredictionExplanation(indexCode, horizon)
+	if err != nil {
+		log.Printf("获取预测依据失败 %s: %v", indexCode, err)
+		c.JSON(http.StatusNotFound, model.APIResponse{
+			Code:      404,
+			Message:   "Index not found",
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if explanation == nil {
+		c.JSON(http.StatusNotFound, model.APIResponse{
+			Code:      404,
+			Message:   "Explanation not found",
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:      200,
+		Message:   "success",
+		Data:      explanation,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
 // getHistoryData 获取历史数据
 func (s *Server) getHistoryData(c *gin.Context) {
 	indexCode := c.Param("index_code")
@@ -185,6 +331,156 @@ func (s *Server) getHistoryData(c *gin.Context) {
 	})
 }
 
+// getIndicatorSeries 获取技术指标时间序列，如 ?names=ma5,ma20,macd,rsi&period=6mo
+func (s *Server) getIndicatorSeries(c *gin.Context) {
+	indexCode := c.Param("index_code")
+	period := c.DefaultQuery("period", "6mo")
+
+	names, err := service.ParseIndicatorNames(c.Query("names"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err := service.ValidatePeriod(period); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	series, err := s.dataService.GetIndicatorSeries(indexCode, names, period)
+	if errors.Is(err, service.ErrInstrumentNotFound) {
+		c.JSON(http.StatusNotFound, model.APIResponse{
+			Code:      404,
+			Message:   "Index not found",
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("获取技术指标失败 %s: %v", indexCode, err)
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Code:      500,
+			Message:   "Internal server error",
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:      200,
+		Message:   "success",
+		Data:      series,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// getMinuteBars 获取分钟K线，如 ?interval=5&limit=240
+func (s *Server) getMinuteBars(c *gin.Context) {
+	indexCode := c.Param("index_code")
+
+	interval, err := service.ParseMinuteInterval(c.Query("interval"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	limit := 0 // 使用服务端默认条数
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+
+	bars, err := s.dataService.GetMinuteBars(indexCode, interval, limit)
+	if err != nil {
+		log.Printf("获取分钟K线失败 %s: %v", indexCode, err)
+		c.JSON(http.StatusNotFound, model.APIResponse{
+			Code:      404,
+			Message:   "Index not found",
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:      200,
+		Message:   "success",
+		Data:      bars,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// sseHeartbeatInterval 实时报价推送的心跳间隔，防止空闲连接被代理断开
+const sseHeartbeatInterval = 15 * time.Second
+
+// streamQuotes 通过 Server-Sent Events 推送实时报价，如 ?codes=sh000001,sz399001，为空时推送所有启用的标的
+// 连接建立后先推送当前报价快照，之后在交易时段内推送每次轮询到的报价
+func (s *Server) streamQuotes(c *gin.Context) {
+	var codes []string
+	for _, code := range strings.Split(c.Query("codes"), ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			codes = append(codes, code)
+		}
+	}
+
+	sub, snapshot, err := s.dataService.SubscribeQuotes(codes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer s.dataService.UnsubscribeQuotes(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭 nginx 缓冲
+	c.Status(http.StatusOK)
+
+	for _, quote := range snapshot {
+		c.SSEvent("quote", quote)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case quote := <-sub.Updates():
+			c.SSEvent("quote", quote)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
 // getAllIndicesInfo 获取所有指数信息
 func (s *Server) getAllIndicesInfo(c *gin.Context) {
 	indicesInfo, err := s.dataService.GetAllIndicesInfo()
@@ -245,7 +541,7 @@ func (s *Server) getDataSourceStatus(c *gin.Context) {
 
 // getPredictionCacheStatus 获取预测缓存状态
 func (s *Server) getPredictionCacheStatus(c *gin.Context) {
-	dailyPredictions, predictTime, hasPredictions := s.dataService.GetDailyPredictions()
+	dailyPredictions, predictTime, hasPredictions := s.dataService.GetDailyPredictions(service.DefaultHorizon)
 
 	status := map[string]interface{}{
 		"has_cache":       hasPredictions,
@@ -285,7 +581,46 @@ func (s *Server) refreshPredictionCache(c *gin.Context) {
 
 // getPredictionStats 获取预测统计信息
 func (s *Server) getPredictionStats(c *gin.Context) {
-	stats, err := s.dataService.GetPredictionStats()
+	indexCode := c.Query("index")
+
+	// from/to 按预测日期筛选（YYYY-MM-DD，包含两端）
+	from, err := parseDateQuery(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	to, err := parseDateQuery(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// horizon 未指定时统计所有预测周期
+	horizon := 0
+	if c.Query("horizon") != "" {
+		if horizon, err = service.ParseHorizon(c.Query("horizon")); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Code:      400,
+				Message:   err.Error(),
+				Data:      nil,
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	stats, err := s.dataService.GetPredictionStats(indexCode, horizon, from, to)
 	if err != nil {
 		log.Printf("获取预测统计信息失败: %v", err)
 		c.JSON(http.StatusInternalServerError, model.APIResponse{
@@ -314,7 +649,18 @@ func (s *Server) getAllHistoricalPredictions(c *gin.Context) {
 		}
 	}
 
-	historyData, err := s.dataService.GetAllHistoricalPredictions(days)
+	horizon, err := service.ParseHorizon(c.Query("horizon"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	historyData, err := s.dataService.GetAllHistoricalPredictions(days, horizon)
 	if err != nil {
 		log.Printf("获取所有历史预测数据失败: %v", err)
 		c.JSON(http.StatusInternalServerError, model.APIResponse{
@@ -344,7 +690,18 @@ func (s *Server) getHistoricalPredictions(c *gin.Context) {
 		}
 	}
 
-	historyData, err := s.dataService.GetHistoricalPredictions(indexCode, days)
+	horizon, err := service.ParseHorizon(c.Query("horizon"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	historyData, err := s.dataService.GetHistoricalPredictions(indexCode, days, horizon)
 	if err != nil {
 		log.Printf("获取历史预测数据失败 %s: %v", indexCode, err)
 		c.JSON(http.StatusNotFound, model.APIResponse{
@@ -363,3 +720,329 @@ func (s *Server) getHistoricalPredictions(c *gin.Context) {
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
+
+// instrumentRequest 新增或更新标的请求
+type instrumentRequest struct {
+	Code            string            `json:"code" binding:"required"`
+	Name            string            `json:"name" binding:"required"`
+	Symbol          string            `json:"symbol" binding:"required"`
+	Market          string            `json:"market"`
+	ProviderSymbols map[string]string `json:"provider_symbols"`
+	Calendar        string            `json:"calendar"`
+	Type            string            `json:"type"`     // index/stock/etf，未指定时为 index
+	Board           string            `json:"board"`    // 个股和ETF板块，未指定时按代码推导
+	LotSize         int               `json:"lot_size"` // 每手股数，未指定时为100
+	Pinyin          string            `json:"pinyin"`
+	Enabled         *bool             `json:"enabled"` // 未指定时默认启用
+}
+
+// listInstruments 列出标的注册表
+func (s *Server) listInstruments(c *gin.Context) {
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:      200,
+		Message:   "success",
+		Data:      s.dataService.ListInstruments(),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// saveInstrument 新增或更新标的
+func (s *Server) saveInstrument(c *gin.Context) {
+	var req instrumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   "Invalid request: " + err.Error(),
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	instrument, err := s.dataService.SaveInstrument(model.Instrument{
+		Code:            req.Code,
+		Name:            req.Name,
+		Symbol:          req.Symbol,
+		Market:          req.Market,
+		ProviderSymbols: req.ProviderSymbols,
+		Calendar:        req.Calendar,
+		Type:            req.Type,
+		Board:           req.Board,
+		LotSize:         req.LotSize,
+		Pinyin:          req.Pinyin,
+		Enabled:         enabled,
+	})
+	if err != nil {
+		log.Printf("保存标的失败 %s: %v", req.Code, err)
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:      200,
+		Message:   "success",
+		Data:      instrument,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// enableInstrument 启用标的
+func (s *Server) enableInstrument(c *gin.Context) {
+	s.setInstrumentEnabled(c, true)
+}
+
+// disableInstrument 停用标的
+func (s *Server) disableInstrument(c *gin.Context) {
+	s.setInstrumentEnabled(c, false)
+}
+
+// setInstrumentEnabled 启用或停用标的
+func (s *Server) setInstrumentEnabled(c *gin.Context, enabled bool) {
+	indexCode := c.Param("index_code")
+
+	instrument, err := s.dataService.SetInstrumentEnabled(indexCode, enabled)
+	if err != nil {
+		log.Printf("更新标的状态失败 %s: %v", indexCode, err)
+		c.JSON(http.StatusNotFound, model.APIResponse{
+			Code:      404,
+			Message:   "Index not found",
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:      200,
+		Message:   "success",
+		Data:      instrument,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// searchSecurities 按代码、名称或拼音首字母搜索证券
+func (s *Server) searchSecurities(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   "Missing query parameter: q",
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	matches, err := s.dataService.SearchSecurities(query)
+	if err != nil {
+		log.Printf("搜索证券失败 %s: %v", query, err)
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Code:      500,
+			Message:   "Failed to search securities",
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:      200,
+		Message:   "success",
+		Data:      matches,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// getTradingCalendar 获取当前的节假日数据
+func (s *Server) getTradingCalendar(c *gin.Context) {
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:      200,
+		Message:   "success",
+		Data:      s.dataService.GetTradingCalendar(),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// getTradingDayInfo 查询指定日期是否为交易日及前后交易日
+func (s *Server) getTradingDayInfo(c *gin.Context) {
+	date, err := time.Parse("2006-01-02", c.Param("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   "Invalid date, expected YYYY-MM-DD",
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:      200,
+		Message:   "success",
+		Data:      s.dataService.GetTradingDayInfo(date),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// updateTradingCalendar 更新节假日数据
+func (s *Server) updateTradingCalendar(c *gin.Context) {
+	var req calendar.HolidayFile
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   "Invalid request: " + err.Error(),
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err := s.dataService.UpdateTradingCalendar(req); err != nil {
+		log.Printf("更新交易日历失败: %v", err)
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:      200,
+		Message:   "success",
+		Data:      s.dataService.GetTradingCalendar(),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// parseDateQuery 解析日期查询参数（YYYY-MM-DD），未提供时返回零值
+func parseDateQuery(c *gin.Context, name string) (time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+
+	date, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid %s date, expected YYYY-MM-DD", name)
+	}
+	return date, nil
+}
+
+// createBacktest 创建回测任务，后台运行后通过 GET /backtests/:id 查询结果
+func (s *Server) createBacktest(c *gin.Context) {
+	var req model.BacktestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   "Invalid request: " + err.Error(),
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	job, err := s.dataService.StartBacktest(req)
+	if errors.Is(err, service.ErrBacktestQueueFull) {
+		c.JSON(http.StatusTooManyRequests, model.APIResponse{
+			Code:      429,
+			Message:   "Backtest queue is full, please retry later",
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("创建回测任务失败: %v", err)
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, model.APIResponse{
+		Code:      202,
+		Message:   "Backtest started",
+		Data:      job,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// listBacktests 获取最近的回测任务（不含逐日结果）
+func (s *Server) listBacktests(c *gin.Context) {
+	jobs, err := s.dataService.ListBacktests()
+	if err != nil {
+		log.Printf("获取回测任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Code:      500,
+			Message:   "Internal server error",
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:      200,
+		Message:   "success",
+		Data:      jobs,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// getBacktest 获取回测任务状态和结果
+func (s *Server) getBacktest(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:      400,
+			Message:   "Invalid backtest id",
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	job, err := s.dataService.GetBacktest(uint(id))
+	if err != nil {
+		log.Printf("获取回测任务失败 %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Code:      500,
+			Message:   "Internal server error",
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if job == nil {
+		c.JSON(http.StatusNotFound, model.APIResponse{
+			Code:      404,
+			Message:   "Backtest not found",
+			Data:      nil,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:      200,
+		Message:   "success",
+		Data:      job,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"stock-prediction-backend/internal/config"
+)
+
+func TestAdminRoutesRequireToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		adminToken    string
+		authorization string
+		wantStatus    int
+	}{
+		{"未配置令牌时禁用", "", "Bearer anything", http.StatusForbidden},
+		{"缺少令牌", "secret", "", http.StatusUnauthorized},
+		{"令牌错误", "secret", "Bearer wrong", http.StatusUnauthorized},
+	}
+
+	routes := []string{"/api/v1/backtests", "/api/v1/indices", "/api/v1/calendar", "/api/v1/prediction-cache/refresh"}
+	for _, tt := range tests {
+		server := &Server{config: &config.Config{AdminToken: tt.adminToken}}
+		server.setupRouter()
+
+		for _, route := range routes {
+			t.Run(tt.name+" "+route, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodPost, route, strings.NewReader(`{}`))
+				req.Header.Set("Content-Type", "application/json")
+				if tt.authorization != "" {
+					req.Header.Set("Authorization", tt.authorization)
+				}
+
+				recorder := httptest.NewRecorder()
+				server.router.ServeHTTP(recorder, req)
+				if recorder.Code != tt.wantStatus {
+					t.Errorf("状态码 = %d，期望 %d", recorder.Code, tt.wantStatus)
+				}
+			})
+		}
+	}
+}
+
+func TestGetIndicatorSeriesInvalidQuery(t *testing.T) {
+	server := &Server{config: &config.Config{}}
+	server.setupRouter()
+
+	for _, query := range []string{"period=2w", "period=", "names=foo"} {
+		t.Run(query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/indicators/sh000001?"+query, nil)
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, req)
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("状态码 = %d，期望 %d", recorder.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
@@ -0,0 +1,423 @@
+package calendar
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// dateLayout 节假日文件中的日期格式
+const dateLayout = "2006-01-02"
+
+// maxSearchDays 查找上一个/下一个交易日时最多跨越的自然日数
+const maxSearchDays = 366
+
+// 收盘时间（上海时区），收盘后当天行情视为已确定
+const (
+	closeHour   = 15
+	closeMinute = 0
+)
+
+// tradingSession 连续竞价时段（上海时区，自零点起的分钟数，左闭右开）
+type tradingSession struct {
+	Start int
+	End   int
+}
+
+// tradingSessions 上午 9:30-11:30，下午 13:00-15:00
+var tradingSessions = []tradingSession{
+	{Start: 9*60 + 30, End: 11*60 + 30},
+	{Start: 13 * 60, End: closeHour*60 + closeMinute},
+}
+
+// 市场状态
+const (
+	MarketPreOpen     = "pre_open"     // 交易日开盘集合竞价之前
+	MarketCallAuction = "call_auction" // 开盘集合竞价（9:15-9:30）或收盘集合竞价（14:57-15:00）
+	MarketContinuous  = "continuous"   // 连续竞价
+	MarketLunchBreak  = "lunch_break"  // 午间休市
+	MarketClosed      = "closed"       // 交易日收盘后
+	MarketHoliday     = "holiday"      // 非交易日（周末或节假日）
+)
+
+// 集合竞价时间（上海时区，自零点起的分钟数）
+const (
+	openingAuctionStart = 9*60 + 15
+	closingAuctionStart = 14*60 + 57
+)
+
+// defaultHolidaysJSON 内置的默认节假日文件
+//
+//go:embed data/holidays.json
+var defaultHolidaysJSON []byte
+
+// HolidayFile 交易所节假日文件格式
+type HolidayFile struct {
+	Exchange       string   `json:"exchange"`
+	Years          []int    `json:"years"`           // 文件覆盖的年份，之外的日期仅按周末判断
+	Holidays       []string `json:"holidays"`        // 休市日期（YYYY-MM-DD）
+	MakeupWorkdays []string `json:"makeup_workdays"` // 调休上班日（周末），交易所仍然休市
+}
+
+// TradingCalendar A股交易日历
+// 交易日 = 周一至周五且不在休市日期中；调休上班的周末交易所不开市
+type TradingCalendar struct {
+	mu             sync.RWMutex
+	path           string
+	location       *time.Location
+	exchange       string
+	years          map[int]bool
+	holidays       map[string]bool
+	makeupWorkdays map[string]bool
+}
+
+// NewTradingCalendar 创建交易日历，优先读取 path 指定的文件，不存在时使用内置节假日数据
+func NewTradingCalendar(path string) (*TradingCalendar, error) {
+	location, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		location = time.FixedZone("CST", 8*3600)
+	}
+
+	c := &TradingCalendar{
+		path:     path,
+		location: location,
+	}
+
+	content := defaultHolidaysJSON
+	if path != "" {
+		fileContent, err := os.ReadFile(path)
+		if err == nil {
+			content = fileContent
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("读取交易日历文件失败: %v", err)
+		} else {
+			log.Printf("⚠️ 交易日历文件不存在: %s，使用内置节假日数据", path)
+		}
+	}
+
+	var file HolidayFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("解析交易日历失败: %v", err)
+	}
+
+	if err := c.load(file); err != nil {
+		return nil, err
+	}
+
+	log.Printf("📅 加载交易日历: %s, 覆盖年份 %v, 休市 %d 天", c.exchange, file.Years, len(c.holidays))
+	return c, nil
+}
+
+// load 校验并加载节假日数据
+func (c *TradingCalendar) load(file HolidayFile) error {
+	if len(file.Years) == 0 {
+		return fmt.Errorf("交易日历未指定覆盖年份")
+	}
+
+	years := make(map[int]bool)
+	for _, year := range file.Years {
+		years[year] = true
+	}
+
+	holidays, err := parseDates(file.Holidays, years)
+	if err != nil {
+		return fmt.Errorf("休市日期无效: %v", err)
+	}
+
+	makeupWorkdays, err := parseDates(file.MakeupWorkdays, years)
+	if err != nil {
+		return fmt.Errorf("调休日期无效: %v", err)
+	}
+
+	for key := range makeupWorkdays {
+		if holidays[key] {
+			return fmt.Errorf("日期 %s 不能同时是休市日和调休上班日", key)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.exchange = file.Exchange
+	c.years = years
+	c.holidays = holidays
+	c.makeupWorkdays = makeupWorkdays
+	return nil
+}
+
+// parseDates 解析日期列表，日期必须位于覆盖年份内
+func parseDates(values []string, years map[int]bool) (map[string]bool, error) {
+	dates := make(map[string]bool)
+	for _, value := range values {
+		date, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", value, err)
+		}
+		if !years[date.Year()] {
+			return nil, fmt.Errorf("%s 不在覆盖年份内", value)
+		}
+		dates[value] = true
+	}
+	return dates, nil
+}
+
+// Update 按年份更新节假日数据：替换 file.Years 中各年份的数据，保留其他年份，
+// 并在配置了文件路径时写回文件
+func (c *TradingCalendar) Update(file HolidayFile) error {
+	if len(file.Years) == 0 {
+		return fmt.Errorf("交易日历未指定覆盖年份")
+	}
+
+	replaced := make(map[int]bool)
+	for _, year := range file.Years {
+		replaced[year] = true
+	}
+
+	current := c.Snapshot()
+	merged := HolidayFile{
+		Exchange:       file.Exchange,
+		Years:          append([]int{}, file.Years...),
+		Holidays:       append([]string{}, file.Holidays...),
+		MakeupWorkdays: append([]string{}, file.MakeupWorkdays...),
+	}
+	if merged.Exchange == "" {
+		merged.Exchange = current.Exchange
+	}
+	for _, year := range current.Years {
+		if !replaced[year] {
+			merged.Years = append(merged.Years, year)
+		}
+	}
+	merged.Holidays = append(merged.Holidays, keepOtherYears(current.Holidays, replaced)...)
+	merged.MakeupWorkdays = append(merged.MakeupWorkdays, keepOtherYears(current.MakeupWorkdays, replaced)...)
+
+	if err := c.load(merged); err != nil {
+		return err
+	}
+
+	if c.path != "" {
+		content, err := json.MarshalIndent(c.Snapshot(), "", "  ")
+		if err != nil {
+			return fmt.Errorf("序列化交易日历失败: %v", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+			log.Printf("⚠️ 创建交易日历目录失败，更新仅在本次运行期间生效: %v", err)
+		} else if err := os.WriteFile(c.path, content, 0644); err != nil {
+			log.Printf("⚠️ 写入交易日历文件失败，更新仅在本次运行期间生效: %v", err)
+		}
+	}
+
+	log.Printf("📅 交易日历已更新: 年份 %v, 休市 %d 天", file.Years, len(file.Holidays))
+	return nil
+}
+
+// keepOtherYears 保留不在 replaced 年份内的日期
+func keepOtherYears(dates []string, replaced map[int]bool) []string {
+	var kept []string
+	for _, value := range dates {
+		if date, err := time.Parse(dateLayout, value); err == nil && !replaced[date.Year()] {
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
+
+// Snapshot 导出当前节假日数据
+func (c *TradingCalendar) Snapshot() HolidayFile {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	file := HolidayFile{
+		Exchange:       c.exchange,
+		Years:          make([]int, 0, len(c.years)),
+		Holidays:       sortedKeys(c.holidays),
+		MakeupWorkdays: sortedKeys(c.makeupWorkdays),
+	}
+	for year := range c.years {
+		file.Years = append(file.Years, year)
+	}
+	sort.Ints(file.Years)
+	return file
+}
+
+// sortedKeys 按日期排序返回集合中的日期
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Location 交易所所在时区（上海）
+func (c *TradingCalendar) Location() *time.Location {
+	return c.location
+}
+
+// dateKey 按上海时区取日期
+func (c *TradingCalendar) dateKey(t time.Time) string {
+	return t.In(c.location).Format(dateLayout)
+}
+
+// Covers 节假日数据是否覆盖该日期所在年份
+func (c *TradingCalendar) Covers(t time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.years[t.In(c.location).Year()]
+}
+
+// IsHoliday 是否为交易所节假日休市
+func (c *TradingCalendar) IsHoliday(t time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.holidays[c.dateKey(t)]
+}
+
+// IsMakeupWorkday 是否为调休上班日（周末上班，但交易所休市）
+func (c *TradingCalendar) IsMakeupWorkday(t time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.makeupWorkdays[c.dateKey(t)]
+}
+
+// IsTradingDay 是否为交易日
+func (c *TradingCalendar) IsTradingDay(t time.Time) bool {
+	weekday := t.In(c.location).Weekday()
+	if weekday == time.Saturday || weekday == time.Sunday {
+		return false
+	}
+	return !c.IsHoliday(t)
+}
+
+// NextTradingDay 获取 t 之后的下一个交易日（保留 t 的时分秒）
+func (c *TradingCalendar) NextTradingDay(t time.Time) time.Time {
+	return c.step(t, 1)
+}
+
+// PrevTradingDay 获取 t 之前的上一个交易日（保留 t 的时分秒）
+func (c *TradingCalendar) PrevTradingDay(t time.Time) time.Time {
+	return c.step(t, -1)
+}
+
+// step 按自然日向前或向后查找交易日
+func (c *TradingCalendar) step(t time.Time, direction int) time.Time {
+	day := t
+	for i := 0; i < maxSearchDays; i++ {
+		day = day.AddDate(0, 0, direction)
+		if c.IsTradingDay(day) {
+			return day
+		}
+	}
+	return day
+}
+
+// AddTradingDays 向后（n>0）或向前（n<0）移动 n 个交易日
+func (c *TradingCalendar) AddTradingDays(t time.Time, n int) time.Time {
+	day := t
+	for ; n > 0; n-- {
+		day = c.NextTradingDay(day)
+	}
+	for ; n < 0; n++ {
+		day = c.PrevTradingDay(day)
+	}
+	return day
+}
+
+// TradingDaysBetween 获取 [from, to] 区间内的交易日（按日期，含两端）
+func (c *TradingCalendar) TradingDaysBetween(from, to time.Time) []time.Time {
+	var days []time.Time
+	for day := from; c.dateKey(day) <= c.dateKey(to); day = day.AddDate(0, 0, 1) {
+		if c.IsTradingDay(day) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// LastClosedTradingDay 获取最近一个已收盘的交易日（上海时区零点）
+func (c *TradingCalendar) LastClosedTradingDay(now time.Time) time.Time {
+	local := now.In(c.location)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.location)
+
+	closed := local.Hour() > closeHour || (local.Hour() == closeHour && local.Minute() >= closeMinute)
+	if c.IsTradingDay(today) && closed {
+		return today
+	}
+	return c.PrevTradingDay(today)
+}
+
+// InSession 是否处于交易日的连续竞价时段内
+func (c *TradingCalendar) InSession(t time.Time) bool {
+	_, ok := c.SessionStart(t)
+	return ok
+}
+
+// NextSessionStart 获取 t 之后（不含 t 所在时段）下一个交易时段的开始时间
+func (c *TradingCalendar) NextSessionStart(t time.Time) time.Time {
+	local := t.In(c.location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.location)
+
+	for i := 0; i < maxSearchDays; i++ {
+		if c.IsTradingDay(day) {
+			for _, session := range tradingSessions {
+				start := day.Add(time.Duration(session.Start) * time.Minute)
+				if start.After(t) {
+					return start
+				}
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// SessionStart 获取 t 所在交易时段的开始时间，不在交易时段内时返回 false
+func (c *TradingCalendar) SessionStart(t time.Time) (time.Time, bool) {
+	if !c.IsTradingDay(t) {
+		return time.Time{}, false
+	}
+
+	local := t.In(c.location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.location)
+	minute := local.Hour()*60 + local.Minute()
+	for _, session := range tradingSessions {
+		if minute >= session.Start && minute < session.End {
+			return day.Add(time.Duration(session.Start) * time.Minute), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// MarketState 获取 t 时刻的市场状态
+func (c *TradingCalendar) MarketState(t time.Time) string {
+	if !c.IsTradingDay(t) {
+		return MarketHoliday
+	}
+
+	local := t.In(c.location)
+	minute := local.Hour()*60 + local.Minute()
+	morning, afternoon := tradingSessions[0], tradingSessions[1]
+	switch {
+	case minute < openingAuctionStart:
+		return MarketPreOpen
+	case minute < morning.Start:
+		return MarketCallAuction
+	case minute < morning.End:
+		return MarketContinuous
+	case minute < afternoon.Start:
+		return MarketLunchBreak
+	case minute < closingAuctionStart:
+		return MarketContinuous
+	case minute < afternoon.End:
+		return MarketCallAuction
+	default:
+		return MarketClosed
+	}
+}
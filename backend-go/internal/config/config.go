@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -11,11 +12,17 @@ import (
 
 // Config 应用配置
 type Config struct {
-	Port     string
-	LogLevel string
-	Cache    CacheConfig
-	API      APIConfig
-	Database DatabaseConfig
+	Port            string
+	LogLevel        string
+	AdminToken      string // 管理接口令牌，为空时禁用管理接口
+	InstrumentsFile string // 标的注册表文件（JSON），不存在时使用内置默认值
+	CalendarFile    string // 交易日历节假日文件（JSON），不存在时使用内置默认值
+	Cache           CacheConfig
+	API             APIConfig
+	Database        DatabaseConfig
+	MarketData      MarketDataConfig
+	Prediction      PredictionConfig
+	LLM             LLMConfig
 }
 
 // CacheConfig 缓存配置
@@ -28,6 +35,71 @@ type APIConfig struct {
 	Timeout time.Duration
 }
 
+// MarketDataConfig 行情数据源配置
+type MarketDataConfig struct {
+	Providers   []string      // 数据源优先级顺序，如 tencent,sina
+	QuoteMaxAge time.Duration // 报价时间超过该时长视为过期
+	BarsMaxAge  time.Duration // 最后一根日K线超过该时长视为过期
+
+	SessionQuoteMaxAge time.Duration // 交易时段内报价时间落后超过该时长视为过期，0 表示不检查
+
+	HealthProbeInterval time.Duration // 数据源健康探测间隔，0 表示禁用
+
+	StreamInterval     time.Duration // 交易时段内实时报价推送的轮询间隔，0 表示禁用
+	MinuteBarIntervals []string      // 需要保存的分钟K线周期（分钟），如 1,5,15,60
+	MinuteBarRefresh   time.Duration // 交易时段内分钟K线的采集间隔，0 表示禁用
+}
+
+// PredictionConfig 预测模型配置
+type PredictionConfig struct {
+	Models []string // 预测模型优先级顺序，前面的模型失败时使用后面的模型，如 ensemble,random_walk
+
+	EnsembleMembers      []string // 集成预测的成员模型
+	EnsembleWeightWindow int      // 计算成员权重使用的最近已验证预测数
+	EnsembleDivergence   float64  // 大模型相对统计模型的偏离阈值（单日百分比，多日按平方根放大）
+}
+
+// LLMConfig 大模型调用配置
+type LLMConfig struct {
+	Mode      string // live: 直接调用; record: 调用并录制响应; replay: 只使用录制的响应
+	RecordDir string // 录制文件目录
+
+	Profiles       []LLMProfileConfig // 可用的大模型接入配置
+	DefaultProfile string             // 默认使用的配置名称
+	IndexProfiles  map[string]string  // 按标的代码指定使用的配置，如 sh000300=qwen
+
+	Required           bool          // 没有可用的大模型配置时是否拒绝启动，否则禁用大模型预测
+	KeyRefreshInterval time.Duration // 检查 API Key 文件变化的间隔，0 表示不检查
+	MaxAttempts        int           // 回复不符合格式或超出合理范围时，连同纠正提示最多请求的次数
+
+	PromptsDir     string   // 提示词模板目录（<名称>/<版本>.tmpl），不存在时只使用内置模板
+	PromptVariants []string // 参与 A/B 实验的提示词模板及权重，如 analysis/v1:50,analysis/v2:50
+	PromptSplit    string   // A/B 分组方式: index 按标的; day 按交易日
+}
+
+// LLMProfileConfig 单个 OpenAI 兼容大模型的接入配置，环境变量前缀为 LLM_<名称>_
+type LLMProfileConfig struct {
+	Name        string
+	BaseURL     string
+	Model       string
+	APIKey      string // 来自环境变量 LLM_<名称>_API_KEY
+	APIKeyFile  string // 来自 LLM_<名称>_API_KEY_FILE 或 Docker secrets /run/secrets/llm_<名称>_api_key，优先于 APIKey
+	Keyless     bool   // 接口不需要 API Key（如本地 vLLM、mock 服务）
+	Temperature float64
+	MaxTokens   int
+	Timeout     time.Duration
+	Headers     map[string]string
+
+	ResponseFormat string // 结构化输出模式: json_object（默认）、json_schema、none
+}
+
+// llmProfileDefaults 常见厂商的默认接口地址和模型，其他名称需要配置 LLM_<名称>_BASE_URL 和 LLM_<名称>_MODEL
+var llmProfileDefaults = map[string]struct{ baseURL, model string }{
+	"deepseek": {"https://api.deepseek.com", "deepseek-chat"},
+	"qwen":     {"https://dashscope.aliyuncs.com/compatible-mode/v1", "qwen-plus"},
+	"moonshot": {"https://api.moonshot.cn/v1", "moonshot-v1-8k"},
+}
+
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
 	Host     string
@@ -47,6 +119,10 @@ func Load() *Config {
 	config := &Config{
 		Port:     getEnv("PORT", "8000"),
 		LogLevel: getEnv("LOG_LEVEL", "info"),
+
+		AdminToken:      getEnv("ADMIN_TOKEN", ""),
+		InstrumentsFile: getEnv("INSTRUMENTS_FILE", "configs/instruments.json"),
+		CalendarFile:    getEnv("TRADING_CALENDAR_FILE", "configs/trading_calendar.json"),
 		Cache: CacheConfig{
 			Duration: getDurationEnv("CACHE_DURATION", 5*time.Minute),
 		},
@@ -61,11 +137,89 @@ func Load() *Config {
 			DBName:   getEnv("DB_NAME", "stock_prediction"),
 			Charset:  getEnv("DB_CHARSET", "utf8mb4"),
 		},
+		MarketData: MarketDataConfig{
+			Providers:   getListEnv("MARKET_DATA_PROVIDERS", []string{"tencent", "sina"}),
+			QuoteMaxAge: getDurationEnv("MARKET_QUOTE_MAX_AGE", 96*time.Hour),
+			BarsMaxAge:  getDurationEnv("MARKET_BARS_MAX_AGE", 240*time.Hour),
+
+			SessionQuoteMaxAge: getDurationEnv("MARKET_SESSION_QUOTE_MAX_AGE", 2*time.Minute),
+
+			HealthProbeInterval: getDurationEnv("MARKET_HEALTH_PROBE_INTERVAL", time.Minute),
+
+			StreamInterval:     getDurationEnv("QUOTE_STREAM_INTERVAL", 3*time.Second),
+			MinuteBarIntervals: getListEnv("MINUTE_BAR_INTERVALS", []string{"1", "5", "15", "60"}),
+			MinuteBarRefresh:   getDurationEnv("MINUTE_BAR_REFRESH_INTERVAL", time.Minute),
+		},
+		LLM: LLMConfig{
+			Mode:      getEnv("LLM_MODE", "live"),
+			RecordDir: getEnv("LLM_RECORD_DIR", "testdata/llm_recordings"),
+
+			Profiles:       loadLLMProfiles(getListEnv("LLM_PROFILES", []string{"deepseek"})),
+			DefaultProfile: strings.ToLower(getEnv("LLM_DEFAULT_PROFILE", "deepseek")),
+			IndexProfiles:  getMapEnv("LLM_INDEX_PROFILES", map[string]string{}),
+
+			Required:           getBoolEnv("LLM_REQUIRED", false),
+			KeyRefreshInterval: getDurationEnv("LLM_KEY_REFRESH_INTERVAL", 30*time.Second),
+			MaxAttempts:        getIntEnv("LLM_MAX_ATTEMPTS", 3),
+
+			PromptsDir:     getEnv("PROMPTS_DIR", "configs/prompts"),
+			PromptVariants: getListEnv("PROMPT_VARIANTS", []string{"analysis/v1"}),
+			PromptSplit:    getEnv("PROMPT_AB_SPLIT", "index"),
+		},
+		Prediction: PredictionConfig{
+			Models: getListEnv("PREDICTION_MODELS", []string{"ensemble", "random_walk"}),
+
+			EnsembleMembers:      getListEnv("ENSEMBLE_MEMBERS", []string{"deepseek", "ema_drift", "mean_reversion", "momentum"}),
+			EnsembleWeightWindow: getIntEnv("ENSEMBLE_WEIGHT_WINDOW", 20),
+			EnsembleDivergence:   getFloatEnv("ENSEMBLE_DIVERGENCE_PERCENT", 2.0),
+		},
 	}
 
 	return config
 }
 
+// loadLLMProfiles 按名称加载大模型接入配置
+func loadLLMProfiles(names []string) []LLMProfileConfig {
+	profiles := make([]LLMProfileConfig, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(name)
+		prefix := "LLM_" + strings.ToUpper(name) + "_"
+		defaults := llmProfileDefaults[name]
+
+		profile := LLMProfileConfig{
+			Name:        name,
+			BaseURL:     getEnv(prefix+"BASE_URL", defaults.baseURL),
+			Model:       getEnv(prefix+"MODEL", defaults.model),
+			APIKey:      getEnv(prefix+"API_KEY", ""),
+			APIKeyFile:  getEnv(prefix+"API_KEY_FILE", dockerSecretFile("llm_"+name+"_api_key")),
+			Keyless:     getBoolEnv(prefix+"KEYLESS", false),
+			Temperature: getFloatEnv(prefix+"TEMPERATURE", 0.3),
+			MaxTokens:   getIntEnv(prefix+"MAX_TOKENS", 1000),
+			Timeout:     getDurationEnv(prefix+"TIMEOUT", 60*time.Second),
+			Headers:     getMapEnv(prefix+"HEADERS", nil),
+
+			ResponseFormat: strings.ToLower(getEnv(prefix+"RESPONSE_FORMAT", "json_object")),
+		}
+		// 兼容旧版本的 DEEPSEEK_API_URL（完整的 chat completions 地址）和 DEEPSEEK_API_KEY
+		if name == "deepseek" {
+			profile.BaseURL = getEnv(prefix+"BASE_URL", getEnv("DEEPSEEK_API_URL", defaults.baseURL))
+			profile.APIKey = getEnv(prefix+"API_KEY", getEnv("DEEPSEEK_API_KEY", ""))
+		}
+
+		profiles = append(profiles, profile)
+	}
+	return profiles
+}
+
+// dockerSecretFile Docker secrets 挂载的文件路径，文件不存在时返回空字符串
+func dockerSecretFile(name string) string {
+	path := "/run/secrets/" + name
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
 // getEnv 获取环境变量，如果不存在则返回默认值
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -84,6 +238,39 @@ func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	return defaultValue
 }
 
+// getListEnv 获取逗号分隔的列表环境变量
+func getListEnv(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		if len(list) > 0 {
+			return list
+		}
+	}
+	return defaultValue
+}
+
+// getMapEnv 获取逗号分隔的 key=value 环境变量，如 sh000300=qwen,sz399006=moonshot
+func getMapEnv(key string, defaultValue map[string]string) map[string]string {
+	if value := os.Getenv(key); value != "" {
+		result := make(map[string]string)
+		for _, item := range strings.Split(value, ",") {
+			k, v, found := strings.Cut(item, "=")
+			if k, v = strings.TrimSpace(k), strings.TrimSpace(v); found && k != "" {
+				result[k] = v
+			}
+		}
+		if len(result) > 0 {
+			return result
+		}
+	}
+	return defaultValue
+}
+
 // getIntEnv 获取整数环境变量
 func getIntEnv(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
@@ -94,6 +281,26 @@ func getIntEnv(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getBoolEnv 获取布尔环境变量
+func getBoolEnv(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// getFloatEnv 获取浮点数环境变量
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 // GetDSN 获取数据库连接字符串
 func (db *DatabaseConfig) GetDSN() string {
 	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=True&loc=Local",
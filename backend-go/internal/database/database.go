@@ -11,6 +11,7 @@ import (
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
@@ -57,16 +58,58 @@ func NewDatabaseService(cfg *config.Config) (*DatabaseService, error) {
 
 // initTables 初始化数据库表
 func (ds *DatabaseService) initTables() error {
+	// 创建数据迁移记录表（部分数据迁移需要在表结构变更之前执行）
+	if err := ds.db.AutoMigrate(&model.SchemaMigration{}); err != nil {
+		return fmt.Errorf("创建数据迁移记录表失败: %v", err)
+	}
+
+	// 同一交易日同一周期的预测建立唯一索引之前，删除并发保存产生的重复记录，只保留最新一条
+	if ds.db.Migrator().HasColumn(&model.PredictionRecord{}, "TradeDate") {
+		if err := ds.migrateOnce("prediction_records_dedupe", ds.dedupePredictions); err != nil {
+			return err
+		}
+	}
+
 	// 创建预测记录表
 	if err := ds.db.AutoMigrate(&model.PredictionRecord{}); err != nil {
 		return fmt.Errorf("创建预测记录表失败: %v", err)
 	}
 
+	// 创建集成预测成员表
+	if err := ds.db.AutoMigrate(&model.PredictionMember{}); err != nil {
+		return fmt.Errorf("创建集成预测成员表失败: %v", err)
+	}
+
+	// 创建回测任务表
+	if err := ds.db.AutoMigrate(&model.BacktestJob{}); err != nil {
+		return fmt.Errorf("创建回测任务表失败: %v", err)
+	}
+
+	// 创建预测依据表
+	if err := ds.db.AutoMigrate(&model.PredictionExplanation{}); err != nil {
+		return fmt.Errorf("创建预测依据表失败: %v", err)
+	}
+
+	// 创建大模型回复拒绝记录表
+	if err := ds.db.AutoMigrate(&model.LLMRejection{}); err != nil {
+		return fmt.Errorf("创建大模型回复拒绝记录表失败: %v", err)
+	}
+
 	// 创建统一的历史数据表
 	if err := ds.db.AutoMigrate(&model.HistoricalData{}); err != nil {
 		return fmt.Errorf("创建历史数据表失败: %v", err)
 	}
 
+	// 创建标的注册表
+	if err := ds.db.AutoMigrate(&model.Instrument{}); err != nil {
+		return fmt.Errorf("创建标的注册表失败: %v", err)
+	}
+
+	// 创建分钟K线表
+	if err := ds.db.AutoMigrate(&model.MinuteBar{}); err != nil {
+		return fmt.Errorf("创建分钟K线表失败: %v", err)
+	}
+
 	// 为历史数据表添加唯一约束索引
 	if err := ds.db.Exec("ALTER TABLE historical_data ADD UNIQUE INDEX idx_unique_index_date (index_code, date)").Error; err != nil {
 		// 如果索引已存在，忽略错误
@@ -75,48 +118,265 @@ func (ds *DatabaseService) initTables() error {
 		}
 	}
 
+	// 引入多模型之前的预测都由大模型生成；大模型预测原名 deepseek，统一为 llm 以延续集成权重的误差历史
+	if err := ds.migrateOnce("prediction_model_llm", func(tx *gorm.DB) error {
+		if err := tx.Model(&model.PredictionRecord{}).
+			Where("model IS NULL OR model = '' OR model = ?", "deepseek").
+			Update("model", "llm").Error; err != nil {
+			return fmt.Errorf("补全旧预测记录的模型名称失败: %v", err)
+		}
+		if err := tx.Model(&model.PredictionMember{}).
+			Where("model = ?", "deepseek").
+			Update("model", "llm").Error; err != nil {
+			return fmt.Errorf("更新集成成员的模型名称失败: %v", err)
+		}
+		return nil
+	}); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+
 	log.Printf("📊 数据库表初始化完成")
 	return nil
 }
 
+// migrateOnce 执行一次性数据迁移，成功后记录到 schema_migrations 表，之后启动时跳过
+func (ds *DatabaseService) migrateOnce(name string, migrate func(tx *gorm.DB) error) error {
+	var count int64
+	if err := ds.db.Model(&model.SchemaMigration{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return fmt.Errorf("查询数据迁移记录失败 %s: %v", name, err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		if err := migrate(tx); err != nil {
+			return err
+		}
+		return tx.Create(&model.SchemaMigration{Name: name}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("数据迁移失败 %s: %v", name, err)
+	}
+
+	log.Printf("📊 数据迁移完成: %s", name)
+	return nil
+}
+
+// dedupePredictions 删除同一标的、交易日和预测周期的重复预测记录及其成员和预测依据，保留 ID 最大的一条
+func (ds *DatabaseService) dedupePredictions(tx *gorm.DB) error {
+	if err := tx.Exec(`DELETE older FROM prediction_records older
+		JOIN prediction_records newer ON older.index_code = newer.index_code
+			AND older.trade_date = newer.trade_date AND older.horizon = newer.horizon AND older.id < newer.id`).Error; err != nil {
+		return fmt.Errorf("删除重复预测记录失败: %v", err)
+	}
+
+	for _, table := range []interface{}{&model.PredictionMember{}, &model.PredictionExplanation{}} {
+		if !tx.Migrator().HasTable(table) {
+			continue
+		}
+		if err := tx.Where("prediction_id NOT IN (?)", tx.Model(&model.PredictionRecord{}).Select("id")).
+			Delete(table).Error; err != nil {
+			return fmt.Errorf("删除重复预测的关联记录失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// predictionUpsertColumns 重新预测时覆盖的列：除主键、唯一索引列和创建时间以外的所有列
+// 新预测中为空的列（如尚未验证的实际价格）在插入语句中取默认值 NULL，覆盖时同样清空
+func (ds *DatabaseService) predictionUpsertColumns() ([]string, error) {
+	stmt := &gorm.Statement{DB: ds.db}
+	if err := stmt.Parse(&model.PredictionRecord{}); err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	for _, field := range stmt.Schema.Fields {
+		switch {
+		case field.DBName == "", field.PrimaryKey, field.AutoCreateTime > 0:
+		case field.DBName == "index_code", field.DBName == "trade_date", field.DBName == "horizon":
+		default:
+			columns = append(columns, field.DBName)
+		}
+	}
+	return columns, nil
+}
+
 // SavePrediction 保存预测记录
-func (ds *DatabaseService) SavePrediction(prediction *model.StockIndex) error {
+// tradeDate 为做出预测的交易日，targetDate 为预测的目标交易日（均按UTC零点保存）
+func (ds *DatabaseService) SavePrediction(prediction *model.StockIndex, tradeDate, targetDate time.Time) error {
+	horizon := prediction.Horizon
+	if horizon <= 0 {
+		horizon = 1
+	}
+
 	record := &model.PredictionRecord{
 		IndexCode:      prediction.Code,
 		IndexName:      prediction.Name,
 		PredictionDate: time.Now().UTC().Truncate(24 * time.Hour), // 使用UTC时区确保一致性
+		TradeDate:      &tradeDate,
+		TargetDate:     &targetDate,
+		Horizon:        horizon,
+		Model:          prediction.Model,
+		PromptVersion:  prediction.PromptVersion,
 		CurrentPrice:   prediction.Current,
 		PredictedPrice: prediction.Predicted,
 		Change:         prediction.Change,
 		ChangePercent:  prediction.ChangePercent,
 		Confidence:     prediction.Confidence,
-		MA5:            prediction.TechnicalIndicators.MA5,
-		MA20:           prediction.TechnicalIndicators.MA20,
-		RSI:            prediction.TechnicalIndicators.RSI,
-		Volatility:     prediction.TechnicalIndicators.Volatility,
-		Trend:          prediction.TechnicalIndicators.Trend,
+		MA5:            indicatorValue(prediction.TechnicalIndicators, "ma5", prediction.TechnicalIndicators.MA5),
+		MA20:           indicatorValue(prediction.TechnicalIndicators, "ma20", prediction.TechnicalIndicators.MA20),
+		RSI:            indicatorValue(prediction.TechnicalIndicators, "rsi", prediction.TechnicalIndicators.RSI),
+		Volatility:     indicatorValue(prediction.TechnicalIndicators, "volatility", prediction.TechnicalIndicators.Volatility),
+		Trend:          indicatorValue(prediction.TechnicalIndicators, "trend", prediction.TechnicalIndicators.Trend),
+	}
+
+	if interval := prediction.Interval; interval != nil {
+		record.Lower80 = &interval.Lower80
+		record.Upper80 = &interval.Upper80
+		record.Lower95 = &interval.Lower95
+		record.Upper95 = &interval.Upper95
+		record.UpProbability = &interval.UpProbability
+		record.IntervalSource = interval.Source
+	}
+
+	if ensemble := prediction.Ensemble; ensemble != nil {
+		record.LLMDivergence = ensemble.LLMDivergence
+		record.Divergent = ensemble.Divergent
+	}
+
+	// 同一交易日同一周期的预测只保留一条：按唯一索引原子地插入或用新预测整行覆盖（包括 Divergent=false 等零值）
+	columns, err := ds.predictionUpsertColumns()
+	if err != nil {
+		return fmt.Errorf("解析预测记录表结构失败: %v", err)
+	}
+	if err := ds.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "index_code"}, {Name: "trade_date"}, {Name: "horizon"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(record).Error; err != nil {
+		return fmt.Errorf("保存预测记录失败 %s: %v", prediction.Code, err)
+	}
+
+	// 覆盖已有记录时 MySQL 不返回其 ID，重新查询以关联集成成员和预测依据
+	var saved model.PredictionRecord
+	if err := ds.db.Select("id").
+		Where("index_code = ? AND trade_date = ? AND horizon = ?", record.IndexCode, tradeDate, horizon).
+		Take(&saved).Error; err != nil {
+		return fmt.Errorf("查询预测记录失败 %s: %v", prediction.Code, err)
+	}
+	record.ID = saved.ID
+
+	if prediction.Ensemble != nil {
+		if err := ds.savePredictionMembers(record, prediction.Ensemble.Members); err != nil {
+			return err
+		}
+	}
+
+	if prediction.Explanation != nil {
+		if err := ds.savePredictionExplanation(record, prediction.Explanation); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("💾 保存预测记录: %s [%s] (当前=%.2f, 预测=%.2f, 置信度=%.1f%%)",
+		prediction.Code, prediction.Model, prediction.Current, prediction.Predicted, prediction.Confidence)
+	return nil
+}
+
+// savePredictionMembers 保存集成预测的成员，重新预测时替换旧的成员记录
+func (ds *DatabaseService) savePredictionMembers(record *model.PredictionRecord, members []model.EnsembleMember) error {
+	rows := make([]model.PredictionMember, 0, len(members))
+	for _, member := range members {
+		rows = append(rows, model.PredictionMember{
+			PredictionID:   record.ID,
+			IndexCode:      record.IndexCode,
+			Horizon:        record.Horizon,
+			Model:          member.Model,
+			TargetDate:     record.TargetDate,
+			CurrentPrice:   record.CurrentPrice,
+			PredictedPrice: member.Predicted,
+			Confidence:     member.Confidence,
+			Weight:         member.Weight,
+			Samples:        member.Samples,
+			RMSE:           member.RMSE,
+		})
 	}
 
-	// 使用 UPSERT 操作，如果记录存在则更新，不存在则插入
-	result := ds.db.Where("index_code = ? AND prediction_date = ?",
-		record.IndexCode, record.PredictionDate).
-		Assign(record).
-		FirstOrCreate(record)
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("prediction_id = ?", record.ID).Delete(&model.PredictionMember{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return fmt.Errorf("保存集成预测成员失败 %s: %v", record.IndexCode, err)
+	}
+
+	return nil
+}
+
+// GetMemberErrors 获取某个成员模型最近 limit 次已验证预测的百分比误差（按目标交易日倒序）
+func (ds *DatabaseService) GetMemberErrors(indexCode string, horizon int, modelName string, limit int) ([]float64, error) {
+	var errors []float64
+
+	result := ds.db.Model(&model.PredictionMember{}).
+		Where("index_code = ? AND horizon = ? AND model = ? AND error_percent IS NOT NULL", indexCode, horizon, modelName).
+		Order("target_date DESC").
+		Limit(limit).
+		Pluck("error_percent", &errors)
 
 	if result.Error != nil {
-		return fmt.Errorf("保存预测记录失败 %s: %v", prediction.Code, result.Error)
+		return nil, fmt.Errorf("查询成员模型误差失败 %s %s: %v", indexCode, modelName, result.Error)
 	}
 
-	log.Printf("💾 保存预测记录: %s (当前=%.2f, 预测=%.2f, 置信度=%.1f%%)",
-		prediction.Code, prediction.Current, prediction.Predicted, prediction.Confidence)
+	return errors, nil
+}
+
+// savePredictionExplanation 保存预测依据，重新预测时替换旧的记录
+func (ds *DatabaseService) savePredictionExplanation(record *model.PredictionRecord, explanation *model.PredictionExplanation) error {
+	row := *explanation
+	row.ID = 0
+	row.PredictionID = record.ID
+	row.IndexCode = record.IndexCode
+	row.Horizon = record.Horizon
+
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("prediction_id = ?", record.ID).Delete(&model.PredictionExplanation{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&row).Error
+	})
+	if err != nil {
+		return fmt.Errorf("保存预测依据失败 %s: %v", record.IndexCode, err)
+	}
 	return nil
 }
 
+// GetPredictionExplanation 获取预测记录的预测依据，没有时返回 nil
+func (ds *DatabaseService) GetPredictionExplanation(predictionID uint) (*model.PredictionExplanation, error) {
+	var explanation model.PredictionExplanation
+
+	result := ds.db.Where("prediction_id = ?", predictionID).First(&explanation)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询预测依据失败 %d: %v", predictionID, result.Error)
+	}
+
+	return &explanation, nil
+}
+
 // GetLatestPrediction 获取最新预测记录
 func (ds *DatabaseService) GetLatestPrediction(indexCode string) (*model.PredictionRecord, error) {
 	var record model.PredictionRecord
 
-	result := ds.db.Where("index_code = ?", indexCode).
+	result := ds.db.Preload("Members").
+		Where("index_code = ?", indexCode).
 		Order("prediction_date DESC").
 		First(&record)
 
@@ -130,13 +390,11 @@ func (ds *DatabaseService) GetLatestPrediction(indexCode string) (*model.Predict
 	return &record, nil
 }
 
-// GetTodayPrediction 获取今日预测记录
-func (ds *DatabaseService) GetTodayPrediction(indexCode string) (*model.PredictionRecord, error) {
+// GetTodayPrediction 获取指定交易日（UTC零点）指定预测周期的预测记录，没有时返回 nil
+func (ds *DatabaseService) GetTodayPrediction(indexCode string, tradeDate time.Time, horizon int) (*model.PredictionRecord, error) {
 	var record model.PredictionRecord
-	// 使用UTC时区获取当前日期，确保与数据库时区一致
-	today := time.Now().UTC().Truncate(24 * time.Hour)
-
-	result := ds.db.Where("index_code = ? AND prediction_date = ?", indexCode, today).
+	result := ds.db.Preload("Members").
+		Where("index_code = ? AND trade_date = ? AND horizon = ?", indexCode, tradeDate, horizon).
 		First(&record)
 
 	if result.Error != nil {
@@ -146,17 +404,14 @@ func (ds *DatabaseService) GetTodayPrediction(indexCode string) (*model.Predicti
 		return nil, fmt.Errorf("查询今日预测记录失败 %s: %v", indexCode, result.Error)
 	}
 
-	log.Printf("📊 从数据库成功获取今日预测: %s (日期: %s)", indexCode, today.Format("2006-01-02"))
+	log.Printf("📊 从数据库成功获取今日预测: %s (交易日: %s)", indexCode, tradeDate.Format("2006-01-02"))
 	return &record, nil
 }
 
-// GetAllTodayPredictions 获取所有指数的今日预测记录
-func (ds *DatabaseService) GetAllTodayPredictions() (map[string]*model.PredictionRecord, error) {
+// GetAllTodayPredictions 获取所有指数指定交易日（UTC零点）指定预测周期的预测记录
+func (ds *DatabaseService) GetAllTodayPredictions(tradeDate time.Time, horizon int) (map[string]*model.PredictionRecord, error) {
 	var records []model.PredictionRecord
-	// 使用UTC时区获取当前日期，确保与数据库时区一致
-	today := time.Now().UTC().Truncate(24 * time.Hour)
-
-	result := ds.db.Where("prediction_date = ?", today).Find(&records)
+	result := ds.db.Preload("Members").Where("trade_date = ? AND horizon = ?", tradeDate, horizon).Find(&records)
 	if result.Error != nil {
 		return nil, fmt.Errorf("查询今日预测记录失败: %v", result.Error)
 	}
@@ -167,7 +422,7 @@ func (ds *DatabaseService) GetAllTodayPredictions() (map[string]*model.Predictio
 	}
 
 	if len(predictionMap) > 0 {
-		log.Printf("📊 从数据库成功获取所有今日预测: %d 条记录 (日期: %s)", len(predictionMap), today.Format("2006-01-02"))
+		log.Printf("📊 从数据库成功获取所有今日预测: %d 条记录 (交易日: %s)", len(predictionMap), tradeDate.Format("2006-01-02"))
 	}
 
 	return predictionMap, nil
@@ -239,14 +494,104 @@ func (ds *DatabaseService) GetHistoricalData(indexCode string, days int) ([]mode
 	return stockData, nil
 }
 
-// GetHistoricalPredictions 获取历史预测记录
-func (ds *DatabaseService) GetHistoricalPredictions(indexCode string, days int) ([]model.PredictionRecord, error) {
+// SaveMinuteBars 保存分钟K线，已存在的K线（盘中未走完的最后一根）按最新数据更新
+func (ds *DatabaseService) SaveMinuteBars(indexCode string, interval int, bars []model.StockData) error {
+	if len(bars) == 0 {
+		return nil
+	}
+
+	records := make([]model.MinuteBar, 0, len(bars))
+	for _, bar := range bars {
+		records = append(records, model.MinuteBar{
+			IndexCode: indexCode,
+			Interval:  interval,
+			Time:      bar.Date.UTC(),
+			Open:      bar.Open,
+			High:      bar.High,
+			Low:       bar.Low,
+			Close:     bar.Close,
+			Volume:    bar.Volume,
+		})
+	}
+
+	err := ds.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "index_code"}, {Name: "interval_minutes"}, {Name: "time"}},
+		DoUpdates: clause.AssignmentColumns([]string{"open", "high", "low", "close", "volume", "updated_at"}),
+	}).Create(&records).Error
+	if err != nil {
+		return fmt.Errorf("保存分钟K线失败 %s: %v", indexCode, err)
+	}
+	return nil
+}
+
+// GetMinuteBars 获取最近 limit 根分钟K线（按时间升序）
+func (ds *DatabaseService) GetMinuteBars(indexCode string, interval, limit int) ([]model.StockData, error) {
+	var records []model.MinuteBar
+	result := ds.db.Where("index_code = ? AND interval_minutes = ?", indexCode, interval).
+		Order("time DESC").
+		Limit(limit).
+		Find(&records)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("查询分钟K线失败 %s: %v", indexCode, result.Error)
+	}
+
+	stockData := make([]model.StockData, 0, len(records))
+	for i := len(records) - 1; i >= 0; i-- { // 反转顺序，使其按时间升序
+		record := records[i]
+		stockData = append(stockData, model.StockData{
+			Date:   record.Time.UTC(),
+			Open:   record.Open,
+			High:   record.High,
+			Low:    record.Low,
+			Close:  record.Close,
+			Volume: record.Volume,
+		})
+	}
+
+	return stockData, nil
+}
+
+// GetHistoricalDataRange 获取 [from, to] 区间内的日K线（按日期升序），零值表示不限
+func (ds *DatabaseService) GetHistoricalDataRange(indexCode string, from, to time.Time) ([]model.StockData, error) {
+	var records []model.HistoricalData
+
+	query := ds.db.Where("index_code = ?", indexCode)
+	if !from.IsZero() {
+		query = query.Where("date >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("date <= ?", to)
+	}
+
+	if result := query.Order("date ASC").Find(&records); result.Error != nil {
+		return nil, fmt.Errorf("查询历史数据失败 %s: %v", indexCode, result.Error)
+	}
+
+	stockData := make([]model.StockData, 0, len(records))
+	for _, record := range records {
+		stockData = append(stockData, model.StockData{
+			Date:   record.Date,
+			Open:   record.Open,
+			High:   record.High,
+			Low:    record.Low,
+			Close:  record.Close,
+			Volume: record.Volume,
+		})
+	}
+
+	return stockData, nil
+}
+
+// GetHistoricalPredictions 获取指定预测周期的历史预测记录
+func (ds *DatabaseService) GetHistoricalPredictions(indexCode string, days int, horizon int) ([]model.PredictionRecord, error) {
 	var records []model.PredictionRecord
 
 	// 计算起始日期
 	startDate := time.Now().UTC().AddDate(0, 0, -days).Truncate(24 * time.Hour)
 
-	result := ds.db.Where("index_code = ? AND prediction_date >= ?", indexCode, startDate).
+	result := ds.db.Preload("Members").
+		Where("index_code = ? AND prediction_date >= ? AND horizon = ?", indexCode, startDate, horizon).
 		Order("prediction_date DESC").
 		Find(&records)
 
@@ -258,14 +603,15 @@ func (ds *DatabaseService) GetHistoricalPredictions(indexCode string, days int)
 	return records, nil
 }
 
-// GetAllHistoricalPredictions 获取所有指数的历史预测记录
-func (ds *DatabaseService) GetAllHistoricalPredictions(days int) (map[string][]model.PredictionRecord, error) {
+// GetAllHistoricalPredictions 获取所有指数指定预测周期的历史预测记录
+func (ds *DatabaseService) GetAllHistoricalPredictions(days int, horizon int) (map[string][]model.PredictionRecord, error) {
 	var records []model.PredictionRecord
 
 	// 计算起始日期
 	startDate := time.Now().UTC().AddDate(0, 0, -days).Truncate(24 * time.Hour)
 
-	result := ds.db.Where("prediction_date >= ?", startDate).
+	result := ds.db.Preload("Members").
+		Where("prediction_date >= ? AND horizon = ?", startDate, horizon).
 		Order("index_code, prediction_date DESC").
 		Find(&records)
 
@@ -286,22 +632,200 @@ func (ds *DatabaseService) GetAllHistoricalPredictions(days int) (map[string][]m
 // ConvertPredictionToStockIndex 将预测记录转换为StockIndex
 func (ds *DatabaseService) ConvertPredictionToStockIndex(record *model.PredictionRecord) *model.StockIndex {
 	return &model.StockIndex{
-		Code:          record.IndexCode,
-		Name:          record.IndexName,
-		Current:       record.CurrentPrice,
-		Predicted:     record.PredictedPrice,
-		Change:        record.Change,
-		ChangePercent: record.ChangePercent,
-		Confidence:    record.Confidence,
-		TechnicalIndicators: model.TechnicalIndicators{
-			MA5:        record.MA5,
-			MA20:       record.MA20,
-			RSI:        record.RSI,
-			Volatility: record.Volatility,
-			Trend:      record.Trend,
-		},
-		Timestamp: record.CreatedAt.UTC().Format(time.RFC3339),
+		Code:                record.IndexCode,
+		Name:                record.IndexName,
+		Current:             record.CurrentPrice,
+		Predicted:           record.PredictedPrice,
+		Change:              record.Change,
+		ChangePercent:       record.ChangePercent,
+		Confidence:          record.Confidence,
+		TechnicalIndicators: recordIndicators(record),
+		Timestamp:           record.CreatedAt.UTC().Format(time.RFC3339),
+
+		Model:               record.Model,
+		PromptVersion:       record.PromptVersion,
+		Horizon:             record.Horizon,
+		TradeDate:           formatDate(record.TradeDate),
+		TargetDate:          formatDate(record.TargetDate),
+		ActualPrice:         record.ActualPrice,
+		ActualChange:        record.ActualChange,
+		ActualChangePercent: record.ActualChangePercent,
+		IsCorrect:           record.IsCorrect,
+		Covered80:           record.Covered80,
+		Covered95:           record.Covered95,
+		Interval:            recordInterval(record),
+		Ensemble:            recordEnsemble(record),
+	}
+}
+
+// indicatorValue 指标K线数足够时返回其取值，否则返回 nil（数据库中存为 NULL）
+func indicatorValue(indicators model.TechnicalIndicators, name string, value float64) *float64 {
+	if !indicators.Sufficient(name) {
+		return nil
+	}
+	return &value
+}
+
+// recordIndicators 从预测记录还原技术指标，为空的列记为数据不足
+func recordIndicators(record *model.PredictionRecord) model.TechnicalIndicators {
+	var indicators model.TechnicalIndicators
+	for _, column := range []struct {
+		name   string
+		value  *float64
+		target *float64
+	}{
+		{"ma5", record.MA5, &indicators.MA5},
+		{"ma20", record.MA20, &indicators.MA20},
+		{"rsi", record.RSI, &indicators.RSI},
+		{"volatility", record.Volatility, &indicators.Volatility},
+		{"trend", record.Trend, &indicators.Trend},
+	} {
+		if column.value == nil {
+			indicators.Insufficient = append(indicators.Insufficient, column.name)
+			continue
+		}
+		*column.target = *column.value
+	}
+	return indicators
+}
+
+// recordEnsemble 从预测记录还原集成预测明细，非集成预测返回 nil
+func recordEnsemble(record *model.PredictionRecord) *model.EnsembleBreakdown {
+	if len(record.Members) == 0 {
+		return nil
+	}
+
+	breakdown := &model.EnsembleBreakdown{
+		Members:       make([]model.EnsembleMember, 0, len(record.Members)),
+		LLMDivergence: record.LLMDivergence,
+		Divergent:     record.Divergent,
+	}
+	for _, member := range record.Members {
+		changePercent := 0.0
+		if member.CurrentPrice > 0 {
+			changePercent = math.Round((member.PredictedPrice-member.CurrentPrice)/member.CurrentPrice*10000) / 100
+		}
+		breakdown.Members = append(breakdown.Members, model.EnsembleMember{
+			Model:         member.Model,
+			Predicted:     member.PredictedPrice,
+			ChangePercent: changePercent,
+			Confidence:    member.Confidence,
+			Weight:        member.Weight,
+			Samples:       member.Samples,
+			RMSE:          member.RMSE,
+			ActualPrice:   member.ActualPrice,
+			ErrorPercent:  member.ErrorPercent,
+		})
+	}
+	return breakdown
+}
+
+// recordInterval 从预测记录还原预测区间，旧记录没有区间时返回 nil
+func recordInterval(record *model.PredictionRecord) *model.PredictionInterval {
+	if record.Lower80 == nil || record.Upper80 == nil || record.Lower95 == nil || record.Upper95 == nil {
+		return nil
+	}
+
+	interval := &model.PredictionInterval{
+		Lower80: *record.Lower80,
+		Upper80: *record.Upper80,
+		Lower95: *record.Lower95,
+		Upper95: *record.Upper95,
+		Source:  record.IntervalSource,
+	}
+	if record.UpProbability != nil {
+		interval.UpProbability = *record.UpProbability
 	}
+	return interval
+}
+
+// formatDate 格式化可为空的日期
+func formatDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
+
+// GetPredictionsWithoutTarget 获取缺少目标交易日的预测记录（旧版本数据）
+func (ds *DatabaseService) GetPredictionsWithoutTarget() ([]model.PredictionRecord, error) {
+	var records []model.PredictionRecord
+
+	result := ds.db.Where("target_date IS NULL OR trade_date IS NULL").
+		Order("prediction_date ASC").
+		Find(&records)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("查询缺少目标交易日的预测记录失败: %v", result.Error)
+	}
+
+	return records, nil
+}
+
+// UpdatePredictionTarget 补全预测记录的交易日、目标交易日和预测周期
+func (ds *DatabaseService) UpdatePredictionTarget(recordID uint, tradeDate, targetDate time.Time, horizon int) error {
+	result := ds.db.Model(&model.PredictionRecord{}).
+		Where("id = ?", recordID).
+		Updates(map[string]interface{}{
+			"trade_date":  tradeDate,
+			"target_date": targetDate,
+			"horizon":     horizon,
+		})
+
+	if result.Error != nil {
+		return fmt.Errorf("更新预测目标交易日失败: %v", result.Error)
+	}
+
+	return nil
+}
+
+// ListInstruments 获取标的注册表中的所有标的
+func (ds *DatabaseService) ListInstruments() ([]model.Instrument, error) {
+	var instruments []model.Instrument
+	if err := ds.db.Order("id ASC").Find(&instruments).Error; err != nil {
+		return nil, fmt.Errorf("查询标的注册表失败: %v", err)
+	}
+	return instruments, nil
+}
+
+// SaveInstrument 保存标的（按代码插入或更新）
+func (ds *DatabaseService) SaveInstrument(instrument *model.Instrument) error {
+	var existing model.Instrument
+	result := ds.db.Where("code = ?", instrument.Code).First(&existing)
+	if result.Error == gorm.ErrRecordNotFound {
+		if err := ds.db.Create(instrument).Error; err != nil {
+			return fmt.Errorf("保存标的失败 %s: %v", instrument.Code, err)
+		}
+		return nil
+	}
+	if result.Error != nil {
+		return fmt.Errorf("查询标的失败 %s: %v", instrument.Code, result.Error)
+	}
+
+	// 通过结构体更新，provider_symbols 才会经过 JSON 序列化；Select 保证零值（如 enabled=false）也会写入
+	instrument.ID = existing.ID
+	instrument.CreatedAt = existing.CreatedAt
+	result = ds.db.Model(&existing).
+		Select("name", "symbol", "market", "provider_symbols", "calendar", "type", "board", "is_st", "lot_size", "pinyin", "enabled").
+		Updates(instrument)
+	if result.Error != nil {
+		return fmt.Errorf("保存标的失败 %s: %v", instrument.Code, result.Error)
+	}
+
+	return nil
+}
+
+// SetInstrumentEnabled 启用或停用标的
+func (ds *DatabaseService) SetInstrumentEnabled(code string, enabled bool) error {
+	result := ds.db.Model(&model.Instrument{}).
+		Where("code = ?", code).
+		Update("enabled", enabled)
+
+	if result.Error != nil {
+		return fmt.Errorf("更新标的状态失败 %s: %v", code, result.Error)
+	}
+
+	return nil
 }
 
 // GetDB 获取底层gorm.DB对象
@@ -318,62 +842,167 @@ func (ds *DatabaseService) Close() error {
 	return sqlDB.Close()
 }
 
-// GetHistoricalPredictionsForDate 获取指定日期的历史预测记录
-func (ds *DatabaseService) GetHistoricalPredictionsForDate(date time.Time) ([]model.PredictionRecord, error) {
+// GetPendingPredictions 获取所有尚未验证的预测记录（按预测日期升序）
+func (ds *DatabaseService) GetPendingPredictions() ([]model.PredictionRecord, error) {
 	var records []model.PredictionRecord
 
-	result := ds.db.Where("prediction_date = ? AND is_correct IS NULL", date).
+	result := ds.db.Where("is_correct IS NULL").
+		Order("prediction_date ASC").
 		Find(&records)
 
 	if result.Error != nil {
-		return nil, fmt.Errorf("查询历史预测记录失败: %v", result.Error)
+		return nil, fmt.Errorf("查询待验证预测记录失败: %v", result.Error)
 	}
 
 	return records, nil
 }
 
-// UpdatePredictionAccuracy 更新预测准确性
-func (ds *DatabaseService) UpdatePredictionAccuracy(recordID uint, isCorrect bool) error {
-	result := ds.db.Model(&model.PredictionRecord{}).
-		Where("id = ?", recordID).
-		Updates(map[string]interface{}{
-			"is_correct": isCorrect,
-		})
+// GetHistoricalClose 获取指定交易日的日K线，没有记录时返回 nil
+func (ds *DatabaseService) GetHistoricalClose(indexCode string, date time.Time) (*model.HistoricalData, error) {
+	var record model.HistoricalData
+
+	result := ds.db.Where("index_code = ? AND date = ?", indexCode, date).
+		First(&record)
 
 	if result.Error != nil {
-		return fmt.Errorf("更新预测准确性失败: %v", result.Error)
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询历史收盘价失败 %s: %v", indexCode, result.Error)
+	}
+
+	return &record, nil
+}
+
+// UpdatePredictionValidation 保存预测验证结果（是否正确、实际收盘价、实际涨跌及区间覆盖），
+// 集成预测同时记录各成员的误差
+func (ds *DatabaseService) UpdatePredictionValidation(recordID uint, validation model.PredictionValidation) error {
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.PredictionRecord{}).
+			Where("id = ?", recordID).
+			Updates(map[string]interface{}{
+				"is_correct":            validation.IsCorrect,
+				"actual_price":          validation.ActualPrice,
+				"actual_change":         validation.ActualChange,
+				"actual_change_percent": validation.ActualChangePercent,
+				"covered80":             validation.Covered80,
+				"covered95":             validation.Covered95,
+				"validated_at":          time.Now().UTC(),
+			}).Error; err != nil {
+			return err
+		}
+
+		if validation.ActualPrice <= 0 {
+			return nil
+		}
+		return tx.Model(&model.PredictionMember{}).
+			Where("prediction_id = ?", recordID).
+			Updates(map[string]interface{}{
+				"actual_price":  validation.ActualPrice,
+				"error_percent": gorm.Expr("ROUND((predicted_price - ?) / ? * 100, 2)", validation.ActualPrice, validation.ActualPrice),
+			}).Error
+	})
+
+	if err != nil {
+		return fmt.Errorf("更新预测验证结果失败: %v", err)
 	}
 
 	return nil
 }
 
-// GetPredictionStats 获取预测统计信息
-func (ds *DatabaseService) GetPredictionStats() (map[string]interface{}, error) {
-	// 获取总预测次数（已验证的）
-	var totalPredictions int64
-	if err := ds.db.Model(&model.PredictionRecord{}).
-		Where("is_correct IS NOT NULL").
-		Count(&totalPredictions).Error; err != nil {
-		return nil, fmt.Errorf("查询总预测次数失败: %v", err)
+// GetValidatedPredictions 获取已验证的预测记录，可按指数代码、预测周期和预测日期区间筛选（零值表示不限）
+func (ds *DatabaseService) GetValidatedPredictions(indexCode string, horizon int, from, to time.Time) ([]model.PredictionRecord, error) {
+	var records []model.PredictionRecord
+
+	query := ds.db.Where("is_correct IS NOT NULL")
+	if indexCode != "" {
+		query = query.Where("index_code = ?", indexCode)
+	}
+	if horizon > 0 {
+		query = query.Where("horizon = ?", horizon)
+	}
+	if !from.IsZero() {
+		query = query.Where("prediction_date >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("prediction_date <= ?", to)
 	}
 
-	// 获取预测正确的次数
-	var correctPredictions int64
-	if err := ds.db.Model(&model.PredictionRecord{}).
-		Where("is_correct = ?", true).
-		Count(&correctPredictions).Error; err != nil {
-		return nil, fmt.Errorf("查询正确预测次数失败: %v", err)
+	if err := query.Order("prediction_date ASC").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("查询已验证预测记录失败: %v", err)
 	}
 
-	// 计算成功率（避免除零错误）
-	var successRate float64
-	if totalPredictions > 0 {
-		successRate = float64(correctPredictions) / float64(totalPredictions) * 100
+	return records, nil
+}
+
+// SaveLLMRejection 保存被拒绝的大模型回复
+func (ds *DatabaseService) SaveLLMRejection(rejection *model.LLMRejection) error {
+	if err := ds.db.Create(rejection).Error; err != nil {
+		return fmt.Errorf("保存大模型回复拒绝记录失败: %v", err)
+	}
+	return nil
+}
+
+// CreateBacktestJob 创建回测任务
+func (ds *DatabaseService) CreateBacktestJob(job *model.BacktestJob) error {
+	if err := ds.db.Create(job).Error; err != nil {
+		return fmt.Errorf("创建回测任务失败: %v", err)
+	}
+	return nil
+}
+
+// SaveBacktestJob 更新回测任务的状态和结果
+func (ds *DatabaseService) SaveBacktestJob(job *model.BacktestJob) error {
+	if err := ds.db.Save(job).Error; err != nil {
+		return fmt.Errorf("更新回测任务失败 %d: %v", job.ID, err)
+	}
+	return nil
+}
+
+// GetBacktestJob 获取回测任务，没有记录时返回 nil
+func (ds *DatabaseService) GetBacktestJob(id uint) (*model.BacktestJob, error) {
+	var job model.BacktestJob
+
+	result := ds.db.First(&job, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询回测任务失败 %d: %v", id, result.Error)
+	}
+
+	return &job, nil
+}
+
+// ListBacktestJobs 获取最近的回测任务（不含逐日结果）
+func (ds *DatabaseService) ListBacktestJobs(limit int) ([]model.BacktestJob, error) {
+	var jobs []model.BacktestJob
+
+	result := ds.db.Omit("result").
+		Order("id DESC").
+		Limit(limit).
+		Find(&jobs)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("查询回测任务失败: %v", result.Error)
+	}
+
+	return jobs, nil
+}
+
+// FailUnfinishedBacktestJobs 将未完成的回测任务标记为失败（服务重启后无法继续运行）
+func (ds *DatabaseService) FailUnfinishedBacktestJobs(reason string) (int64, error) {
+	result := ds.db.Model(&model.BacktestJob{}).
+		Where("status IN ?", []string{model.BacktestPending, model.BacktestRunning}).
+		Updates(map[string]interface{}{
+			"status":      model.BacktestFailed,
+			"error":       reason,
+			"finished_at": time.Now().UTC(),
+		})
+
+	if result.Error != nil {
+		return 0, fmt.Errorf("更新未完成的回测任务失败: %v", result.Error)
 	}
 
-	return map[string]interface{}{
-		"total_predictions":   totalPredictions,
-		"correct_predictions": correctPredictions,
-		"success_rate":        math.Round(successRate*100) / 100, // 保留两位小数
-	}, nil
+	return result.RowsAffected, nil
 }
@@ -0,0 +1,263 @@
+// Package indicators 技术指标计算
+//
+// 每个指标返回与输入K线等长、逐日对齐的序列，窗口内数据不足的位置为 NaN。
+// 递推类指标（EMA、MACD、OBV）按国内行情软件的惯例从第一根K线开始计算。
+package indicators
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"stock-prediction-backend/internal/model"
+	"strings"
+)
+
+// TradingDaysPerYear 年化波动率使用的年交易日数
+const TradingDaysPerYear = 252
+
+// Indicator 可按名称选择的技术指标，一个指标可以包含多条线（如 MACD 的 DIF/DEA/柱）
+type Indicator struct {
+	Name     string   // 名称，如 macd
+	Label    string   // 中文名称，用于提示词
+	Period   int      // 计算窗口（交易日数），多参数指标为最长的窗口
+	Method   string   // 计算方法，如 sma、ema、wilder
+	Required int      // 得到有效值所需的最少K线数
+	Lines    []string // 各条线的名称，单线指标与指标同名
+	compute  func(data []model.StockData) [][]float64
+}
+
+// Series 指标在每根K线上的取值及元数据
+type Series struct {
+	Name       string
+	Label      string
+	Period     int
+	Method     string
+	Required   int
+	Sufficient bool                 // K线数不少于 Required；为 false 时取值为 NaN 或尚未收敛，不应使用
+	Lines      map[string][]float64 // 键为线的名称，与K线逐日对齐
+}
+
+// Compute 计算指标的完整序列
+func (ind Indicator) Compute(data []model.StockData) Series {
+	values := ind.compute(data)
+	series := Series{
+		Name:       ind.Name,
+		Label:      ind.Label,
+		Period:     ind.Period,
+		Method:     ind.Method,
+		Required:   ind.Required,
+		Sufficient: len(data) >= ind.Required,
+		Lines:      make(map[string][]float64, len(ind.Lines)),
+	}
+	for i, line := range ind.Lines {
+		series.Lines[line] = values[i]
+	}
+	return series
+}
+
+// closeIndicator 基于收盘价的单线指标
+func closeIndicator(name, label string, period int, method string, required int, compute func(closes []float64, period int) []float64) Indicator {
+	return barIndicator(name, label, period, method, required, func(data []model.StockData) []float64 {
+		return compute(Closes(data), period)
+	})
+}
+
+// barIndicator 基于K线的单线指标
+func barIndicator(name, label string, period int, method string, required int, compute func(data []model.StockData) []float64) Indicator {
+	return Indicator{
+		Name:     name,
+		Label:    label,
+		Period:   period,
+		Method:   method,
+		Required: required,
+		Lines:    []string{name},
+		compute: func(data []model.StockData) [][]float64 {
+			return [][]float64{compute(data)}
+		},
+	}
+}
+
+// registry 所有可选择的指标
+var registry = map[string]Indicator{}
+
+func register(indicators ...Indicator) {
+	for _, ind := range indicators {
+		registry[ind.Name] = ind
+	}
+}
+
+func init() {
+	for _, period := range []int{5, 10, 20, 60} {
+		register(closeIndicator(fmt.Sprintf("ma%d", period), fmt.Sprintf("%d日均线", period), period, "sma", period, SMA))
+	}
+	for _, period := range []int{12, 26} {
+		register(closeIndicator(fmt.Sprintf("ema%d", period), fmt.Sprintf("%d日指数均线", period), period, "ema", period, EMA))
+	}
+	for _, period := range []int{6, 12, 24} {
+		register(closeIndicator(fmt.Sprintf("bias%d", period), fmt.Sprintf("%d日乖离率", period), period, "sma", period, BIAS))
+	}
+	for _, period := range []int{5, 10} {
+		period := period
+		register(barIndicator(fmt.Sprintf("vol_ma%d", period), fmt.Sprintf("%d日成交量均线", period), period, "sma", period, func(data []model.StockData) []float64 {
+			return SMA(Volumes(data), period)
+		}))
+	}
+
+	register(
+		Indicator{
+			Name:     "macd",
+			Label:    "MACD(12,26,9)",
+			Period:   26,
+			Method:   "ema",
+			Required: 26 + 9 - 1,
+			Lines:    []string{"macd_dif", "macd_dea", "macd_hist"},
+			compute: func(data []model.StockData) [][]float64 {
+				dif, dea, hist := MACD(Closes(data), 12, 26, 9)
+				return [][]float64{dif, dea, hist}
+			},
+		},
+		Indicator{
+			Name:     "kdj",
+			Label:    "KDJ(9,3,3)",
+			Period:   9,
+			Method:   "sma_smoothing",
+			Required: 9,
+			Lines:    []string{"kdj_k", "kdj_d", "kdj_j"},
+			compute: func(data []model.StockData) [][]float64 {
+				k, d, j := KDJ(data, 9, 3, 3)
+				return [][]float64{k, d, j}
+			},
+		},
+		Indicator{
+			Name:     "boll",
+			Label:    "布林带(20,2)",
+			Period:   20,
+			Method:   "sma_population_stdev",
+			Required: 20,
+			Lines:    []string{"boll_upper", "boll_middle", "boll_lower"},
+			compute: func(data []model.StockData) [][]float64 {
+				upper, middle, lower := Bollinger(Closes(data), 20, 2)
+				return [][]float64{upper, middle, lower}
+			},
+		},
+		closeIndicator("rsi", "RSI(14)", 14, "wilder", 14+1, RSI),
+		closeIndicator("volatility", "20日年化波动率", 20, "annualized_log_return_stdev", 20+1, Volatility),
+		closeIndicator("trend", "20日趋势", 20, "linear_regression", 20, Trend),
+		barIndicator("atr", "ATR(14)", 14, "wilder", 14, func(data []model.StockData) []float64 { return ATR(data, 14) }),
+		barIndicator("obv", "能量潮OBV", 0, "cumulative", 2, OBV),
+		barIndicator("cci", "CCI(14)", 14, "mean_deviation", 14, func(data []model.StockData) []float64 { return CCI(data, 14) }),
+		barIndicator("wr", "威廉指标%R(14)", 14, "range", 14, func(data []model.StockData) []float64 { return WilliamsR(data, 14) }),
+	)
+}
+
+// Lookup 按名称查找指标，名称不区分大小写
+func Lookup(name string) (Indicator, error) {
+	ind, exists := registry[strings.ToLower(strings.TrimSpace(name))]
+	if !exists {
+		return Indicator{}, fmt.Errorf("未知的技术指标: %s（可选: %s）", name, strings.Join(Names(), ", "))
+	}
+	return ind, nil
+}
+
+// Names 所有指标名称，按字母排序
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MaxRequired 所选指标中最大的 Required，即这些指标在最后一根K线上都有有效值至少需要的K线数，未知指标忽略
+func MaxRequired(names ...string) int {
+	required := 0
+	for _, name := range names {
+		if ind, err := Lookup(name); err == nil && ind.Required > required {
+			required = ind.Required
+		}
+	}
+	return required
+}
+
+// Closes 收盘价序列
+func Closes(data []model.StockData) []float64 {
+	values := make([]float64, len(data))
+	for i, bar := range data {
+		values[i] = bar.Close
+	}
+	return values
+}
+
+// Volumes 成交量序列
+func Volumes(data []model.StockData) []float64 {
+	values := make([]float64, len(data))
+	for i, bar := range data {
+		values[i] = float64(bar.Volume)
+	}
+	return values
+}
+
+// Last 序列的最后一个值，序列为空时返回 NaN
+func Last(series []float64) float64 {
+	if len(series) == 0 {
+		return math.NaN()
+	}
+	return series[len(series)-1]
+}
+
+// nanSeries 长度为 n、全部为 NaN 的序列
+func nanSeries(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = math.NaN()
+	}
+	return values
+}
+
+// Value 指标在最后一根K线上的取值
+type Value struct {
+	Name       string
+	Label      string
+	Period     int
+	Method     string
+	Sufficient bool // K线数是否足够，为 false 时各条线均为数据不足
+	Lines      []LineValue
+}
+
+// LineValue 指标中一条线的取值
+type LineValue struct {
+	Name  string
+	Value float64
+	Ready bool // 数据不足时为 false，Value 无意义
+}
+
+// Latest 按名称计算各指标在最后一根K线上的取值，用于提示词等只需要最新值的场景
+func Latest(data []model.StockData, names ...string) ([]Value, error) {
+	values := make([]Value, 0, len(names))
+	for _, name := range names {
+		ind, err := Lookup(name)
+		if err != nil {
+			return nil, err
+		}
+
+		series := ind.Compute(data)
+		value := Value{
+			Name:       series.Name,
+			Label:      series.Label,
+			Period:     series.Period,
+			Method:     series.Method,
+			Sufficient: series.Sufficient,
+		}
+		for _, line := range ind.Lines {
+			last := Last(series.Lines[line])
+			value.Lines = append(value.Lines, LineValue{
+				Name:  line,
+				Value: last,
+				Ready: series.Sufficient && !math.IsNaN(last),
+			})
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
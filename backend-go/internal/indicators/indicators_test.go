@@ -0,0 +1,289 @@
+package indicators
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"stock-prediction-backend/internal/model"
+)
+
+// 测试K线：参考值由独立实现按各指标公式计算
+var (
+	testCloses = []float64{10, 11, 12, 11, 13, 14, 13, 15, 16, 15}
+	testHighs  = []float64{10.5, 11.6, 12.4, 12.0, 13.2, 14.5, 14.0, 15.3, 16.2, 16.0}
+	testLows   = []float64{9.5, 10.4, 11.2, 10.8, 11.0, 13.1, 12.8, 13.5, 15.1, 14.7}
+	testVols   = []int64{100, 200, 150, 120, 300, 250, 180, 400, 350, 220}
+)
+
+// nan 参考序列中数据不足的位置
+var nan = math.NaN()
+
+func testBars() []model.StockData {
+	data := make([]model.StockData, len(testCloses))
+	for i := range testCloses {
+		data[i] = model.StockData{
+			Open:   testCloses[i],
+			High:   testHighs[i],
+			Low:    testLows[i],
+			Close:  testCloses[i],
+			Volume: testVols[i],
+		}
+	}
+	return data
+}
+
+// assertSeries 逐点比较序列，期望值为 NaN 的位置要求实际值也为 NaN
+func assertSeries(t *testing.T, name string, got, want []float64, tolerance float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s 长度 = %d，期望 %d", name, len(got), len(want))
+	}
+	for i := range want {
+		if math.IsNaN(want[i]) {
+			if !math.IsNaN(got[i]) {
+				t.Errorf("%s[%d] = %v，期望 NaN", name, i, got[i])
+			}
+			continue
+		}
+		if math.IsNaN(got[i]) || math.Abs(got[i]-want[i]) > tolerance {
+			t.Errorf("%s[%d] = %v，期望 %v", name, i, got[i], want[i])
+		}
+	}
+}
+
+func TestIndicators(t *testing.T) {
+	data := testBars()
+
+	tests := []struct {
+		name string
+		got  func() map[string][]float64
+		want map[string][]float64
+	}{
+		{
+			name: "MACD(3,6,2)",
+			got: func() map[string][]float64 {
+				dif, dea, hist := MACD(testCloses, 3, 6, 2)
+				return map[string][]float64{"dif": dif, "dea": dea, "hist": hist}
+			},
+			want: map[string][]float64{
+				"dif":  {0, 0.2143, 0.4745, 0.2853, 0.6056, 0.8478, 0.5988, 0.8530, 1.0362, 0.7393},
+				"dea":  {0, 0.1429, 0.3639, 0.3115, 0.5076, 0.7344, 0.6440, 0.7833, 0.9519, 0.8101},
+				"hist": {0, 0.1429, 0.2211, -0.0524, 0.1960, 0.2268, -0.0903, 0.1393, 0.1686, -0.1417},
+			},
+		},
+		{
+			name: "KDJ(5,3,3)",
+			got: func() map[string][]float64 {
+				k, d, j := KDJ(data, 5, 3, 3)
+				return map[string][]float64{"k": k, "d": d, "j": j}
+			},
+			want: map[string][]float64{
+				"k": {nan, nan, nan, nan, 64.8649, 72.5115, 68.1608, 76.5517, 83.0857, 76.9591},
+				"d": {nan, nan, nan, nan, 54.9550, 60.8071, 63.2584, 67.6895, 72.8216, 74.2007},
+				"j": {nan, nan, nan, nan, 84.6847, 95.9203, 77.9658, 94.2761, 103.6141, 82.4759},
+			},
+		},
+		{
+			name: "布林带(5,2)",
+			got: func() map[string][]float64 {
+				upper, middle, lower := Bollinger(testCloses, 5, 2)
+				return map[string][]float64{"upper": upper, "middle": middle, "lower": lower}
+			},
+			want: map[string][]float64{
+				"upper":  {nan, nan, nan, nan, 13.4396, 14.5324, 14.6396, 15.8533, 16.5324, 16.6396},
+				"middle": {nan, nan, nan, nan, 11.4, 12.2, 12.6, 13.2, 14.2, 14.6},
+				"lower":  {nan, nan, nan, nan, 9.3604, 9.8676, 10.5604, 10.5467, 11.8676, 12.5604},
+			},
+		},
+		{
+			name: "ATR(3)",
+			got:  func() map[string][]float64 { return map[string][]float64{"atr": ATR(data, 3)} },
+			want: map[string][]float64{
+				"atr": {nan, nan, 1.3333, 1.2889, 1.5926, 1.5617, 1.4412, 1.7274, 1.5516, 1.4677},
+			},
+		},
+		{
+			name: "OBV",
+			got:  func() map[string][]float64 { return map[string][]float64{"obv": OBV(data)} },
+			want: map[string][]float64{
+				"obv": {0, 200, 350, 230, 530, 780, 600, 1000, 1350, 1130},
+			},
+		},
+		{
+			name: "CCI(5)",
+			got:  func() map[string][]float64 { return map[string][]float64{"cci": CCI(data, 5)} },
+			want: map[string][]float64{
+				"cci": {nan, nan, nan, nan, 110.2151, 141.3502, 59.1398, 101.6043, 123.7304, 58.3900},
+			},
+		},
+		{
+			name: "威廉指标%R(5)",
+			got:  func() map[string][]float64 { return map[string][]float64{"wr": WilliamsR(data, 5)} },
+			want: map[string][]float64{
+				"wr": {nan, nan, nan, nan, -5.4054, -12.1951, -40.5405, -6.6667, -3.8462, -35.2941},
+			},
+		},
+		{
+			name: "BIAS(3)",
+			got:  func() map[string][]float64 { return map[string][]float64{"bias": BIAS(testCloses, 3)} },
+			want: map[string][]float64{
+				"bias": {nan, nan, 9.0909, -2.9412, 8.3333, 10.5263, -2.5, 7.1429, 9.0909, -2.1739},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.got()
+			for line, want := range tt.want {
+				assertSeries(t, tt.name+" "+line, got[line], want, 1e-3)
+			}
+		})
+	}
+}
+
+func TestRSIWilder(t *testing.T) {
+	// Wilder 平滑的经典示例数据（StockCharts RSI 教程），参考值保留两位小数
+	closes := []float64{
+		44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08, 45.89, 46.03, 45.61, 46.28,
+		46.28, 46.00, 46.03, 46.41, 46.22, 45.64, 46.21, 46.25, 45.71, 46.45, 45.78, 45.35, 44.03, 44.18,
+		44.22, 44.57, 43.42, 42.66, 43.13,
+	}
+	want := []float64{
+		70.46, 66.25, 66.48, 69.35, 66.29, 57.92, 62.88, 63.21, 56.01, 62.34,
+		54.67, 50.39, 40.02, 41.49, 41.90, 45.50, 37.32, 33.09, 37.79,
+	}
+	for i := 0; i < 14; i++ {
+		want = append([]float64{nan}, want...)
+	}
+
+	assertSeries(t, "RSI(14)", RSI(closes, 14), want, 0.01)
+}
+
+func TestBoundaries(t *testing.T) {
+	flat := make([]model.StockData, 5)
+	for i := range flat {
+		flat[i] = model.StockData{Open: 10, High: 10, Low: 10, Close: 10, Volume: 100}
+	}
+
+	tests := []struct {
+		name string
+		got  []float64
+		want []float64
+	}{
+		{"RSI K线数不超过周期时全部为 NaN", RSI([]float64{1, 2, 3}, 3), []float64{nan, nan, nan}},
+		{"RSI 价格不变时为 50", RSI([]float64{5, 5, 5, 5}, 2), []float64{nan, nan, 50, 50}},
+		{"RSI 没有下跌时为 100", RSI([]float64{1, 2, 3, 4}, 2), []float64{nan, nan, 100, 100}},
+		{"ATR K线数少于周期时全部为 NaN", ATR(flat[:2], 3), []float64{nan, nan}},
+		{"SMA 周期为 0 时全部为 NaN", SMA([]float64{1, 2}, 0), []float64{nan, nan}},
+		{"EMA 跳过开头的 NaN", EMA([]float64{nan, 2, 4}, 3), []float64{nan, 2, 3}},
+		{"BIAS 均线数据不足时为 NaN", BIAS([]float64{10, 11}, 3), []float64{nan, nan}},
+		{"%R 最高等于最低时取 -50", WilliamsR(flat, 3), []float64{nan, nan, -50, -50, -50}},
+		{"CCI 平均绝对偏差为 0 时取 0", CCI(flat, 3), []float64{nan, nan, 0, 0, 0}},
+		{"OBV 空序列", OBV(nil), []float64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, tt.name, tt.got, tt.want, 1e-9)
+		})
+	}
+
+	t.Run("KDJ 最高等于最低时 RSV 取 50", func(t *testing.T) {
+		k, d, j := KDJ(flat, 3, 3, 3)
+		for _, line := range [][]float64{k, d, j} {
+			assertSeries(t, "KDJ", line, []float64{nan, nan, 50, 50, 50}, 1e-9)
+		}
+	})
+}
+
+func TestSufficient(t *testing.T) {
+	data := testBars()
+
+	tests := []struct {
+		name       string
+		bars       int
+		sufficient bool
+	}{
+		{"rsi", 14, false},
+		{"rsi", 15, true},
+		{"macd", 33, false},
+		{"macd", 34, true},
+		{"boll", 19, false},
+		{"obv", 1, false},
+		{"obv", 2, true},
+	}
+
+	for _, tt := range tests {
+		ind, err := Lookup(tt.name)
+		if err != nil {
+			t.Fatalf("查找指标失败: %v", err)
+		}
+
+		bars := make([]model.StockData, 0, tt.bars)
+		for len(bars) < tt.bars {
+			bars = append(bars, data[len(bars)%len(data)])
+		}
+
+		series := ind.Compute(bars)
+		if series.Sufficient != tt.sufficient {
+			t.Errorf("%s 在 %d 根K线上 Sufficient = %v，期望 %v", tt.name, tt.bars, series.Sufficient, tt.sufficient)
+		}
+		for line, values := range series.Lines {
+			if len(values) != tt.bars {
+				t.Errorf("%s 的 %s 长度 = %d，期望与K线对齐为 %d", tt.name, line, len(values), tt.bars)
+			}
+		}
+	}
+
+	if _, err := Lookup("unknown"); err == nil {
+		t.Error("未知指标应返回错误")
+	}
+}
+
+func TestCalculateInsufficient(t *testing.T) {
+	// 10 根K线只够计算 5 日和 10 日的指标
+	snapshot := Calculate(testBars(), 2)
+
+	insufficient := make(map[string]bool)
+	for _, name := range snapshot.Insufficient {
+		insufficient[name] = true
+	}
+	for _, name := range []string{"ma20", "rsi", "macd", "boll", "atr", "cci", "wr", "bias12", "bias24"} {
+		if !insufficient[name] {
+			t.Errorf("%s 应标记为数据不足，实际 Insufficient = %v", name, snapshot.Insufficient)
+		}
+	}
+	for _, name := range []string{"ma5", "kdj", "obv", "bias6", "vol_ma5", "vol_ma10"} {
+		if insufficient[name] {
+			t.Errorf("%s 不应标记为数据不足", name)
+		}
+	}
+
+	// 数据不足的指标取 0，而不是 NaN
+	if snapshot.RSI != 0 || snapshot.BollUpper != 0 || snapshot.MACDDIF != 0 {
+		t.Errorf("数据不足的指标应取 0: RSI=%v BollUpper=%v MACDDIF=%v", snapshot.RSI, snapshot.BollUpper, snapshot.MACDDIF)
+	}
+	if snapshot.MA5 != 14.6 || snapshot.OBV != 1130 {
+		t.Errorf("MA5/OBV = %v/%v，期望 14.6/1130", snapshot.MA5, snapshot.OBV)
+	}
+
+	// JSON中数据不足的指标输出为 null，有效的 0 值和其他指标正常输出
+	content, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("序列化指标快照失败: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(content, &fields); err != nil {
+		t.Fatalf("解析指标快照JSON失败: %v", err)
+	}
+	for _, key := range []string{"ma_20", "rsi", "macd_dif", "macd_dea", "macd_hist", "boll_upper", "atr", "williams_r", "bias_24"} {
+		if string(fields[key]) != "null" {
+			t.Errorf("%s 应输出为 null: %s", key, content)
+		}
+	}
+	if string(fields["ma_5"]) != "14.6" {
+		t.Errorf("ma_5 应输出 14.6: %s", content)
+	}
+}
@@ -0,0 +1,95 @@
+package indicators
+
+import "math"
+
+// SMA 简单移动平均，前 period-1 个位置为 NaN
+func SMA(values []float64, period int) []float64 {
+	result := nanSeries(len(values))
+	if period <= 0 {
+		return result
+	}
+
+	var sum float64
+	for i, value := range values {
+		sum += value
+		if i >= period {
+			sum -= values[i-period]
+		}
+		if i >= period-1 {
+			result[i] = sum / float64(period)
+		}
+	}
+	return result
+}
+
+// EMA 指数移动平均，平滑系数 2/(period+1)，以第一个值作为初始值（与国内行情软件一致）
+func EMA(values []float64, period int) []float64 {
+	return smooth(values, 2/float64(period+1))
+}
+
+// smooth 指数平滑 result[i] = alpha*values[i] + (1-alpha)*result[i-1]，跳过开头的 NaN
+func smooth(values []float64, alpha float64) []float64 {
+	result := nanSeries(len(values))
+	started := false
+	for i, value := range values {
+		if math.IsNaN(value) {
+			continue
+		}
+		if !started {
+			result[i] = value
+			started = true
+			continue
+		}
+		result[i] = alpha*value + (1-alpha)*result[i-1]
+	}
+	return result
+}
+
+// MACD 指数平滑异同移动平均
+// DIF = EMA(short) - EMA(long)，DEA = EMA(DIF, signal)，柱 = 2*(DIF-DEA)（国内惯例）
+func MACD(closes []float64, short, long, signal int) (dif, dea, hist []float64) {
+	shortEMA := EMA(closes, short)
+	longEMA := EMA(closes, long)
+
+	dif = make([]float64, len(closes))
+	for i := range closes {
+		dif[i] = shortEMA[i] - longEMA[i]
+	}
+	dea = EMA(dif, signal)
+
+	hist = make([]float64, len(closes))
+	for i := range closes {
+		hist[i] = 2 * (dif[i] - dea[i])
+	}
+	return dif, dea, hist
+}
+
+// Bollinger 布林带，中轨为 period 日均线，上下轨为中轨 ± k 倍总体标准差
+func Bollinger(closes []float64, period int, k float64) (upper, middle, lower []float64) {
+	middle = SMA(closes, period)
+	upper = nanSeries(len(closes))
+	lower = nanSeries(len(closes))
+
+	for i := period - 1; i < len(closes); i++ {
+		var variance float64
+		for _, value := range closes[i-period+1 : i+1] {
+			variance += (value - middle[i]) * (value - middle[i])
+		}
+		std := math.Sqrt(variance / float64(period))
+		upper[i] = middle[i] + k*std
+		lower[i] = middle[i] - k*std
+	}
+	return upper, middle, lower
+}
+
+// BIAS 乖离率：收盘价相对 period 日均线的偏离百分比
+func BIAS(closes []float64, period int) []float64 {
+	ma := SMA(closes, period)
+	result := nanSeries(len(closes))
+	for i := range closes {
+		if !math.IsNaN(ma[i]) && ma[i] != 0 {
+			result[i] = (closes[i] - ma[i]) / ma[i] * 100
+		}
+	}
+	return result
+}
@@ -0,0 +1,147 @@
+package indicators
+
+import (
+	"math"
+	"stock-prediction-backend/internal/model"
+)
+
+// RSI 相对强弱指数（Wilder 平滑）
+// 第 period 个涨跌幅处取前 period 个涨幅、跌幅的简单平均，之后 平均 = (前值*(n-1) + 当日) / n，
+// RSI = 100 - 100/(1 + 平均涨幅/平均跌幅)；区间内没有下跌时为 100，价格完全不变时为 50
+func RSI(closes []float64, period int) []float64 {
+	result := nanSeries(len(closes))
+	if period <= 0 || len(closes) <= period {
+		return result
+	}
+
+	var avgGain, avgLoss float64
+	for i := 1; i < len(closes); i++ {
+		change := closes[i] - closes[i-1]
+		gain, loss := math.Max(change, 0), math.Max(-change, 0)
+
+		switch {
+		case i < period:
+			avgGain += gain
+			avgLoss += loss
+			continue
+		case i == period:
+			avgGain = (avgGain + gain) / float64(period)
+			avgLoss = (avgLoss + loss) / float64(period)
+		default:
+			avgGain = (avgGain*float64(period-1) + gain) / float64(period)
+			avgLoss = (avgLoss*float64(period-1) + loss) / float64(period)
+		}
+
+		switch {
+		case avgLoss == 0 && avgGain == 0:
+			result[i] = 50
+		case avgLoss == 0:
+			result[i] = 100
+		default:
+			result[i] = 100 - 100/(1+avgGain/avgLoss)
+		}
+	}
+	return result
+}
+
+// KDJ 随机指标
+// RSV = (收盘 - n日最低) / (n日最高 - n日最低) * 100，K = RSV 的 m1 日平滑，D = K 的 m2 日平滑，J = 3K - 2D
+// K、D 初始值为 50，n日最高等于最低时 RSV 取 50
+func KDJ(data []model.StockData, n, m1, m2 int) (k, d, j []float64) {
+	k = nanSeries(len(data))
+	d = nanSeries(len(data))
+	j = nanSeries(len(data))
+
+	prevK, prevD := 50.0, 50.0
+	for i := n - 1; i < len(data); i++ {
+		high, low := highestLowest(data[i-n+1 : i+1])
+		rsv := 50.0
+		if high > low {
+			rsv = (data[i].Close - low) / (high - low) * 100
+		}
+
+		k[i] = (float64(m1-1)*prevK + rsv) / float64(m1)
+		d[i] = (float64(m2-1)*prevD + k[i]) / float64(m2)
+		j[i] = 3*k[i] - 2*d[i]
+		prevK, prevD = k[i], d[i]
+	}
+	return k, d, j
+}
+
+// CCI 顺势指标：(典型价格 - 典型价格均值) / (0.015 * 平均绝对偏差)，典型价格 = (最高+最低+收盘)/3
+func CCI(data []model.StockData, period int) []float64 {
+	typical := make([]float64, len(data))
+	for i, bar := range data {
+		typical[i] = (bar.High + bar.Low + bar.Close) / 3
+	}
+	ma := SMA(typical, period)
+
+	result := nanSeries(len(data))
+	for i := period - 1; i < len(data); i++ {
+		var deviation float64
+		for _, value := range typical[i-period+1 : i+1] {
+			deviation += math.Abs(value - ma[i])
+		}
+		deviation /= float64(period)
+
+		if deviation == 0 {
+			result[i] = 0
+			continue
+		}
+		result[i] = (typical[i] - ma[i]) / (0.015 * deviation)
+	}
+	return result
+}
+
+// WilliamsR 威廉指标 %R = (n日最高 - 收盘) / (n日最高 - n日最低) * -100，取值 -100 ~ 0
+// 高于 -20 为超买，低于 -80 为超卖；n日最高等于最低时取 -50
+func WilliamsR(data []model.StockData, period int) []float64 {
+	result := nanSeries(len(data))
+	for i := period - 1; i < len(data); i++ {
+		high, low := highestLowest(data[i-period+1 : i+1])
+		if high == low {
+			result[i] = -50
+			continue
+		}
+		result[i] = (data[i].Close - high) / (high - low) * 100
+	}
+	return result
+}
+
+// ATR 平均真实波幅，真实波幅取 最高-最低、|最高-昨收|、|最低-昨收| 的最大值，
+// 第 period 根K线取简单平均，之后按 Wilder 平滑 ATR = (前值*(n-1) + TR) / n
+func ATR(data []model.StockData, period int) []float64 {
+	result := nanSeries(len(data))
+	if period <= 0 || len(data) < period {
+		return result
+	}
+
+	var sum float64
+	for i, bar := range data {
+		tr := bar.High - bar.Low
+		if i > 0 {
+			prevClose := data[i-1].Close
+			tr = math.Max(tr, math.Max(math.Abs(bar.High-prevClose), math.Abs(bar.Low-prevClose)))
+		}
+
+		switch {
+		case i < period-1:
+			sum += tr
+		case i == period-1:
+			result[i] = (sum + tr) / float64(period)
+		default:
+			result[i] = (result[i-1]*float64(period-1) + tr) / float64(period)
+		}
+	}
+	return result
+}
+
+// highestLowest K线区间内的最高价和最低价
+func highestLowest(data []model.StockData) (float64, float64) {
+	high, low := math.Inf(-1), math.Inf(1)
+	for _, bar := range data {
+		high = math.Max(high, bar.High)
+		low = math.Min(low, bar.Low)
+	}
+	return high, low
+}
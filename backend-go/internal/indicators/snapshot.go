@@ -0,0 +1,95 @@
+package indicators
+
+import (
+	"math"
+	"stock-prediction-backend/internal/model"
+)
+
+// snapshotIndicators 指标快照中包含的指标
+var snapshotIndicators = []string{
+	"ma5", "ma20", "rsi", "volatility", "trend",
+	"ema12", "ema26", "macd", "kdj", "boll", "atr", "obv", "cci", "wr",
+	"bias6", "bias12", "bias24", "vol_ma5", "vol_ma10",
+}
+
+// priceLines 以价格为单位的指标线，按标的价格精度取整
+var priceLines = map[string]bool{
+	"ma5": true, "ma20": true, "ema12": true, "ema26": true,
+	"macd_dif": true, "macd_dea": true, "macd_hist": true,
+	"boll_upper": true, "boll_middle": true, "boll_lower": true, "atr": true,
+}
+
+// Calculate 计算最后一根K线的指标快照，只使用传入的数据（回测时传入截至当日的K线即可避免未来数据）
+// 价格类指标保留 priceDecimals 位小数（与标的最小报价单位一致），其余保留两位小数
+// K线数不足以计算的指标取值为 0，并记录在 Insufficient 中（JSON中输出为 null，入库时为 NULL）
+func Calculate(data []model.StockData, priceDecimals int) model.TechnicalIndicators {
+	last := make(map[string]float64)
+	var insufficient []string
+	for _, name := range snapshotIndicators {
+		series := registry[name].Compute(data)
+		if !series.Sufficient {
+			insufficient = append(insufficient, name)
+			continue
+		}
+		for line, values := range series.Lines {
+			last[line] = Last(values)
+		}
+	}
+
+	value := func(line string) float64 {
+		if priceLines[line] {
+			return round(last[line], priceDecimals)
+		}
+		return round(last[line], 2)
+	}
+
+	return model.TechnicalIndicators{
+		MA5:        value("ma5"),
+		MA20:       value("ma20"),
+		RSI:        value("rsi"),
+		Volatility: value("volatility"),
+		Trend:      value("trend"),
+
+		EMA12:      value("ema12"),
+		EMA26:      value("ema26"),
+		MACDDIF:    value("macd_dif"),
+		MACDDEA:    value("macd_dea"),
+		MACDHist:   value("macd_hist"),
+		KDJK:       value("kdj_k"),
+		KDJD:       value("kdj_d"),
+		KDJJ:       value("kdj_j"),
+		BollUpper:  value("boll_upper"),
+		BollMiddle: value("boll_middle"),
+		BollLower:  value("boll_lower"),
+		ATR:        value("atr"),
+		OBV:        value("obv"),
+		CCI:        value("cci"),
+		WilliamsR:  value("wr"),
+		BIAS6:      value("bias6"),
+		BIAS12:     value("bias12"),
+		BIAS24:     value("bias24"),
+		VolumeMA5:  value("vol_ma5"),
+		VolumeMA10: value("vol_ma10"),
+
+		Insufficient: insufficient,
+	}
+}
+
+// SnapshotRequired 指标快照中所有指标都有有效值至少需要的K线数
+func SnapshotRequired() int {
+	return MaxRequired(snapshotIndicators...)
+}
+
+// DailyVolatility 将年化波动率（百分比）换算为日波动率（百分比）
+func DailyVolatility(annualizedPercent float64) float64 {
+	return annualizedPercent / math.Sqrt(TradingDaysPerYear)
+}
+
+// round 保留 decimals 位小数，NaN 和无穷大取 0
+func round(value float64, decimals int) float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0
+	}
+	scale := math.Pow10(decimals)
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,78 @@
+package indicators
+
+import "math"
+
+// Volatility 年化波动率（百分比）：最近 period 个日对数收益率的样本标准差 × √252
+func Volatility(closes []float64, period int) []float64 {
+	result := nanSeries(len(closes))
+	if period < 2 {
+		return result
+	}
+
+	returns := nanSeries(len(closes))
+	for i := 1; i < len(closes); i++ {
+		if closes[i-1] > 0 && closes[i] > 0 {
+			returns[i] = math.Log(closes[i] / closes[i-1])
+		}
+	}
+
+	for i := period; i < len(closes); i++ {
+		window := returns[i-period+1 : i+1]
+
+		var mean float64
+		for _, ret := range window {
+			mean += ret
+		}
+		mean /= float64(period)
+
+		var variance float64
+		for _, ret := range window {
+			variance += (ret - mean) * (ret - mean)
+		}
+		variance /= float64(period - 1)
+
+		// 窗口内有无效价格时 NaN 会传递到结果
+		result[i] = math.Sqrt(variance*TradingDaysPerYear) * 100
+	}
+	return result
+}
+
+// Trend 趋势（百分比）：对最近 period 个收盘价做最小二乘线性回归，
+// 取拟合直线从窗口起点到终点的涨跌幅，比首尾价格直接相比更不易受单日波动影响
+func Trend(closes []float64, period int) []float64 {
+	result := nanSeries(len(closes))
+	if period < 2 {
+		return result
+	}
+
+	// x 取 0..period-1，均值和离差平方和与位置无关
+	n := float64(period)
+	meanX := (n - 1) / 2
+	var sxx float64
+	for x := 0.0; x < n; x++ {
+		sxx += (x - meanX) * (x - meanX)
+	}
+
+	for i := period - 1; i < len(closes); i++ {
+		window := closes[i-period+1 : i+1]
+
+		var meanY float64
+		for _, value := range window {
+			meanY += value
+		}
+		meanY /= n
+
+		var sxy float64
+		for x, value := range window {
+			sxy += (float64(x) - meanX) * (value - meanY)
+		}
+
+		slope := sxy / sxx
+		start := meanY - slope*meanX
+		if start <= 0 {
+			continue
+		}
+		result[i] = slope * (n - 1) / start * 100
+	}
+	return result
+}
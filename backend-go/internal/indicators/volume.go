@@ -0,0 +1,20 @@
+package indicators
+
+import "stock-prediction-backend/internal/model"
+
+// OBV 能量潮：收盘上涨时累加当日成交量，下跌时减去，持平不变，第一根K线为 0
+func OBV(data []model.StockData) []float64 {
+	result := make([]float64, len(data))
+	for i := 1; i < len(data); i++ {
+		volume := float64(data[i].Volume)
+		switch {
+		case data[i].Close > data[i-1].Close:
+			result[i] = result[i-1] + volume
+		case data[i].Close < data[i-1].Close:
+			result[i] = result[i-1] - volume
+		default:
+			result[i] = result[i-1]
+		}
+	}
+	return result
+}
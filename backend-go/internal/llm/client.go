@@ -0,0 +1,286 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// chatCompletionsPath OpenAI 兼容接口的对话补全路径
+const chatCompletionsPath = "/chat/completions"
+
+// 结构化输出模式
+const (
+	ResponseFormatNone       = "none"        // 不指定，依靠提示词约束输出
+	ResponseFormatJSONObject = "json_object" // 只返回 JSON 对象（DeepSeek、通义千问、Moonshot 等支持）
+	ResponseFormatJSONSchema = "json_schema" // 按 JSON Schema 约束输出（OpenAI、vLLM 等支持）
+)
+
+// defaultTimeout 单次请求的默认超时
+const defaultTimeout = 60 * time.Second
+
+// Profile 大模型接入配置，兼容 OpenAI chat completions 接口的服务（DeepSeek、通义千问、Moonshot、vLLM 等）
+type Profile struct {
+	Name        string            // 配置名称，如 deepseek、qwen
+	BaseURL     string            // 接口地址，如 https://api.deepseek.com，可直接写到 /chat/completions
+	Model       string            // 模型名称，如 deepseek-chat
+	APIKey      string            // 为空时不发送 Authorization 头（如本地 vLLM）
+	APIKeyFile  string            // API Key 文件（k8s Secret 或 Docker secrets），优先于 APIKey，内容变化时可通过 ReloadKey 更新
+	Temperature float64           // 采样温度
+	MaxTokens   int               // 最大输出 token 数
+	Timeout     time.Duration     // 单次请求超时，0 使用默认值
+	Headers     map[string]string // 额外的请求头
+
+	ResponseFormat string // 结构化输出模式，为空时使用 json_object
+}
+
+// Message 对话消息
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRequest chat completions 请求
+type ChatRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature float64   `json:"temperature"`
+	Stream      bool      `json:"stream"`
+
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// ResponseFormat 结构化输出设置
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+// JSONSchema json_schema 模式的输出约束
+type JSONSchema struct {
+	Name   string          `json:"name"`
+	Strict bool            `json:"strict"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// ChatResponse chat completions 响应
+type ChatResponse struct {
+	ID      string   `json:"id"`
+	Object  string   `json:"object"`
+	Created int64    `json:"created"`
+	Model   string   `json:"model"`
+	Choices []Choice `json:"choices"`
+	Usage   Usage    `json:"usage"`
+}
+
+// Choice 单个回复
+type Choice struct {
+	Index        int     `json:"index"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason"`
+}
+
+// Usage token 用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Client OpenAI 兼容的 chat completions 客户端
+type Client struct {
+	profile    Profile
+	endpoint   string
+	httpClient *resty.Client
+	apiKey     atomic.Pointer[string] // 当前使用的 API Key，密钥轮换时原子替换
+}
+
+// NewClient 创建客户端，缺少接口地址或模型时返回错误
+func NewClient(profile Profile, httpClient *resty.Client) (*Client, error) {
+	if strings.TrimSpace(profile.BaseURL) == "" {
+		return nil, fmt.Errorf("大模型配置 %s 缺少接口地址", profile.Name)
+	}
+	if strings.TrimSpace(profile.Model) == "" {
+		return nil, fmt.Errorf("大模型配置 %s 缺少模型名称", profile.Name)
+	}
+	if profile.Timeout <= 0 {
+		profile.Timeout = defaultTimeout
+	}
+	switch profile.ResponseFormat {
+	case "":
+		profile.ResponseFormat = ResponseFormatJSONObject
+	case ResponseFormatNone, ResponseFormatJSONObject, ResponseFormatJSONSchema:
+	default:
+		return nil, fmt.Errorf("大模型配置 %s 的输出模式无效: %s（支持 none、json_object、json_schema）", profile.Name, profile.ResponseFormat)
+	}
+	if httpClient == nil {
+		httpClient = resty.New()
+	}
+
+	client := &Client{
+		profile:    profile,
+		endpoint:   Endpoint(profile.BaseURL),
+		httpClient: httpClient,
+	}
+	client.apiKey.Store(&profile.APIKey)
+
+	if profile.APIKeyFile != "" {
+		if _, err := client.ReloadKey(); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// Endpoint 根据接口地址得到 chat completions 的完整地址
+func Endpoint(baseURL string) string {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if strings.HasSuffix(baseURL, chatCompletionsPath) {
+		return baseURL
+	}
+	return baseURL + chatCompletionsPath
+}
+
+// Name 配置名称
+func (c *Client) Name() string {
+	return c.profile.Name
+}
+
+// Model 模型名称
+func (c *Client) Model() string {
+	return c.profile.Model
+}
+
+// HasKey 是否配置了 API Key
+func (c *Client) HasKey() bool {
+	return *c.apiKey.Load() != ""
+}
+
+// ReloadKey 从 API Key 文件重新读取密钥，返回密钥是否发生变化
+// 文件读取失败或内容为空时保留当前密钥
+func (c *Client) ReloadKey() (bool, error) {
+	if c.profile.APIKeyFile == "" {
+		return false, nil
+	}
+
+	content, err := os.ReadFile(c.profile.APIKeyFile)
+	if err != nil {
+		return false, fmt.Errorf("读取大模型配置 %s 的 API Key 文件失败: %v", c.profile.Name, err)
+	}
+	key := strings.TrimSpace(string(content))
+	if key == "" {
+		return false, fmt.Errorf("大模型配置 %s 的 API Key 文件为空: %s", c.profile.Name, c.profile.APIKeyFile)
+	}
+
+	if key == *c.apiKey.Load() {
+		return false, nil
+	}
+	c.apiKey.Store(&key)
+	return true, nil
+}
+
+// WatchKey 定期检查 API Key 文件，密钥轮换后无需重启即可生效，stop 关闭时退出
+func (c *Client) WatchKey(interval time.Duration, stop <-chan struct{}) {
+	if c.profile.APIKeyFile == "" || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+
+		changed, err := c.ReloadKey()
+		if err != nil {
+			log.Printf("⚠️ %v，继续使用当前密钥", err)
+			continue
+		}
+		if changed {
+			log.Printf("🔑 大模型配置 %s 的 API Key 已更新", c.profile.Name)
+		}
+	}
+}
+
+// NewRequest 使用配置中的模型和采样参数构建请求
+func (c *Client) NewRequest(messages ...Message) ChatRequest {
+	return ChatRequest{
+		Model:       c.profile.Model,
+		Messages:    messages,
+		MaxTokens:   c.profile.MaxTokens,
+		Temperature: c.profile.Temperature,
+	}
+}
+
+// JSONResponse 按配置的结构化输出模式构建 response_format，none 模式返回 nil
+// json_schema 模式使用 name 和 schema 严格约束输出，其余模式只要求返回 JSON 对象
+func (c *Client) JSONResponse(name string, schema json.RawMessage) *ResponseFormat {
+	switch c.profile.ResponseFormat {
+	case ResponseFormatNone:
+		return nil
+	case ResponseFormatJSONSchema:
+		return &ResponseFormat{
+			Type:       ResponseFormatJSONSchema,
+			JSONSchema: &JSONSchema{Name: name, Strict: true, Schema: schema},
+		}
+	default:
+		return &ResponseFormat{Type: ResponseFormatJSONObject}
+	}
+}
+
+// Send 发送请求并返回原始响应体，非 200 响应返回错误
+func (c *Client) Send(ctx context.Context, request ChatRequest) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.profile.Timeout)
+	defer cancel()
+
+	req := c.httpClient.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeaders(c.profile.Headers).
+		SetBody(request)
+	if key := *c.apiKey.Load(); key != "" {
+		req.SetHeader("Authorization", "Bearer "+key)
+	}
+
+	resp, err := req.Post(c.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("请求大模型接口 %s 失败: %v", c.profile.Name, err)
+	}
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("大模型接口 %s 返回错误: %d, 响应: %s", c.profile.Name, resp.StatusCode(), resp.String())
+	}
+
+	return resp.Body(), nil
+}
+
+// ParseResponse 解析原始响应体，没有回复内容时返回错误
+func ParseResponse(body []byte) (*ChatResponse, error) {
+	var response ChatResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("解析大模型响应失败: %v", err)
+	}
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("大模型响应中没有选择项")
+	}
+	return &response, nil
+}
+
+// Content 第一个回复的内容
+func (r *ChatResponse) Content() string {
+	if len(r.Choices) == 0 {
+		return ""
+	}
+	return r.Choices[0].Message.Content
+}
@@ -0,0 +1,109 @@
+// Package llmtest 提供基于 httptest 的 chat completions 假服务，
+// 用于在不访问真实大模型的情况下测试完整的预测流程
+package llmtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"stock-prediction-backend/internal/llm"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReplyFunc 根据请求生成回复内容，返回错误时假服务响应 500
+type ReplyFunc func(request llm.ChatRequest) (string, error)
+
+// Server chat completions 假服务，记录收到的请求
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	reply    ReplyFunc
+	requests []llm.ChatRequest
+	headers  []http.Header
+}
+
+// NewServer 启动假服务，使用完毕后需要调用 Close
+func NewServer(reply ReplyFunc) *Server {
+	s := &Server{reply: reply}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Reply 总是返回固定内容
+func Reply(content string) ReplyFunc {
+	return func(llm.ChatRequest) (string, error) {
+		return content, nil
+	}
+}
+
+// Profile 指向假服务的大模型配置
+func (s *Server) Profile(name, model string) llm.Profile {
+	return llm.Profile{
+		Name:        name,
+		BaseURL:     s.URL + "/v1",
+		Model:       model,
+		APIKey:      "test-key",
+		Temperature: 0.3,
+		MaxTokens:   1000,
+	}
+}
+
+// Requests 已收到的请求
+func (s *Server) Requests() []llm.ChatRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]llm.ChatRequest(nil), s.requests...)
+}
+
+// Headers 已收到请求的请求头，与 Requests 一一对应
+func (s *Server) Headers() []http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]http.Header(nil), s.headers...)
+}
+
+// handle 处理 chat completions 请求
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+		http.NotFound(w, r)
+		return
+	}
+
+	var request llm.ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, request)
+	s.headers = append(s.headers, r.Header.Clone())
+	s.mu.Unlock()
+
+	content, err := s.reply(request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := llm.ChatResponse{
+		ID:      "chatcmpl-fake",
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   request.Model,
+		Choices: []llm.Choice{{
+			Message:      llm.Message{Role: "assistant", Content: content},
+			FinishReason: "stop",
+		}},
+		Usage: llm.Usage{
+			PromptTokens:     len(request.Messages),
+			CompletionTokens: len(content),
+			TotalTokens:      len(request.Messages) + len(content),
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
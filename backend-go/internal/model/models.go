@@ -1,6 +1,11 @@
 package model
 
 import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -10,6 +15,8 @@ type StockIndex struct {
 	Name                string              `json:"name"`
 	Symbol              string              `json:"symbol"`
 	Market              string              `json:"market"`
+	Type                string              `json:"type"`        // 标的类型: index / stock / etf
+	PriceLimit          float64             `json:"price_limit"` // 涨跌幅限制百分比，0 表示无限制
 	Current             float64             `json:"current"`
 	Predicted           float64             `json:"predicted"`
 	Change              float64             `json:"change"`        // 预测涨跌金额
@@ -17,15 +24,144 @@ type StockIndex struct {
 	Confidence          float64             `json:"confidence"`
 	TechnicalIndicators TechnicalIndicators `json:"technical_indicators"`
 	Timestamp           string              `json:"timestamp"`
+
+	Model         string `json:"model,omitempty"`          // 产生预测的模型，如 llm、arima_garch
+	PromptVersion string `json:"prompt_version,omitempty"` // 大模型提示词模板版本，如 analysis/v1
+	Horizon       int    `json:"horizon,omitempty"`        // 预测周期（交易日数）
+	TradeDate     string `json:"trade_date,omitempty"`     // 做出预测的交易日（上海时区）
+	TargetDate    string `json:"target_date,omitempty"`    // 预测的目标交易日
+
+	// 以下字段仅在历史预测中返回（目标交易日收盘后验证）
+	ActualPrice         *float64 `json:"actual_price,omitempty"`
+	ActualChange        *float64 `json:"actual_change,omitempty"`
+	ActualChangePercent *float64 `json:"actual_change_percent,omitempty"`
+	IsCorrect           *bool    `json:"is_correct,omitempty"`
+	Covered80           *bool    `json:"covered_80,omitempty"` // 实际收盘价是否落在80%区间内
+	Covered95           *bool    `json:"covered_95,omitempty"` // 实际收盘价是否落在95%区间内
+
+	Interval *PredictionInterval `json:"interval,omitempty"` // 预测区间与上涨概率
+	Ensemble *EnsembleBreakdown  `json:"ensemble,omitempty"` // 集成预测的各成员明细
+
+	Explanation *PredictionExplanation `json:"-"` // 预测依据，随预测记录保存，通过 explanation 接口查询
+}
+
+// EnsembleBreakdown 集成预测明细
+type EnsembleBreakdown struct {
+	Members       []EnsembleMember `json:"members"`
+	LLMDivergence *float64         `json:"llm_divergence,omitempty"` // 大模型预测相对统计模型加权价格的偏离（百分比）
+	Divergent     bool             `json:"divergent"`                // 偏离是否超过阈值
+}
+
+// EnsembleMember 集成预测中单个模型的预测结果
+type EnsembleMember struct {
+	Model         string   `json:"model"`
+	Predicted     float64  `json:"predicted"`
+	ChangePercent float64  `json:"change_percent"` // 相对当前价格的预测涨跌幅（百分比）
+	Confidence    float64  `json:"confidence"`
+	Weight        float64  `json:"weight"`                  // 集成权重
+	Samples       int      `json:"samples"`                 // 计算权重使用的已验证预测数
+	RMSE          float64  `json:"rmse"`                    // 近期预测的百分比误差均方根，无样本时为 0
+	ActualPrice   *float64 `json:"actual_price,omitempty"`  // 目标交易日实际收盘价
+	ErrorPercent  *float64 `json:"error_percent,omitempty"` // 预测误差（百分比）
+}
+
+// PredictionInterval 预测区间与上涨概率
+type PredictionInterval struct {
+	Lower80       float64 `json:"lower_80"`
+	Upper80       float64 `json:"upper_80"`
+	Lower95       float64 `json:"lower_95"`
+	Upper95       float64 `json:"upper_95"`
+	UpProbability float64 `json:"up_probability"` // 上涨概率（百分比）
+	Source        string  `json:"source"`         // llm: 大模型给出; volatility: 按历史波动率计算; ensemble: 各成员区间加权
+}
+
+// PredictionValidation 预测验证结果
+type PredictionValidation struct {
+	IsCorrect           bool
+	ActualPrice         float64
+	ActualChange        float64
+	ActualChangePercent float64
+	Covered80           *bool // 没有预测区间时为空
+	Covered95           *bool
 }
 
 // TechnicalIndicators 技术指标
+// indicator 标签为字段所属的指标名称，该指标K线数不足时字段在JSON中输出为 null
 type TechnicalIndicators struct {
-	MA5        float64 `json:"ma_5"`
-	MA20       float64 `json:"ma_20"`
-	RSI        float64 `json:"rsi"`
-	Volatility float64 `json:"volatility"`
-	Trend      float64 `json:"trend"`
+	MA5        float64 `json:"ma_5" indicator:"ma5"`
+	MA20       float64 `json:"ma_20" indicator:"ma20"`
+	RSI        float64 `json:"rsi" indicator:"rsi"`               // 14日 Wilder RSI
+	Volatility float64 `json:"volatility" indicator:"volatility"` // 20日年化波动率（百分比）
+	Trend      float64 `json:"trend" indicator:"trend"`           // 20日线性回归趋势（百分比）
+
+	// 扩展指标，只在实时计算时提供，数据库中的历史预测不保存
+	EMA12      float64 `json:"ema_12,omitempty" indicator:"ema12"`
+	EMA26      float64 `json:"ema_26,omitempty" indicator:"ema26"`
+	MACDDIF    float64 `json:"macd_dif,omitempty" indicator:"macd"`
+	MACDDEA    float64 `json:"macd_dea,omitempty" indicator:"macd"`
+	MACDHist   float64 `json:"macd_hist,omitempty" indicator:"macd"` // 2*(DIF-DEA)
+	KDJK       float64 `json:"kdj_k,omitempty" indicator:"kdj"`
+	KDJD       float64 `json:"kdj_d,omitempty" indicator:"kdj"`
+	KDJJ       float64 `json:"kdj_j,omitempty" indicator:"kdj"`
+	BollUpper  float64 `json:"boll_upper,omitempty" indicator:"boll"`
+	BollMiddle float64 `json:"boll_middle,omitempty" indicator:"boll"`
+	BollLower  float64 `json:"boll_lower,omitempty" indicator:"boll"`
+	ATR        float64 `json:"atr,omitempty" indicator:"atr"`
+	OBV        float64 `json:"obv,omitempty" indicator:"obv"`
+	CCI        float64 `json:"cci,omitempty" indicator:"cci"`
+	WilliamsR  float64 `json:"williams_r,omitempty" indicator:"wr"` // -100 ~ 0
+	BIAS6      float64 `json:"bias_6,omitempty" indicator:"bias6"`
+	BIAS12     float64 `json:"bias_12,omitempty" indicator:"bias12"`
+	BIAS24     float64 `json:"bias_24,omitempty" indicator:"bias24"`
+	VolumeMA5  float64 `json:"volume_ma_5,omitempty" indicator:"vol_ma5"`
+	VolumeMA10 float64 `json:"volume_ma_10,omitempty" indicator:"vol_ma10"`
+
+	Insufficient []string `json:"insufficient,omitempty"` // K线数不足以计算的指标（如 rsi、macd），取值为 0，JSON中为 null
+}
+
+// Sufficient 指标是否有足够的K线计算，name 为指标名称，如 rsi
+func (t TechnicalIndicators) Sufficient(name string) bool {
+	for _, insufficient := range t.Insufficient {
+		if insufficient == name {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalJSON K线数不足的指标输出为 null，避免与真实的 0 值混淆
+func (t TechnicalIndicators) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	value := reflect.ValueOf(t)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		key, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		var encoded []byte
+		if name := field.Tag.Get("indicator"); name != "" && !t.Sufficient(name) {
+			encoded = []byte("null")
+		} else {
+			if options == "omitempty" && (value.Field(i).IsZero() || field.Type.Kind() == reflect.Slice && value.Field(i).Len() == 0) {
+				continue
+			}
+			var err error
+			if encoded, err = json.Marshal(value.Field(i).Interface()); err != nil {
+				return nil, err
+			}
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(strconv.Quote(key))
+		buf.WriteByte(':')
+		buf.Write(encoded)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
 }
 
 // APIResponse API响应结构
@@ -46,6 +182,26 @@ type HistoryData struct {
 	Volume int64   `json:"volume"`
 }
 
+// IndicatorSeriesResponse 技术指标时间序列（/indicators 接口），各指标的序列与 Dates 逐日对齐
+type IndicatorSeriesResponse struct {
+	IndexCode  string            `json:"index_code"`
+	Period     string            `json:"period"`
+	LastDate   string            `json:"last_date"` // 最后一根K线的日期
+	Dates      []string          `json:"dates"`
+	Indicators []IndicatorSeries `json:"indicators"`
+}
+
+// IndicatorSeries 单个技术指标的序列及元数据
+type IndicatorSeries struct {
+	Name       string                `json:"name"`
+	Label      string                `json:"label"`
+	Period     int                   `json:"period"`     // 计算窗口（交易日数）
+	Method     string                `json:"method"`     // 计算方法，如 sma、ema、wilder
+	Required   int                   `json:"required"`   // 得到有效值所需的最少K线数
+	Sufficient bool                  `json:"sufficient"` // K线数是否足够
+	Lines      map[string][]*float64 `json:"lines"`      // 各条线的序列，数据不足的位置为 null
+}
+
 // IndexInfo 指数基本信息
 type IndexInfo struct {
 	Code          string  `json:"code"`
@@ -56,21 +212,45 @@ type IndexInfo struct {
 	Change        float64 `json:"change"`
 	ChangePercent float64 `json:"changePercent"`
 	Volume        int64   `json:"volume"`
-	Timestamp     string  `json:"timestamp"`
+	Timestamp     string  `json:"timestamp"`         // 交易所行情时间
+	MarketState   string  `json:"market_state"`      // 市场状态: pre_open、call_auction、continuous、lunch_break、closed、holiday
+	QuoteAge      int64   `json:"quote_age_seconds"` // 行情时间距今的秒数
+	Stale         bool    `json:"stale"`             // 休市期间所有数据源行情时间均超过 MARKET_QUOTE_MAX_AGE，返回的是最新的一份
+}
+
+// TradingDayInfo 交易日查询结果
+type TradingDayInfo struct {
+	Date            string `json:"date"`
+	IsTradingDay    bool   `json:"is_trading_day"`
+	IsHoliday       bool   `json:"is_holiday"`
+	IsMakeupWorkday bool   `json:"is_makeup_workday"` // 调休上班日，交易所休市
+	PrevTradingDay  string `json:"prev_trading_day"`
+	NextTradingDay  string `json:"next_trading_day"`
+	Covered         bool   `json:"covered"` // 节假日数据是否覆盖该年份
 }
 
 // DataSourceStatus 数据源状态
 type DataSourceStatus struct {
-	YFinance struct {
-		Status     string `json:"status"`
-		Error      string `json:"error"`
-		TestSymbol string `json:"test_symbol"`
-	} `json:"yfinance"`
-	YahooFinanceWebsite struct {
-		Status string `json:"status"`
-		Error  string `json:"error"`
-	} `json:"yahoo_finance_website"`
-	Recommendation string `json:"recommendation"`
+	Providers      []ProviderStatus `json:"providers"`
+	Active         string           `json:"active"` // 当前优先使用的数据源
+	Recommendation string           `json:"recommendation"`
+	CheckedAt      string           `json:"checked_at"`
+}
+
+// ProviderStatus 单个行情数据源的健康状态
+type ProviderStatus struct {
+	Name                 string  `json:"name"`
+	Status               string  `json:"status"` // healthy / degraded / down / unknown
+	LastSuccess          string  `json:"last_success"`
+	LastError            string  `json:"last_error"`
+	LastErrorAt          string  `json:"last_error_at"`
+	ConsecutiveFailures  int     `json:"consecutive_failures"`
+	LatencyP50Ms         float64 `json:"latency_p50_ms"`
+	LatencyP90Ms         float64 `json:"latency_p90_ms"`
+	LatencyP99Ms         float64 `json:"latency_p99_ms"`
+	Samples              int     `json:"samples"`                // 延迟样本数
+	LastQuoteTime        string  `json:"last_quote_time"`        // 最近一次报价的行情时间
+	DataFreshnessSeconds float64 `json:"data_freshness_seconds"` // 最近报价距今秒数
 }
 
 // StockData 股票数据
@@ -84,27 +264,113 @@ type StockData struct {
 	Volume         int64     `json:"volume"`
 }
 
+// SecurityMatch 证券搜索结果
+type SecurityMatch struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	Symbol     string `json:"symbol"`
+	Market     string `json:"market"`
+	Type       string `json:"type"`
+	Pinyin     string `json:"pinyin"`
+	Registered bool   `json:"registered"` // 是否已加入标的注册表
+	Enabled    bool   `json:"enabled"`    // 是否已启用预测
+}
+
+// PredictionStats 预测准确性统计
+type PredictionStats struct {
+	TotalPredictions   int64   `json:"total_predictions"`   // 已验证的预测次数
+	CorrectPredictions int64   `json:"correct_predictions"` // 方向预测正确的次数
+	SuccessRate        float64 `json:"success_rate"`        // 方向准确率（百分比）
+
+	Index   string `json:"index,omitempty"`   // 筛选条件：指数代码
+	Horizon string `json:"horizon,omitempty"` // 筛选条件：预测周期
+	From    string `json:"from,omitempty"`    // 筛选条件：起始预测日期
+	To      string `json:"to,omitempty"`      // 筛选条件：截止预测日期
+
+	Overall         AccuracyMetrics            `json:"overall"`
+	ByIndex         map[string]AccuracyMetrics `json:"by_index"`
+	ByHorizon       map[string]AccuracyMetrics `json:"by_horizon"`        // 按预测周期（1d/5d/20d）
+	ByModel         map[string]AccuracyMetrics `json:"by_model"`          // 按预测模型
+	ByPromptVersion map[string]AccuracyMetrics `json:"by_prompt_version"` // 按大模型提示词模板版本
+	ByMonth         map[string]AccuracyMetrics `json:"by_month"`          // 按预测月份（YYYY-MM）
+	ByConfidence    []ConfidenceBucket         `json:"by_confidence"`     // 按置信度区间
+	Calibration     []CalibrationPoint         `json:"calibration"`       // 校准曲线
+}
+
+// AccuracyMetrics 预测误差指标
+// 价格误差指标只统计记录了实际收盘价的预测
+type AccuracyMetrics struct {
+	Count             int     `json:"count"`              // 已验证的预测数
+	Correct           int     `json:"correct"`            // 方向正确数
+	DirectionAccuracy float64 `json:"direction_accuracy"` // 方向准确率（百分比）
+	PriceSamples      int     `json:"price_samples"`      // 有实际收盘价的预测数
+	MAE               float64 `json:"mae"`                // 平均绝对误差
+	RMSE              float64 `json:"rmse"`               // 均方根误差
+	MAPE              float64 `json:"mape"`               // 平均绝对百分比误差（百分比）
+	IntervalSamples   int     `json:"interval_samples"`   // 有预测区间的预测数
+	Coverage80        float64 `json:"coverage_80"`        // 80%区间实际覆盖率（百分比）
+	Coverage95        float64 `json:"coverage_95"`        // 95%区间实际覆盖率（百分比）
+	BrierScore        float64 `json:"brier_score"`        // 上涨概率的Brier分数，越小越好
+}
+
+// ConfidenceBucket 置信度区间统计
+type ConfidenceBucket struct {
+	Range string  `json:"range"` // 如 "70-80"
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	AccuracyMetrics
+}
+
+// CalibrationPoint 校准曲线上的点：平均置信度与实际方向准确率
+type CalibrationPoint struct {
+	Range            string  `json:"range"`
+	Count            int     `json:"count"`
+	MeanConfidence   float64 `json:"mean_confidence"`
+	ObservedAccuracy float64 `json:"observed_accuracy"`
+	CalibrationGap   float64 `json:"calibration_gap"` // 实际准确率 - 平均置信度，负数表示过度自信
+}
+
 // ===== 数据库模型 =====
 
 // PredictionRecord 预测记录数据库模型
 type PredictionRecord struct {
-	ID             uint      `gorm:"primaryKey" json:"id"`
-	IndexCode      string    `gorm:"type:varchar(20);not null;index" json:"index_code"`  // 指数代码
-	IndexName      string    `gorm:"type:varchar(50);not null" json:"index_name"`        // 指数名称
-	PredictionDate time.Time `gorm:"type:date;not null;index" json:"prediction_date"`    // 预测日期
-	CurrentPrice   float64   `gorm:"type:decimal(10,2);not null" json:"current_price"`   // 当前价格
-	PredictedPrice float64   `gorm:"type:decimal(10,2);not null" json:"predicted_price"` // 预测价格
-	Change         float64   `gorm:"type:decimal(10,2);not null" json:"change"`          // 预测涨跌金额
-	ChangePercent  float64   `gorm:"type:decimal(5,2);not null" json:"change_percent"`   // 预测涨跌百分比
-	Confidence     float64   `gorm:"type:decimal(5,2);not null" json:"confidence"`       // 置信度
-	MA5            float64   `gorm:"type:decimal(10,2)" json:"ma5"`                      // 5日移动平均线
-	MA20           float64   `gorm:"type:decimal(10,2)" json:"ma20"`                     // 20日移动平均线
-	RSI            float64   `gorm:"type:decimal(5,2)" json:"rsi"`                       // RSI指标
-	Volatility     float64   `gorm:"type:decimal(5,2)" json:"volatility"`                // 波动率
-	Trend          float64   `gorm:"type:decimal(5,2)" json:"trend"`                     // 趋势指标
-	IsCorrect      *bool     `gorm:"type:bool;default:null" json:"is_correct"`           // 预测是否正确（空值表示尚未验证）
-	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`                   // 创建时间
-	UpdatedAt      time.Time `gorm:"autoUpdateTime" json:"updated_at"`                   // 更新时间
+	ID                  uint               `gorm:"primaryKey" json:"id"`
+	IndexCode           string             `gorm:"type:varchar(20);not null;index;uniqueIndex:idx_prediction_trade,priority:1" json:"index_code"` // 指数代码
+	IndexName           string             `gorm:"type:varchar(50);not null" json:"index_name"`                                                   // 指数名称
+	PredictionDate      time.Time          `gorm:"type:date;not null;index" json:"prediction_date"`                                               // 预测日期
+	TradeDate           *time.Time         `gorm:"type:date;index;uniqueIndex:idx_prediction_trade,priority:2" json:"trade_date"`                 // 做出预测的交易日（上海时区）
+	TargetDate          *time.Time         `gorm:"type:date;index" json:"target_date"`                                                            // 预测的目标交易日
+	Horizon             int                `gorm:"not null;default:1;uniqueIndex:idx_prediction_trade,priority:3" json:"horizon"`                 // 预测周期（交易日数）
+	Model               string             `gorm:"type:varchar(30);index" json:"model"`                                                           // 产生预测的模型
+	PromptVersion       string             `gorm:"type:varchar(50);index" json:"prompt_version"`                                                  // 大模型提示词模板版本，如 analysis/v1
+	CurrentPrice        float64            `gorm:"type:decimal(12,3);not null" json:"current_price"`                                              // 当前价格
+	PredictedPrice      float64            `gorm:"type:decimal(12,3);not null" json:"predicted_price"`                                            // 预测价格
+	Change              float64            `gorm:"type:decimal(12,3);not null" json:"change"`                                                     // 预测涨跌金额
+	ChangePercent       float64            `gorm:"type:decimal(5,2);not null" json:"change_percent"`                                              // 预测涨跌百分比
+	Confidence          float64            `gorm:"type:decimal(5,2);not null" json:"confidence"`                                                  // 置信度
+	MA5                 *float64           `gorm:"type:decimal(12,3);default:null" json:"ma5"`                                                    // 5日移动平均线（K线数不足时为空）
+	MA20                *float64           `gorm:"type:decimal(12,3);default:null" json:"ma20"`                                                   // 20日移动平均线（K线数不足时为空）
+	RSI                 *float64           `gorm:"type:decimal(5,2);default:null" json:"rsi"`                                                     // RSI指标（K线数不足时为空）
+	Volatility          *float64           `gorm:"type:decimal(5,2);default:null" json:"volatility"`                                              // 波动率（K线数不足时为空）
+	Trend               *float64           `gorm:"type:decimal(5,2);default:null" json:"trend"`                                                   // 趋势指标（K线数不足时为空）
+	IsCorrect           *bool              `gorm:"type:bool;default:null" json:"is_correct"`                                                      // 预测是否正确（空值表示尚未验证）
+	ActualPrice         *float64           `gorm:"type:decimal(12,3);default:null" json:"actual_price"`                                           // 目标交易日实际收盘价
+	ActualChange        *float64           `gorm:"type:decimal(12,3);default:null" json:"actual_change"`                                          // 实际涨跌金额（相对预测时价格）
+	ActualChangePercent *float64           `gorm:"type:decimal(6,2);default:null" json:"actual_change_percent"`                                   // 实际涨跌百分比
+	ValidatedAt         *time.Time         `gorm:"default:null" json:"validated_at"`                                                              // 验证时间
+	Lower80             *float64           `gorm:"type:decimal(12,3);default:null" json:"lower_80"`                                               // 80%区间下限
+	Upper80             *float64           `gorm:"type:decimal(12,3);default:null" json:"upper_80"`                                               // 80%区间上限
+	Lower95             *float64           `gorm:"type:decimal(12,3);default:null" json:"lower_95"`                                               // 95%区间下限
+	Upper95             *float64           `gorm:"type:decimal(12,3);default:null" json:"upper_95"`                                               // 95%区间上限
+	UpProbability       *float64           `gorm:"type:decimal(5,2);default:null" json:"up_probability"`                                          // 上涨概率（百分比）
+	IntervalSource      string             `gorm:"type:varchar(20)" json:"interval_source"`                                                       // 区间来源: llm / volatility
+	Covered80           *bool              `gorm:"type:bool;default:null" json:"covered_80"`                                                      // 实际收盘价是否落在80%区间内
+	Covered95           *bool              `gorm:"type:bool;default:null" json:"covered_95"`                                                      // 实际收盘价是否落在95%区间内
+	LLMDivergence       *float64           `gorm:"type:decimal(6,2);default:null" json:"llm_divergence"`                                          // 集成预测中大模型相对统计模型的偏离（百分比）
+	Divergent           bool               `gorm:"not null;default:false" json:"divergent"`                                                       // 大模型偏离是否超过阈值
+	Members             []PredictionMember `gorm:"foreignKey:PredictionID" json:"members,omitempty"`                                              // 集成预测成员
+	CreatedAt           time.Time          `gorm:"autoCreateTime" json:"created_at"`                                                              // 创建时间
+	UpdatedAt           time.Time          `gorm:"autoUpdateTime" json:"updated_at"`                                                              // 更新时间
 }
 
 // TableName 设置表名
@@ -112,16 +378,183 @@ func (PredictionRecord) TableName() string {
 	return "predictions"
 }
 
+// BacktestRequest 回测参数
+type BacktestRequest struct {
+	IndexCode   string  `json:"index_code"`
+	Strategy    string  `json:"strategy"`     // 预测模型名称，如 arima_garch
+	Horizon     int     `json:"horizon"`      // 预测周期（交易日数）
+	From        string  `json:"from"`         // 回测起始日期（YYYY-MM-DD）
+	To          string  `json:"to"`           // 回测截止日期（YYYY-MM-DD）
+	Window      int     `json:"window"`       // 每个交易日可用的历史K线数
+	CostPercent float64 `json:"cost_percent"` // 单边交易成本（百分比）
+}
+
+// BacktestResult 回测结果
+type BacktestResult struct {
+	Request BacktestRequest `json:"request"`
+	Samples int             `json:"samples"` // 有效预测的交易日数
+	Skipped int             `json:"skipped"` // 模型预测失败的交易日数
+
+	DirectionAccuracy float64 `json:"direction_accuracy"` // 方向准确率（百分比）
+	MAE               float64 `json:"mae"`                // 平均绝对误差
+	RMSE              float64 `json:"rmse"`               // 均方根误差
+	MAPE              float64 `json:"mape"`               // 平均绝对百分比误差（百分比）
+
+	// 做多/空仓策略：预测上涨时持有到下一交易日，否则空仓
+	TotalReturn     float64 `json:"total_return"`     // 策略累计收益（百分比）
+	BuyHoldReturn   float64 `json:"buy_hold_return"`  // 同期持有不动的收益（百分比）
+	MaxDrawdown     float64 `json:"max_drawdown"`     // 策略最大回撤（百分比）
+	Trades          int     `json:"trades"`           // 仓位变化次数
+	ExposurePercent float64 `json:"exposure_percent"` // 持仓交易日占比（百分比）
+
+	Points []BacktestPoint `json:"points"`
+}
+
+// BacktestPoint 回测中单个交易日的结果
+type BacktestPoint struct {
+	Date      string  `json:"date"`
+	Close     float64 `json:"close"`
+	Predicted float64 `json:"predicted"`
+	Actual    float64 `json:"actual"`   // 目标交易日实际收盘价
+	Position  int     `json:"position"` // 1 持有，0 空仓
+	Equity    float64 `json:"equity"`   // 策略净值（初始为 1）
+}
+
+// 回测任务状态
+const (
+	BacktestPending   = "pending"
+	BacktestRunning   = "running"
+	BacktestCompleted = "completed"
+	BacktestFailed    = "failed"
+)
+
+// BacktestJob 回测任务记录
+type BacktestJob struct {
+	ID         uint            `gorm:"primaryKey" json:"id"`
+	Status     string          `gorm:"type:varchar(20);not null;index" json:"status"` // pending / running / completed / failed
+	Request    BacktestRequest `gorm:"type:text;serializer:json" json:"request"`      // 回测参数
+	Result     *BacktestResult `gorm:"type:longtext;serializer:json" json:"result"`   // 回测结果，完成后写入
+	Error      string          `gorm:"type:text" json:"error,omitempty"`              // 失败原因
+	StartedAt  *time.Time      `gorm:"default:null" json:"started_at"`                // 开始运行时间
+	FinishedAt *time.Time      `gorm:"default:null" json:"finished_at"`               // 结束时间
+	CreatedAt  time.Time       `gorm:"autoCreateTime" json:"created_at"`              // 创建时间
+	UpdatedAt  time.Time       `gorm:"autoUpdateTime" json:"updated_at"`              // 更新时间
+}
+
+// TableName 设置表名
+func (BacktestJob) TableName() string {
+	return "backtest_jobs"
+}
+
+// PredictionExplanation 预测依据：预测理由，以及大模型参与时的提示词、模型、耗时和 token 用量
+type PredictionExplanation struct {
+	ID           uint     `gorm:"primaryKey" json:"id"`
+	PredictionID uint     `gorm:"not null;uniqueIndex" json:"prediction_id"`         // 关联的预测记录
+	IndexCode    string   `gorm:"type:varchar(20);not null;index" json:"index_code"` // 标的代码
+	Horizon      int      `gorm:"not null" json:"horizon"`                           // 预测周期（交易日数）
+	Model        string   `gorm:"type:varchar(30)" json:"model"`                     // 产生预测的模型，如 llm、ensemble
+	Reasoning    string   `gorm:"type:text" json:"reasoning"`                        // 最终预测的理由
+	Direction    string   `gorm:"type:varchar(10)" json:"direction,omitempty"`       // 大模型给出的方向: up / down / flat
+	KeyFactors   []string `gorm:"type:text;serializer:json" json:"key_factors,omitempty"`
+
+	// 以下字段仅在大模型参与预测时填写
+	LLMReasoning     string `gorm:"type:text" json:"llm_reasoning,omitempty"`         // 大模型给出的理由
+	Profile          string `gorm:"type:varchar(50)" json:"profile,omitempty"`        // 大模型配置名称
+	LLMModel         string `gorm:"type:varchar(100)" json:"llm_model,omitempty"`     // 大模型名称
+	PromptVersion    string `gorm:"type:varchar(50)" json:"prompt_version,omitempty"` // 提示词模板版本
+	SystemPrompt     string `gorm:"type:text" json:"system_prompt,omitempty"`         // 系统提示词
+	Prompt           string `gorm:"type:mediumtext" json:"prompt,omitempty"`          // 完整的用户提示词
+	Attempts         int    `json:"attempts,omitempty"`                               // 请求次数（含未通过校验后的重试）
+	LatencyMs        int64  `json:"latency_ms,omitempty"`                             // 各次请求的总耗时（毫秒）
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`                          // 各次请求的输入 token 数之和
+	CompletionTokens int    `json:"completion_tokens,omitempty"`                      // 各次请求的输出 token 数之和
+	TotalTokens      int    `json:"total_tokens,omitempty"`                           // 各次请求的 token 总数
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"` // 创建时间
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"` // 更新时间
+}
+
+// TableName 设置表名
+func (PredictionExplanation) TableName() string {
+	return "prediction_explanations"
+}
+
+// LLMRejection 被拒绝的大模型回复（格式不符或超出合理范围），用于排查提示词和模型质量
+type LLMRejection struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	IndexCode string    `gorm:"type:varchar(20);not null;index" json:"index_code"` // 标的代码
+	Horizon   int       `gorm:"not null" json:"horizon"`                           // 预测周期（交易日数）
+	Profile   string    `gorm:"type:varchar(50);not null;index" json:"profile"`    // 大模型配置名称
+	Model     string    `gorm:"type:varchar(100)" json:"model"`                    // 模型名称
+	Attempt   int       `gorm:"not null" json:"attempt"`                           // 第几次请求
+	Reason    string    `gorm:"type:text;not null" json:"reason"`                  // 拒绝原因
+	Content   string    `gorm:"type:text" json:"content"`                          // 大模型原始回复
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"created_at"`            // 创建时间
+}
+
+// TableName 设置表名
+func (LLMRejection) TableName() string {
+	return "llm_rejections"
+}
+
+// PredictionMember 集成预测成员记录，验证时记录各成员的误差用于计算后续权重
+type PredictionMember struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	PredictionID   uint       `gorm:"not null;index" json:"prediction_id"`                                 // 所属集成预测记录
+	IndexCode      string     `gorm:"type:varchar(20);not null;index:idx_member_lookup" json:"index_code"` // 指数代码
+	Horizon        int        `gorm:"not null;index:idx_member_lookup" json:"horizon"`                     // 预测周期（交易日数）
+	Model          string     `gorm:"type:varchar(30);not null;index:idx_member_lookup" json:"model"`      // 成员模型
+	TargetDate     *time.Time `gorm:"type:date" json:"target_date"`                                        // 预测的目标交易日
+	CurrentPrice   float64    `gorm:"type:decimal(12,3);not null" json:"current_price"`                    // 预测时价格
+	PredictedPrice float64    `gorm:"type:decimal(12,3);not null" json:"predicted_price"`                  // 成员预测价格
+	Confidence     float64    `gorm:"type:decimal(5,2);not null" json:"confidence"`                        // 成员置信度
+	Weight         float64    `gorm:"type:decimal(6,4);not null" json:"weight"`                            // 集成权重
+	Samples        int        `gorm:"not null;default:0" json:"samples"`                                   // 计算权重使用的样本数
+	RMSE           float64    `gorm:"type:decimal(6,2);not null;default:0" json:"rmse"`                    // 计算权重时的近期误差均方根（百分比）
+	ActualPrice    *float64   `gorm:"type:decimal(12,3);default:null" json:"actual_price"`                 // 目标交易日实际收盘价
+	ErrorPercent   *float64   `gorm:"type:decimal(6,2);default:null" json:"error_percent"`                 // 预测误差（百分比）
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`                                    // 创建时间
+}
+
+// TableName 设置表名
+func (PredictionMember) TableName() string {
+	return "prediction_members"
+}
+
+// Instrument 标的注册表数据库模型
+type Instrument struct {
+	ID              uint              `gorm:"primaryKey" json:"id"`
+	Code            string            `gorm:"type:varchar(20);not null;uniqueIndex" json:"code"`     // 代码，如 sh000001
+	Name            string            `gorm:"type:varchar(50);not null" json:"name"`                 // 名称
+	Symbol          string            `gorm:"type:varchar(20);not null" json:"symbol"`               // 统一代码，如 000001.SS
+	Market          string            `gorm:"type:varchar(50)" json:"market"`                        // 交易所
+	ProviderSymbols map[string]string `gorm:"type:text;serializer:json" json:"provider_symbols"`     // 各数据源专用代码，为空时按统一代码推导
+	Calendar        string            `gorm:"type:varchar(20);not null" json:"calendar"`             // 交易日历，如 XSHG / XSHE
+	Type            string            `gorm:"type:varchar(10);not null;default:'index'" json:"type"` // 标的类型: index / stock / etf
+	Board           string            `gorm:"type:varchar(10)" json:"board"`                         // 板块: main / chinext / star / bse
+	IsST            bool              `gorm:"not null" json:"is_st"`                                 // 是否ST（按名称前缀推导，涨跌幅限制5%）
+	LotSize         int               `gorm:"not null" json:"lot_size"`                              // 每手股数（最小交易单位）
+	Pinyin          string            `gorm:"type:varchar(20);index" json:"pinyin"`                  // 名称拼音首字母，用于搜索
+	Enabled         bool              `gorm:"not null" json:"enabled"`                               // 是否启用
+	CreatedAt       time.Time         `gorm:"autoCreateTime" json:"created_at"`                      // 创建时间
+	UpdatedAt       time.Time         `gorm:"autoUpdateTime" json:"updated_at"`                      // 更新时间
+}
+
+// TableName 设置表名
+func (Instrument) TableName() string {
+	return "instruments"
+}
+
 // HistoricalData 历史数据数据库模型
 type HistoricalData struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	IndexCode string    `gorm:"type:varchar(20);not null;index" json:"index_code"` // 指数代码
 	IndexName string    `gorm:"type:varchar(50);not null" json:"index_name"`       // 指数名称
 	Date      time.Time `gorm:"type:date;not null;index" json:"date"`              // 日期
-	Open      float64   `gorm:"type:decimal(10,2);not null" json:"open"`           // 开盘价
-	High      float64   `gorm:"type:decimal(10,2);not null" json:"high"`           // 最高价
-	Low       float64   `gorm:"type:decimal(10,2);not null" json:"low"`            // 最低价
-	Close     float64   `gorm:"type:decimal(10,2);not null" json:"close"`          // 收盘价
+	Open      float64   `gorm:"type:decimal(12,3);not null" json:"open"`           // 开盘价
+	High      float64   `gorm:"type:decimal(12,3);not null" json:"high"`           // 最高价
+	Low       float64   `gorm:"type:decimal(12,3);not null" json:"low"`            // 最低价
+	Close     float64   `gorm:"type:decimal(12,3);not null" json:"close"`          // 收盘价
 	Volume    int64     `gorm:"type:bigint;not null" json:"volume"`                // 成交量
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`                  // 创建时间
 	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`                  // 更新时间
@@ -131,3 +564,59 @@ type HistoricalData struct {
 func (HistoricalData) TableName() string {
 	return "historical_data"
 }
+
+// SchemaMigration 已执行的一次性数据迁移
+type SchemaMigration struct {
+	Name      string    `gorm:"type:varchar(100);primaryKey" json:"name"` // 迁移名称
+	AppliedAt time.Time `gorm:"autoCreateTime" json:"applied_at"`         // 执行时间
+}
+
+// MinuteBar 分钟K线数据库模型，Time 为K线结束时间（UTC）
+type MinuteBar struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	IndexCode string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_minute_bar,priority:1" json:"index_code"`      // 标的代码
+	Interval  int       `gorm:"column:interval_minutes;not null;uniqueIndex:idx_minute_bar,priority:2" json:"interval"` // K线周期（分钟）
+	Time      time.Time `gorm:"not null;uniqueIndex:idx_minute_bar,priority:3" json:"time"`                             // K线结束时间
+	Open      float64   `gorm:"type:decimal(12,3);not null" json:"open"`                                                // 开盘价
+	High      float64   `gorm:"type:decimal(12,3);not null" json:"high"`                                                // 最高价
+	Low       float64   `gorm:"type:decimal(12,3);not null" json:"low"`                                                 // 最低价
+	Close     float64   `gorm:"type:decimal(12,3);not null" json:"close"`                                               // 收盘价
+	Volume    int64     `gorm:"type:bigint;not null" json:"volume"`                                                     // 成交量
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`                                                       // 创建时间
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`                                                       // 更新时间
+}
+
+// MinuteBarResponse 分钟K线查询结果
+type MinuteBarResponse struct {
+	IndexCode string          `json:"index_code"`
+	Interval  int             `json:"interval"`
+	Source    string          `json:"source"` // database 或 live
+	Bars      []MinuteBarData `json:"bars"`
+}
+
+// MinuteBarData 单根分钟K线，时间为北京时间
+type MinuteBarData struct {
+	Time   string  `json:"time"`
+	Open   float64 `json:"open"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Close  float64 `json:"close"`
+	Volume int64   `json:"volume"`
+}
+
+// LiveQuote 实时报价推送
+type LiveQuote struct {
+	Code           string  `json:"code"`
+	Name           string  `json:"name"`
+	Price          float64 `json:"price"`
+	Open           float64 `json:"open"`
+	High           float64 `json:"high"`
+	Low            float64 `json:"low"`
+	YesterdayClose float64 `json:"yesterday_close"`
+	Change         float64 `json:"change"`
+	ChangePercent  float64 `json:"change_percent"`
+	Volume         int64   `json:"volume"`
+	Timestamp      string  `json:"timestamp"`    // 交易所行情时间
+	MarketState    string  `json:"market_state"` // 推送时的市场状态
+	Stale          bool    `json:"stale"`        // 行情时间超过 MARKET_QUOTE_MAX_AGE（休市期间的兜底报价）
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"stock-prediction-backend/internal/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 回测参数
+const (
+	defaultBacktestStrategy = ModelARIMAGARCH
+	maxBacktestWindow       = 250 // 约一年的交易日
+	maxBacktestCostPercent  = 5.0
+	maxConcurrentBacktests  = 2  // 同时运行的回测任务数
+	maxQueuedBacktests      = 8  // 运行中和等待中的回测任务总数上限，超过时拒绝新任务
+	backtestListLimit       = 50 // 回测任务列表返回的最大条数
+)
+
+// ErrBacktestQueueFull 回测任务队列已满
+var ErrBacktestQueueFull = errors.New("回测任务队列已满，请稍后再试")
+
+// normalizeBacktestRequest 填充默认值并校验回测参数，返回起止日期（UTC零点，与数据库日期一致）
+func (ds *DataService) normalizeBacktestRequest(req *model.BacktestRequest) (time.Time, time.Time, error) {
+	req.IndexCode = strings.TrimSpace(req.IndexCode)
+	if req.IndexCode == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("缺少回测标的代码")
+	}
+	if _, exists := ds.registry.Lookup(req.IndexCode); !exists {
+		return time.Time{}, time.Time{}, fmt.Errorf("标的不存在: %s", req.IndexCode)
+	}
+
+	req.Strategy = strings.ToLower(strings.TrimSpace(req.Strategy))
+	if req.Strategy == "" {
+		req.Strategy = defaultBacktestStrategy
+	}
+	if req.Strategy == ModelDeepSeek {
+		req.Strategy = ModelLLM
+	}
+	if _, err := ds.newBacktestPredictor(req.Strategy); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if req.Horizon == 0 {
+		req.Horizon = DefaultHorizon
+	}
+	if _, err := ParseHorizon(strconv.Itoa(req.Horizon)); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	// 默认使用与线上预测相同数量的历史K线
+	if req.Window == 0 {
+		req.Window = predictionHistoryCount()
+	}
+	if req.Window <= minReturnSamples || req.Window > maxBacktestWindow {
+		return time.Time{}, time.Time{}, fmt.Errorf("历史K线数需在 %d 到 %d 之间", minReturnSamples+1, maxBacktestWindow)
+	}
+
+	if req.CostPercent < 0 || req.CostPercent > maxBacktestCostPercent {
+		return time.Time{}, time.Time{}, fmt.Errorf("交易成本需在 0 到 %.0f%% 之间", maxBacktestCostPercent)
+	}
+
+	// 默认回测最近一年
+	to := toDateUTC(time.Now(), ds.calendar.Location())
+	if req.To != "" {
+		parsed, err := time.Parse("2006-01-02", req.To)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("无效的截止日期: %s", req.To)
+		}
+		to = parsed
+	}
+	from := to.AddDate(-1, 0, 0)
+	if req.From != "" {
+		parsed, err := time.Parse("2006-01-02", req.From)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("无效的起始日期: %s", req.From)
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("起始日期不能晚于截止日期")
+	}
+	req.From = from.Format("2006-01-02")
+	req.To = to.Format("2006-01-02")
+
+	return from, to, nil
+}
+
+// newBacktestPredictor 创建回测使用的预测模型
+// 集成预测的权重来自线上已验证的误差，回测时会用到未来数据，因此不支持
+func (ds *DataService) newBacktestPredictor(name string) (Predictor, error) {
+	switch name {
+	case ModelEnsemble:
+		return nil, fmt.Errorf("集成预测的成员权重依赖线上验证结果，不支持回测")
+	case ModelLLM, ModelDeepSeek:
+		if !ds.llmEnabled() {
+			return nil, fmt.Errorf("大模型预测未启用，请配置 API Key")
+		}
+		return &llmPredictor{ds: ds}, nil
+	}
+
+	if predictor := newStatisticalPredictor(name); predictor != nil {
+		return predictor, nil
+	}
+	return nil, fmt.Errorf("未知的回测策略: %s", name)
+}
+
+// RunBacktest 逐日回放数据库中的日K线进行回测
+// 每个交易日只使用截至当日收盘的K线计算技术指标和预测，预测 horizon 个交易日后的收盘价；
+// 做多/空仓策略在预测上涨时持有到下一交易日，否则空仓
+func (ds *DataService) RunBacktest(req model.BacktestRequest) (*model.BacktestResult, error) {
+	if ds.db == nil {
+		return nil, fmt.Errorf("回测需要数据库中的历史数据，数据库未初始化")
+	}
+
+	from, to, err := ds.normalizeBacktestRequest(&req)
+	if err != nil {
+		return nil, err
+	}
+
+	instrument, _ := ds.registry.Lookup(req.IndexCode)
+	predictor, err := ds.newBacktestPredictor(req.Strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	// 截止日期之后还需要 horizon 个交易日的K线用于验证
+	bars, err := ds.db.GetHistoricalDataRange(req.IndexCode, time.Time{}, to.AddDate(0, 0, req.Horizon*2+30))
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("🧪 开始回测 %s [%s, %s]: 策略=%s, 周期=%s, K线=%d",
+		req.IndexCode, req.From, req.To, req.Strategy, horizonLabel(req.Horizon), len(bars))
+
+	result, err := replayBacktest(req, instrument, predictor, bars, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("🧪 回测完成 %s: 样本=%d, 方向准确率=%.2f%%, MAE=%.2f, 策略收益=%.2f%%, 持有收益=%.2f%%",
+		req.IndexCode, result.Samples, result.DirectionAccuracy, result.MAE, result.TotalReturn, result.BuyHoldReturn)
+	return result, nil
+}
+
+// replayBacktest 按日期升序回放 bars 中 [from, to] 区间的交易日
+func replayBacktest(req model.BacktestRequest, instrument model.Instrument, predictor Predictor, bars []model.StockData, from, to time.Time) (*model.BacktestResult, error) {
+	result := &model.BacktestResult{Request: req, Points: []model.BacktestPoint{}}
+	acc := &accuracyAccumulator{}
+	equity, peak := 1.0, 1.0
+	position, longDays := 0, 0
+	firstClose, lastClose := 0.0, 0.0
+
+	for i := req.Window - 1; i+req.Horizon < len(bars); i++ {
+		bar := bars[i]
+		if bar.Date.Before(from) || bar.Date.After(to) {
+			continue
+		}
+
+		// 只使用截至当日的K线，避免未来数据
+		history := bars[i-req.Window+1 : i+1]
+		current := bar.Close
+		actual := bars[i+req.Horizon].Close
+
+		prediction, err := predictor.Predict(PredictionInput{
+			Instrument:   instrument,
+			Horizon:      req.Horizon,
+			CurrentPrice: current,
+			Indicators:   CalculateTechnicalIndicators(instrument, history),
+			History:      history,
+			AsOf:         bar.Date,
+		})
+
+		// 模型失败的交易日保持空仓
+		newPosition := 0
+		predicted := 0.0
+		if err != nil || prediction.PredictedPrice <= 0 {
+			result.Skipped++
+		} else {
+			predicted = prediction.PredictedPrice
+			if predicted > current {
+				newPosition = 1
+			}
+
+			correct := (predicted-current)*(actual-current) > 0
+			acc.add(model.PredictionRecord{
+				CurrentPrice:   current,
+				PredictedPrice: predicted,
+				ActualPrice:    &actual,
+				IsCorrect:      &correct,
+			})
+		}
+
+		// 调仓成本，然后按下一交易日的涨跌计算净值
+		if newPosition != position {
+			result.Trades++
+			equity *= 1 - req.CostPercent/100
+		}
+		position = newPosition
+		if position == 1 {
+			longDays++
+			equity *= bars[i+1].Close / current
+		}
+		peak = math.Max(peak, equity)
+		result.MaxDrawdown = math.Max(result.MaxDrawdown, (peak-equity)/peak*100)
+
+		if firstClose == 0 {
+			firstClose = current
+		}
+		lastClose = bars[i+1].Close
+
+		result.Points = append(result.Points, model.BacktestPoint{
+			Date:      bar.Date.Format("2006-01-02"),
+			Close:     current,
+			Predicted: roundPrice(predicted, instrument),
+			Actual:    actual,
+			Position:  position,
+			Equity:    math.Round(equity*10000) / 10000,
+		})
+	}
+
+	if acc.count == 0 {
+		return nil, fmt.Errorf("回测区间内没有可用的预测（需要至少 %d 根历史K线，并且目标交易日已有收盘价）", req.Window)
+	}
+
+	metrics := acc.metrics()
+	result.Samples = acc.count
+	result.DirectionAccuracy = metrics.DirectionAccuracy
+	result.MAE = metrics.MAE
+	result.RMSE = metrics.RMSE
+	result.MAPE = metrics.MAPE
+	result.TotalReturn = round2((equity - 1) * 100)
+	result.BuyHoldReturn = round2((lastClose/firstClose - 1) * 100)
+	result.MaxDrawdown = round2(result.MaxDrawdown)
+	result.ExposurePercent = round2(float64(longDays) / float64(len(result.Points)) * 100)
+
+	return result, nil
+}
+
+// StartBacktest 创建回测任务并在后台运行，结果保存到数据库
+// 运行中和等待中的任务达到 maxQueuedBacktests 时返回 ErrBacktestQueueFull
+func (ds *DataService) StartBacktest(req model.BacktestRequest) (*model.BacktestJob, error) {
+	if ds.db == nil {
+		return nil, fmt.Errorf("回测需要数据库中的历史数据，数据库未初始化")
+	}
+
+	if _, _, err := ds.normalizeBacktestRequest(&req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case ds.backtestQueue <- struct{}{}:
+	default:
+		return nil, ErrBacktestQueueFull
+	}
+
+	job := &model.BacktestJob{Status: model.BacktestPending, Request: req}
+	if err := ds.db.CreateBacktestJob(job); err != nil {
+		<-ds.backtestQueue
+		return nil, err
+	}
+
+	running := *job
+	go ds.runBacktestJob(&running)
+
+	log.Printf("🧪 已创建回测任务 %d: %s %s", job.ID, req.IndexCode, req.Strategy)
+	return job, nil
+}
+
+// runBacktestJob 在后台运行回测任务并保存结果，结束后释放队列位置
+func (ds *DataService) runBacktestJob(job *model.BacktestJob) {
+	defer func() { <-ds.backtestQueue }()
+
+	ds.backtestSlots <- struct{}{}
+	defer func() { <-ds.backtestSlots }()
+
+	started := time.Now().UTC()
+	job.Status = model.BacktestRunning
+	job.StartedAt = &started
+	if err := ds.db.SaveBacktestJob(job); err != nil {
+		log.Printf("❌ %v", err)
+	}
+
+	result, err := ds.RunBacktest(job.Request)
+
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	if err != nil {
+		log.Printf("❌ 回测任务 %d 失败: %v", job.ID, err)
+		job.Status = model.BacktestFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = model.BacktestCompleted
+		job.Result = result
+	}
+
+	if err := ds.db.SaveBacktestJob(job); err != nil {
+		log.Printf("❌ %v", err)
+	}
+}
+
+// GetBacktest 获取回测任务及结果，不存在时返回 nil
+func (ds *DataService) GetBacktest(id uint) (*model.BacktestJob, error) {
+	if ds.db == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+	return ds.db.GetBacktestJob(id)
+}
+
+// ListBacktests 获取最近的回测任务
+func (ds *DataService) ListBacktests() ([]model.BacktestJob, error) {
+	if ds.db == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+	return ds.db.ListBacktestJobs(backtestListLimit)
+}
+
+// failInterruptedBacktests 将上次运行中断的回测任务标记为失败
+func (ds *DataService) failInterruptedBacktests() {
+	if ds.db == nil {
+		return
+	}
+
+	count, err := ds.db.FailUnfinishedBacktestJobs("服务重启，回测任务中断")
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("⚠️ %d 个回测任务因服务重启中断，已标记为失败", count)
+	}
+}
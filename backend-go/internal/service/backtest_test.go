@@ -0,0 +1,66 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"stock-prediction-backend/internal/calendar"
+	"stock-prediction-backend/internal/database"
+	"stock-prediction-backend/internal/indicators"
+	"stock-prediction-backend/internal/model"
+)
+
+func TestStartBacktestQueueFull(t *testing.T) {
+	registry, err := NewInstrumentRegistry("", nil)
+	if err != nil {
+		t.Fatalf("加载标的注册表失败: %v", err)
+	}
+	tradingCalendar, err := calendar.NewTradingCalendar("")
+	if err != nil {
+		t.Fatalf("加载交易日历失败: %v", err)
+	}
+
+	ds := &DataService{
+		registry:      registry,
+		calendar:      tradingCalendar,
+		db:            &database.DatabaseService{},
+		backtestSlots: make(chan struct{}, maxConcurrentBacktests),
+		backtestQueue: make(chan struct{}, maxQueuedBacktests),
+	}
+	for i := 0; i < maxQueuedBacktests; i++ {
+		ds.backtestQueue <- struct{}{}
+	}
+
+	_, err = ds.StartBacktest(model.BacktestRequest{IndexCode: "sh000001", Strategy: ModelEMADrift})
+	if !errors.Is(err, ErrBacktestQueueFull) {
+		t.Fatalf("错误 = %v，期望 ErrBacktestQueueFull", err)
+	}
+
+	// 参数错误优先于队列检查返回
+	_, err = ds.StartBacktest(model.BacktestRequest{IndexCode: "unknown"})
+	if err == nil || errors.Is(err, ErrBacktestQueueFull) {
+		t.Fatalf("错误 = %v，期望标的不存在", err)
+	}
+}
+
+func TestNormalizeBacktestRequestDefaultWindow(t *testing.T) {
+	registry, err := NewInstrumentRegistry("", nil)
+	if err != nil {
+		t.Fatalf("加载标的注册表失败: %v", err)
+	}
+	tradingCalendar, err := calendar.NewTradingCalendar("")
+	if err != nil {
+		t.Fatalf("加载交易日历失败: %v", err)
+	}
+	ds := &DataService{registry: registry, calendar: tradingCalendar}
+
+	req := model.BacktestRequest{IndexCode: "sh000001", Strategy: ModelEMADrift}
+	if _, _, err := ds.normalizeBacktestRequest(&req); err != nil {
+		t.Fatalf("校验回测参数失败: %v", err)
+	}
+
+	// 默认窗口与线上预测获取的K线数一致，并且足够计算 MACD 等所有快照指标
+	if req.Window != predictionHistoryCount() || req.Window < indicators.SnapshotRequired() {
+		t.Errorf("默认窗口 = %d，期望 %d", req.Window, predictionHistoryCount())
+	}
+}
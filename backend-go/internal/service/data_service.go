@@ -2,15 +2,15 @@ package service
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"math"
-	"math/rand"
+	"stock-prediction-backend/internal/calendar"
 	"stock-prediction-backend/internal/config"
 	"stock-prediction-backend/internal/database"
+	"stock-prediction-backend/internal/indicators"
+	"stock-prediction-backend/internal/llm"
 	"stock-prediction-backend/internal/model"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -24,47 +24,23 @@ type CacheItem struct {
 	ExpiresAt time.Time
 }
 
-// DeepSeekRequest DeepSeek API请求结构
-type DeepSeekRequest struct {
-	Model       string            `json:"model"`
-	Messages    []DeepSeekMessage `json:"messages"`
-	MaxTokens   int               `json:"max_tokens"`
-	Temperature float64           `json:"temperature"`
-	Stream      bool              `json:"stream"`
-}
-
-// DeepSeekMessage DeepSeek消息结构
-type DeepSeekMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-// DeepSeekResponse DeepSeek API响应结构
-type DeepSeekResponse struct {
-	ID      string `json:"id"`
-	Object  string `json:"object"`
-	Created int64  `json:"created"`
-	Model   string `json:"model"`
-	Choices []struct {
-		Index   int `json:"index"`
-		Message struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
-		} `json:"message"`
-		FinishReason string `json:"finish_reason"`
-	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
-}
-
 // PredictionResult AI预测结果结构
 type PredictionResult struct {
-	PredictedPrice float64 `json:"predicted_price"`
-	Confidence     float64 `json:"confidence"`
-	Reasoning      string  `json:"reasoning"`
+	PredictedPrice float64  `json:"predicted_price"`
+	Confidence     float64  `json:"confidence"`
+	Lower80        float64  `json:"lower_80"`
+	Upper80        float64  `json:"upper_80"`
+	Lower95        float64  `json:"lower_95"`
+	Upper95        float64  `json:"upper_95"`
+	UpProbability  float64  `json:"up_probability"`
+	Direction      string   `json:"direction"`   // 预测方向: up、down、flat
+	KeyFactors     []string `json:"key_factors"` // 影响预测的关键因素
+	Reasoning      string   `json:"reasoning"`
+
+	Model          string                   `json:"-"` // 产生预测的模型
+	IntervalSource string                   `json:"-"` // 区间来源，为空表示由大模型给出
+	Ensemble       *model.EnsembleBreakdown `json:"-"` // 集成预测的成员明细
+	LLMTrace       *LLMTrace                `json:"-"` // 大模型调用记录，大模型参与预测时填写
 }
 
 // DataService 数据服务
@@ -72,43 +48,45 @@ type DataService struct {
 	cache                map[string]*CacheItem
 	cacheMutex           sync.RWMutex
 	httpClient           *resty.Client
-	deepSeekKey          string
-	deepSeekURL          string
+	registry             *InstrumentRegistry       // 标的注册表
+	calendar             *calendar.TradingCalendar // A股交易日历
+	marketData           *ProviderChain            // 行情数据源链
+	healthMonitor        *ProviderHealthMonitor    // 数据源健康监控
+	predictors           *PredictorChain           // 预测模型链
+	probeStop            chan struct{}             // 停止健康探测、行情轮询和密钥监控
+	probeInterval        time.Duration             // 健康探测间隔
+	quoteHub             *QuoteHub                 // 实时报价分发
+	streamInterval       time.Duration             // 实时报价轮询间隔
+	minuteBarIntervals   []int                     // 需要采集的分钟K线周期
+	minuteBarRefresh     time.Duration             // 分钟K线采集间隔
+	keyRefreshInterval   time.Duration             // 大模型 API Key 文件检查间隔
+	backtestSlots        chan struct{}             // 限制同时运行的回测任务数
+	backtestQueue        chan struct{}             // 限制运行中和等待中的回测任务总数
+	llmClients           map[string]*llm.Client    // 大模型客户端（按配置名称）
+	llmDefault           string                    // 默认大模型配置
+	llmIndexProfiles     map[string]string         // 按标的代码指定的大模型配置
+	llmRecorder          *llmRecorder              // 大模型请求录制/回放
+	prompts              *PromptLibrary            // 大模型提示词模板
+	llmMaxAttempts       int                       // 大模型回复未通过校验时最多请求的次数
 	timer                *time.Timer
 	stopChan             chan bool
-	dailyPredictions     map[string]*model.StockIndex // 每日预测缓存
-	dailyPredictionsTime time.Time                    // 预测生成时间
+	dailyPredictions     map[int]map[string]*model.StockIndex // 每日预测缓存（按预测周期、指数代码）
+	dailyPredictionsTime time.Time                            // 预测生成时间
 	dailyMutex           sync.RWMutex
 	db                   *database.DatabaseService // 数据库服务
 }
 
-// StockIndices 股票指数配置
-var StockIndices = map[string]model.StockIndex{
-	"sz399001": {
-		Code:   "sz399001",
-		Name:   "深证成指",
-		Symbol: "399001.SZ",
-		Market: "深圳证券交易所",
-	},
-	"sh000001": {
-		Code:   "sh000001",
-		Name:   "上证综指",
-		Symbol: "000001.SS",
-		Market: "上海证券交易所",
-	},
-	"sz399006": {
-		Code:   "sz399006",
-		Name:   "创业板指",
-		Symbol: "399006.SZ",
-		Market: "深圳证券交易所",
-	},
-	"sh000688": {
-		Code:   "sh000688",
-		Name:   "科创50",
-		Symbol: "000688.SS",
-		Market: "上海证券交易所",
-	},
-}
+// sessionQuoteCacheDuration 交易时段内报价的缓存时间
+const sessionQuoteCacheDuration = 15 * time.Second
+
+// cacheEvictionInterval 清理过期缓存的间隔
+const cacheEvictionInterval = 10 * time.Minute
+
+// predictionHistoryBars 线上预测参考的日K线数（约一个月）
+const predictionHistoryBars = 30
+
+// maxKLineCount 单次请求日K线的最大条数
+const maxKLineCount = 640
 
 // NewDataService 创建数据服务实例
 func NewDataService(cfg *config.Config) *DataService {
@@ -123,18 +101,83 @@ func NewDataService(cfg *config.Config) *DataService {
 		dbService = nil // 确保为 nil
 	}
 
+	httpClient := resty.New().
+		SetTimeout(30 * time.Second).
+		SetRetryCount(3).
+		SetRetryWaitTime(1 * time.Second)
+
+	// 加载标的注册表，失败时无法确定要跟踪的标的，直接退出
+	registry, err := NewInstrumentRegistry(cfg.InstrumentsFile, dbService)
+	if err != nil {
+		log.Fatalf("❌ 加载标的注册表失败: %v", err)
+	}
+
+	tradingCalendar, err := calendar.NewTradingCalendar(cfg.CalendarFile)
+	if err != nil {
+		log.Fatalf("❌ 加载交易日历失败: %v", err)
+	}
+
+	recorder, err := newLLMRecorder(cfg.LLM.Mode, cfg.LLM.RecordDir)
+	if err != nil {
+		log.Fatalf("❌ 初始化大模型录制/回放失败: %v", err)
+	}
+
+	prompts, err := NewPromptLibrary(cfg.LLM.PromptsDir, cfg.LLM.PromptVariants, cfg.LLM.PromptSplit, tradingCalendar.Location())
+	if err != nil {
+		log.Fatalf("❌ 加载提示词模板失败: %v", err)
+	}
+
+	minuteBarIntervals, err := parseMinuteIntervals(cfg.MarketData.MinuteBarIntervals)
+	if err != nil {
+		log.Fatalf("❌ 分钟K线周期配置无效: %v", err)
+	}
+
+	providers := NewMarketDataProviders(cfg, httpClient)
+	healthMonitor := NewProviderHealthMonitor(providers)
+
 	ds := &DataService{
-		cache: make(map[string]*CacheItem),
-		httpClient: resty.New().
-			SetTimeout(30 * time.Second).
-			SetRetryCount(3).
-			SetRetryWaitTime(1 * time.Second),
-		deepSeekKey:      "sk-f3a1fb35364b48adb7a2e9a79160495e",       // DeepSeek API Key
-		deepSeekURL:      "https://api.deepseek.com/chat/completions", // DeepSeek API URL
-		dailyPredictions: make(map[string]*model.StockIndex),
-		stopChan:         make(chan bool),
-		db:               dbService,
+		cache:              make(map[string]*CacheItem),
+		httpClient:         httpClient,
+		registry:           registry,
+		calendar:           tradingCalendar,
+		marketData:         NewProviderChain(providers, cfg.MarketData.QuoteMaxAge, cfg.MarketData.BarsMaxAge, cfg.MarketData.SessionQuoteMaxAge, tradingCalendar, healthMonitor),
+		healthMonitor:      healthMonitor,
+		probeStop:          make(chan struct{}),
+		probeInterval:      cfg.MarketData.HealthProbeInterval,
+		quoteHub:           NewQuoteHub(),
+		streamInterval:     cfg.MarketData.StreamInterval,
+		minuteBarIntervals: minuteBarIntervals,
+		minuteBarRefresh:   cfg.MarketData.MinuteBarRefresh,
+		keyRefreshInterval: cfg.LLM.KeyRefreshInterval,
+		backtestSlots:      make(chan struct{}, maxConcurrentBacktests),
+		backtestQueue:      make(chan struct{}, maxQueuedBacktests),
+		llmRecorder:        recorder,
+		prompts:            prompts,
+		dailyPredictions:   make(map[int]map[string]*model.StockIndex),
+		stopChan:           make(chan bool),
+		db:                 dbService,
+	}
+	if err := ds.initLLMClients(cfg.LLM, httpClient); err != nil {
+		log.Fatalf("❌ 初始化大模型失败: %v", err)
+	}
+	ds.predictors = NewPredictorChain(NewPredictors(cfg, ds))
+
+	log.Printf("📡 行情数据源: %s", ds.marketData.Name())
+	log.Printf("🤖 预测模型: %s", ds.predictors.Name())
+	if ds.llmEnabled() {
+		log.Printf("📝 提示词模板: %s", prompts.Describe())
 	}
+	return ds
+}
+
+// Start 启动后台任务：定时预测、启动时预测、数据源健康探测、缓存清理、实时报价轮询、分钟K线采集和密钥监控
+// 命令行工具（如回测）只需要数据访问，不调用 Start
+func (ds *DataService) Start() {
+	// 补全旧版本预测记录的目标交易日
+	ds.backfillPredictionTargets()
+
+	// 上次运行中断的回测任务标记为失败
+	ds.failInterruptedBacktests()
 
 	// 启动定时任务：每天下午3点10分执行预测（A股收盘后）
 	go ds.startDailyScheduler()
@@ -142,13 +185,30 @@ func NewDataService(cfg *config.Config) *DataService {
 	// 启动时检查是否需要立即执行预测
 	go ds.checkAndPerformInitialPrediction()
 
+	// 启动数据源后台健康探测
+	go ds.startHealthProbes(ds.probeInterval)
+
+	// 定期清理过期缓存
+	go ds.startCacheEviction(cacheEvictionInterval)
+
+	// 交易时段内轮询实时报价并推送给订阅者，同时采集分钟K线
+	go ds.startQuotePoller(ds.streamInterval)
+	go ds.startMinuteBarCollector(ds.minuteBarRefresh)
+
+	// 监控大模型 API Key 文件，支持密钥轮换
+	ds.watchLLMKeys(ds.keyRefreshInterval)
+
 	log.Printf("🔄 定时预测任务已启动，每天下午3点10分执行（A股收盘后）")
-	return ds
 }
 
 // GetStockData 获取股票历史数据
 func (ds *DataService) GetStockData(symbol string, period string) ([]model.StockData, error) {
-	cacheKey := fmt.Sprintf("%s_%s", symbol, period)
+	return ds.getDailyBars(symbol, ds.getPeriodDays(period))
+}
+
+// getDailyBars 获取最近 count 根日K线：优先使用缓存和数据库，数据库缺少交易日时从数据源获取
+func (ds *DataService) getDailyBars(symbol string, count int) ([]model.StockData, error) {
+	cacheKey := fmt.Sprintf("%s_%d", symbol, count)
 
 	// 检查内存缓存
 	if cached, found := ds.getCache(cacheKey); found {
@@ -159,11 +219,8 @@ func (ds *DataService) GetStockData(symbol string, period string) ([]model.Stock
 	// 尝试从数据库获取历史数据
 	if ds.db != nil {
 		// 转换symbol为indexCode
-		indexCode := ds.convertSymbolToIndexCode(symbol)
-		if indexCode != "" {
-			// 根据周期确定天数
-			days := ds.getPeriodDays(period)
-			if dbData, err := ds.db.GetHistoricalData(indexCode, days); err == nil && len(dbData) > 0 {
+		if instrument, exists := ds.registry.FindBySymbol(symbol); exists {
+			if dbData, err := ds.db.GetHistoricalData(instrument.Code, count); err == nil && len(dbData) > 0 && !ds.hasTradingDayGaps(dbData) {
 				log.Printf("📊 从数据库获取历史数据: %s, 数据量: %d", symbol, len(dbData))
 				// 缓存数据
 				ds.setCache(cacheKey, dbData, 5*time.Minute)
@@ -173,7 +230,7 @@ func (ds *DataService) GetStockData(symbol string, period string) ([]model.Stock
 	}
 
 	// 数据库中没有，尝试获取真实数据
-	data, err := ds.fetchRealData(symbol, period)
+	data, err := ds.fetchRealData(symbol, count)
 	if err != nil {
 		return nil, fmt.Errorf("获取真实数据失败: %v", err)
 	}
@@ -187,15 +244,12 @@ func (ds *DataService) GetStockData(symbol string, period string) ([]model.Stock
 
 	// 尝试保存到数据库（异步）
 	if ds.db != nil {
-		indexCode := ds.convertSymbolToIndexCode(symbol)
-		if indexCode != "" {
-			if indexInfo, exists := StockIndices[indexCode]; exists {
-				go func() {
-					if err := ds.db.SaveHistoricalData(indexCode, indexInfo.Name, data); err != nil {
-						log.Printf("保存历史数据到数据库失败 %s: %v", indexCode, err)
-					}
-				}()
-			}
+		if instrument, exists := ds.registry.FindBySymbol(symbol); exists {
+			go func() {
+				if err := ds.db.SaveHistoricalData(instrument.Code, instrument.Name, data); err != nil {
+					log.Printf("保存历史数据到数据库失败 %s: %v", instrument.Code, err)
+				}
+			}()
 		}
 	}
 
@@ -203,261 +257,71 @@ func (ds *DataService) GetStockData(symbol string, period string) ([]model.Stock
 	return data, nil
 }
 
-// fetchRealData 获取真实数据
-func (ds *DataService) fetchRealData(symbol string, period string) ([]model.StockData, error) {
-	// 使用腾讯财经API获取历史数据
-	return ds.fetchFromTencent(symbol, period)
-}
-
-// fetchFromTencent 从腾讯财经API获取数据
-func (ds *DataService) fetchFromTencent(symbol string, period string) ([]model.StockData, error) {
-	// 转换为腾讯财经的股票代码格式
-	tencentSymbol := ds.convertToTencentSymbol(symbol)
-	if tencentSymbol == "" {
-		return nil, fmt.Errorf("不支持的股票代码: %s", symbol)
+// hasTradingDayGaps 检查数据库中的日K线是否缺少交易日（包括最近一个已收盘的交易日）
+func (ds *DataService) hasTradingDayGaps(data []model.StockData) bool {
+	have := make(map[string]bool, len(data))
+	for _, item := range data {
+		have[item.Date.UTC().Format("2006-01-02")] = true
 	}
 
-	// 获取历史K线数据
-	return ds.fetchTencentKLineData(tencentSymbol, period)
-}
-
-// convertToTencentSymbol 转换为腾讯财经格式的股票代码
-func (ds *DataService) convertToTencentSymbol(symbol string) string {
-	symbolMap := map[string]string{
-		"000001.SS": "sh000001", // 上证综指
-		"399001.SZ": "sz399001", // 深证成指
-		"399006.SZ": "sz399006", // 创业板指
-		"000688.SS": "sh000688", // 科创50
-	}
-	return symbolMap[symbol]
-}
+	// 数据库日期按UTC零点保存，对应上海时区的同一天
+	first := data[0].Date.UTC()
+	last := ds.calendar.LastClosedTradingDay(time.Now())
+	lastDate := time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, time.UTC)
 
-// convertSymbolToIndexCode 将symbol转换为indexCode
-func (ds *DataService) convertSymbolToIndexCode(symbol string) string {
-	for code, index := range StockIndices {
-		if index.Symbol == symbol {
-			return code
+	var missing []string
+	for _, day := range ds.calendar.TradingDaysBetween(first, lastDate) {
+		if key := day.UTC().Format("2006-01-02"); !have[key] {
+			missing = append(missing, key)
 		}
 	}
-	return ""
-}
-
-// getPeriodDays 根据周期获取天数
-func (ds *DataService) getPeriodDays(period string) int {
-	switch period {
-	case "1d":
-		return 1
-	case "5d":
-		return 5
-	case "1mo":
-		return 30
-	case "3mo":
-		return 90
-	case "6mo":
-		return 180
-	case "1y":
-		return 365
-	default:
-		return 30 // 默认一个月
-	}
-}
-
-// fetchTencentKLineData 获取腾讯财经K线数据
-func (ds *DataService) fetchTencentKLineData(symbol string, period string) ([]model.StockData, error) {
-	// 腾讯财经历史数据API
-	// 实时数据接口: http://sqt.gtimg.cn/q=股票代码
-	// 历史数据需要通过组合多个接口获取
-
-	// 首先获取当前数据作为基准
-	currentData, err := ds.fetchTencentCurrentData(symbol)
-	if err != nil {
-		return nil, fmt.Errorf("获取当前数据失败: %v", err)
-	}
-
-	// 根据周期确定天数
-	days := 30 // 默认一个月
-	switch period {
-	case "1d":
-		days = 1
-	case "5d":
-		days = 5
-	case "1mo":
-		days = 30
-	case "3mo":
-		days = 90
-	case "6mo":
-		days = 180
-	case "1y":
-		days = 365
-	}
-
-	if days > 250 {
-		days = 250 // 限制最多250天数据
-	}
 
-	return ds.generateHistoryFromCurrent(currentData, days), nil
-}
-
-// fetchTencentCurrentData 获取腾讯财经当前数据
-func (ds *DataService) fetchTencentCurrentData(symbol string) (*model.StockData, error) {
-	// 腾讯财经实时数据API
-	url := fmt.Sprintf("http://sqt.gtimg.cn/q=%s", symbol)
-
-	resp, err := ds.httpClient.R().
-		SetHeader("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36").
-		SetHeader("Referer", "http://gu.qq.com").
-		Get(url)
-
-	if err != nil {
-		return nil, fmt.Errorf("请求失败: %v", err)
-	}
-
-	if resp.StatusCode() != 200 {
-		return nil, fmt.Errorf("HTTP错误: %d", resp.StatusCode())
-	}
-
-	// 解析腾讯财经返回的数据格式
-	// 格式: v_sh000001="1~上证指数~000001~3000.00~2990.00~3010.00~1000000~...";
-	body := resp.String()
-	data, err := ds.parseTencentResponse(body, symbol)
-	if err != nil {
-		return nil, fmt.Errorf("解析数据失败: %v", err)
+	if len(missing) > 0 {
+		log.Printf("📅 数据库历史数据缺少 %d 个交易日（如 %s），从数据源补齐", len(missing), missing[0])
+		return true
 	}
-
-	return data, nil
+	return false
 }
 
-// parseTencentResponse 解析腾讯财经返回的数据
-func (ds *DataService) parseTencentResponse(body, symbol string) (*model.StockData, error) {
-	// 查找数据行
-	// 格式: v_sh000001="数据内容";
-	start := fmt.Sprintf("v_%s=\"", symbol)
-	startIdx := strings.Index(body, start)
-	if startIdx == -1 {
-		return nil, fmt.Errorf("未找到数据")
-	}
-
-	startIdx += len(start)
-	endIdx := strings.Index(body[startIdx:], "\"")
-	if endIdx == -1 {
-		return nil, fmt.Errorf("数据格式错误")
-	}
-
-	dataStr := body[startIdx : startIdx+endIdx]
-	fields := strings.Split(dataStr, "~")
-
-	// 腾讯财经数据字段说明:
-	// 0: 未知  1: 名称  2: 代码  3: 当前价  4: 昨收  5: 今开
-	// 6: 成交量  7: 外盘  8: 内盘  ...
-	if len(fields) < 35 {
-		return nil, fmt.Errorf("数据字段不足")
-	}
-
-	// 解析价格数据
-	currentPrice, err := ds.parseFloat(fields[3])
-	if err != nil {
-		return nil, fmt.Errorf("解析当前价失败: %v", err)
-	}
-
-	yesterdayClose, err := ds.parseFloat(fields[4])
-	if err != nil {
-		return nil, fmt.Errorf("解析昨收价失败: %v", err)
-	}
-
-	todayOpen, err := ds.parseFloat(fields[5])
-	if err != nil {
-		return nil, fmt.Errorf("解析开盘价失败: %v", err)
-	}
-
-	todayHigh, err := ds.parseFloat(fields[33]) // 最高价
-	if err != nil {
-		todayHigh = currentPrice
-	}
-
-	todayLow, err := ds.parseFloat(fields[34]) // 最低价
-	if err != nil {
-		todayLow = currentPrice
-	}
-
-	volume, err := ds.parseInt64(fields[36]) // 成交量
-	if err != nil {
-		volume = 1000000
+// fetchRealData 从数据源获取最近 count 根日K线
+func (ds *DataService) fetchRealData(symbol string, count int) ([]model.StockData, error) {
+	instrument, exists := ds.registry.FindBySymbol(symbol)
+	if !exists {
+		return nil, fmt.Errorf("不支持的股票代码: %s", symbol)
 	}
 
-	// 创建股票数据
-	stockData := &model.StockData{
-		Date:           time.Now(),
-		Open:           todayOpen,
-		High:           todayHigh,
-		Low:            todayLow,
-		Close:          currentPrice,
-		YesterdayClose: yesterdayClose, // 保存昨收价
-		Volume:         volume * 100,   // 腾讯返回的是手数，需要转换为股数
+	if count > maxKLineCount {
+		count = maxKLineCount
 	}
 
-	log.Printf("腾讯财经数据 %s: 当前价=%.2f, 昨收=%.2f, 今开=%.2f", symbol, currentPrice, yesterdayClose, todayOpen)
-	return stockData, nil
+	// 按配置的数据源顺序获取日K线，失败时自动切换
+	return ds.marketData.FetchDailyBars(&instrument, count)
 }
 
-// generateHistoryFromCurrent 基于当前数据生成历史数据
-func (ds *DataService) generateHistoryFromCurrent(currentData *model.StockData, days int) []model.StockData {
-	var data []model.StockData
-	currentPrice := currentData.Close
-
-	for i := 0; i < days; i++ {
-		date := time.Now().AddDate(0, 0, -days+i+1)
-
-		// 生成基于真实数据的历史价格
-		if i == days-1 {
-			// 最后一天使用真实数据
-			data = append(data, *currentData)
-		} else {
-			// 历史数据基于当前价格反推
-			daysFromNow := days - i - 1
-			// 每天随机波动 -2% 到 +2%
-			dailyChange := (rand.Float64() - 0.5) * 0.04
-			// 添加长期趋势（向当前价格收敛）
-			trendFactor := 1.0 - (float64(daysFromNow) * 0.001)
-
-			price := currentPrice * trendFactor * (1 + dailyChange)
-
-			open := price * (0.995 + rand.Float64()*0.01)
-			high := math.Max(open, price) * (1.0 + rand.Float64()*0.02)
-			low := math.Min(open, price) * (0.98 + rand.Float64()*0.02)
-			volume := currentData.Volume * int64(0.5+rand.Float64())
-
-			data = append(data, model.StockData{
-				Date:           date,
-				Open:           math.Round(open*100) / 100,
-				High:           math.Round(high*100) / 100,
-				Low:            math.Round(low*100) / 100,
-				Close:          math.Round(price*100) / 100,
-				YesterdayClose: math.Round((price*0.995)*100) / 100, // 估算昨收价
-				Volume:         volume,
-			})
-		}
-	}
-
-	log.Printf("基于腾讯数据生成历史数据: %d天", len(data))
-	return data
+// periodDays 支持的历史数据周期及对应的天数
+var periodDays = map[string]int{
+	"1d":  1,
+	"5d":  5,
+	"1mo": 30,
+	"3mo": 90,
+	"6mo": 180,
+	"1y":  365,
 }
 
-// parseFloat 解析浮点数
-func (ds *DataService) parseFloat(s string) (float64, error) {
-	s = strings.TrimSpace(s)
-	if s == "" || s == "-" {
-		return 0, fmt.Errorf("空值")
+// ValidatePeriod 校验历史数据周期，可选 1d、5d、1mo、3mo、6mo、1y
+func ValidatePeriod(period string) error {
+	if _, ok := periodDays[period]; !ok {
+		return fmt.Errorf("不支持的周期: %s，可选 1d、5d、1mo、3mo、6mo、1y", period)
 	}
-	return strconv.ParseFloat(s, 64)
+	return nil
 }
 
-// parseInt64 解析整数
-func (ds *DataService) parseInt64(s string) (int64, error) {
-	s = strings.TrimSpace(s)
-	if s == "" || s == "-" {
-		return 0, fmt.Errorf("空值")
+// getPeriodDays 根据周期获取天数，不支持的周期默认一个月
+func (ds *DataService) getPeriodDays(period string) int {
+	if days, ok := periodDays[period]; ok {
+		return days
 	}
-	return strconv.ParseInt(s, 10, 64)
+	return 30
 }
 
 // 删除了generateMockData函数 - 不再使用模拟数据
@@ -488,40 +352,42 @@ func (ds *DataService) GetCurrentPrice(symbol string) (float64, error) {
 func (ds *DataService) GetCurrentStockData(symbol string) (*model.StockData, error) {
 	cacheKey := fmt.Sprintf("stock_data_%s", symbol)
 
-	// 检查缓存
+	// 检查缓存，交易时段内缓存的报价已延迟时重新获取
 	if cached, found := ds.getCache(cacheKey); found {
-		return cached.(*model.StockData), nil
+		if stockData := cached.(*model.StockData); !ds.marketData.SessionStale(stockData, time.Now()) {
+			return stockData, nil
+		}
 	}
 
-	// 转换为腾讯财经的股票代码格式
-	tencentSymbol := ds.convertToTencentSymbol(symbol)
-	if tencentSymbol == "" {
+	instrument, exists := ds.registry.FindBySymbol(symbol)
+	if !exists {
 		return nil, fmt.Errorf("不支持的股票代码: %s", symbol)
 	}
 
-	// 获取腾讯财经实时数据
-	stockData, err := ds.fetchTencentCurrentData(tencentSymbol)
+	// 按配置的数据源顺序获取实时数据，失败时自动切换
+	stockData, err := ds.marketData.FetchQuote(&instrument)
 	if err != nil {
-		return nil, fmt.Errorf("获取腾讯财经数据失败: %v", err)
+		return nil, fmt.Errorf("获取实时数据失败: %v", err)
 	}
 
-	// 缓存数据
-	ds.setCache(cacheKey, stockData, 5*time.Minute)
+	// 缓存数据，交易时段内行情变化快，缩短缓存时间
+	ds.setCache(cacheKey, stockData, ds.quoteCacheDuration(time.Now()))
 	return stockData, nil
 }
 
-// fetchRealCurrentPrice 获取真实当前价格
-func (ds *DataService) fetchRealCurrentPrice(symbol string) (float64, error) {
-	// 转换为腾讯财经的股票代码格式
-	tencentSymbol := ds.convertToTencentSymbol(symbol)
-	if tencentSymbol == "" {
-		return 0, fmt.Errorf("不支持的股票代码: %s", symbol)
+// quoteCacheDuration 报价缓存时间：交易时段内 15 秒，其余时间 5 分钟
+func (ds *DataService) quoteCacheDuration(now time.Time) time.Duration {
+	if ds.calendar.InSession(now) {
+		return sessionQuoteCacheDuration
 	}
+	return 5 * time.Minute
+}
 
-	// 获取腾讯财经实时数据
-	stockData, err := ds.fetchTencentCurrentData(tencentSymbol)
+// fetchRealCurrentPrice 获取真实当前价格
+func (ds *DataService) fetchRealCurrentPrice(symbol string) (float64, error) {
+	stockData, err := ds.GetCurrentStockData(symbol)
 	if err != nil {
-		return 0, fmt.Errorf("获取腾讯财经数据失败: %v", err)
+		return 0, err
 	}
 
 	return stockData.Close, nil
@@ -529,316 +395,143 @@ func (ds *DataService) fetchRealCurrentPrice(symbol string) (float64, error) {
 
 // 删除了generateMockCurrentPrice函数 - 不再使用模拟价格
 
-// CalculateTechnicalIndicators 计算技术指标
-func (ds *DataService) CalculateTechnicalIndicators(data []model.StockData) model.TechnicalIndicators {
-	if len(data) == 0 {
-		return model.TechnicalIndicators{}
-	}
-
-	// 计算移动平均线
-	ma5 := ds.calculateMA(data, 5)
-	ma20 := ds.calculateMA(data, 20)
-
-	// 计算RSI
-	rsi := ds.calculateRSI(data, 14)
-
-	// 计算波动率
-	volatility := ds.calculateVolatility(data)
-
-	// 计算趋势
-	trend := ds.calculateTrend(data)
-
-	return model.TechnicalIndicators{
-		MA5:        math.Round(ma5*100) / 100,
-		MA20:       math.Round(ma20*100) / 100,
-		RSI:        math.Round(rsi*100) / 100,
-		Volatility: math.Round(volatility*100) / 100,
-		Trend:      math.Round(trend*100) / 100,
-	}
-}
-
-// calculateMA 计算移动平均线
-func (ds *DataService) calculateMA(data []model.StockData, period int) float64 {
-	if len(data) < period {
-		period = len(data)
-	}
-
-	var sum float64
-	for i := len(data) - period; i < len(data); i++ {
-		sum += data[i].Close
-	}
-	return sum / float64(period)
-}
-
-// calculateRSI 计算RSI
-func (ds *DataService) calculateRSI(data []model.StockData, period int) float64 {
-	if len(data) < period+1 {
-		return 50.0 // 默认值
-	}
-
-	var gains, losses float64
-	for i := len(data) - period; i < len(data); i++ {
-		if i > 0 {
-			change := data[i].Close - data[i-1].Close
-			if change > 0 {
-				gains += change
-			} else {
-				losses += math.Abs(change)
-			}
-		}
-	}
-
-	avgGain := gains / float64(period)
-	avgLoss := losses / float64(period)
-
-	if avgLoss == 0 {
-		return 100.0
-	}
-
-	rs := avgGain / avgLoss
-	rsi := 100 - (100 / (1 + rs))
-	return rsi
+// CalculateTechnicalIndicators 计算技术指标，只使用传入的数据（回测时传入截至当日的K线即可避免未来数据）
+// 价格类指标按标的最小报价单位取整
+func CalculateTechnicalIndicators(instrument model.Instrument, data []model.StockData) model.TechnicalIndicators {
+	return indicators.Calculate(data, priceDecimals(instrument))
 }
 
-// calculateVolatility 计算波动率
-func (ds *DataService) calculateVolatility(data []model.StockData) float64 {
-	if len(data) < 2 {
-		return 0.0
-	}
-
-	var returns []float64
-	for i := 1; i < len(data); i++ {
-		ret := (data[i].Close - data[i-1].Close) / data[i-1].Close
-		returns = append(returns, ret)
-	}
-
-	// 计算标准差
-	var sum, mean float64
-	for _, ret := range returns {
-		sum += ret
-	}
-	mean = sum / float64(len(returns))
-
-	var variance float64
-	for _, ret := range returns {
-		variance += math.Pow(ret-mean, 2)
-	}
-	variance /= float64(len(returns))
-
-	return math.Sqrt(variance) * 100 // 转换为百分比
+// PredictPriceAndConfidence 预测价格、置信度和预测区间
+func (ds *DataService) PredictPriceAndConfidence(instrument model.Instrument, horizon int, currentPrice float64, indicators model.TechnicalIndicators) (*PredictionResult, error) {
+	return ds.PredictPriceAndConfidenceWithHistory(instrument, horizon, currentPrice, indicators, nil)
 }
 
-// calculateTrend 计算趋势
-func (ds *DataService) calculateTrend(data []model.StockData) float64 {
-	if len(data) < 2 {
-		return 0.0
+// PredictPriceAndConfidenceWithHistory 预测价格、置信度和预测区间（包含历史数据）
+// 按配置的模型链依次尝试，主模型失败时回退到后续模型
+func (ds *DataService) PredictPriceAndConfidenceWithHistory(instrument model.Instrument, horizon int, currentPrice float64, indicators model.TechnicalIndicators, historicalData []model.StockData) (*PredictionResult, error) {
+	result, err := ds.predictors.Predict(PredictionInput{
+		Instrument:   instrument,
+		Horizon:      horizon,
+		CurrentPrice: currentPrice,
+		Indicators:   indicators,
+		History:      historicalData,
+		AsOf:         time.Now(),
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// 简单的线性趋势计算
-	firstPrice := data[0].Close
-	lastPrice := data[len(data)-1].Close
-
-	return ((lastPrice - firstPrice) / firstPrice) * 100
-}
-
-// PredictPriceAndConfidence 预测价格和置信度
-func (ds *DataService) PredictPriceAndConfidence(currentPrice float64, indicators model.TechnicalIndicators) (float64, float64) {
-	return ds.PredictPriceAndConfidenceWithHistory(currentPrice, indicators, nil)
+	log.Printf("预测成功 [%s]: 价格=%.2f, 置信度=%.2f, 80%%区间=[%.2f, %.2f]",
+		result.Model, result.PredictedPrice, result.Confidence, result.Lower80, result.Upper80)
+	return result, nil
 }
 
-// PredictPriceAndConfidenceWithHistory 预测价格和置信度（包含历史数据）
-func (ds *DataService) PredictPriceAndConfidenceWithHistory(currentPrice float64, indicators model.TechnicalIndicators, historicalData []model.StockData) (float64, float64) {
-	// 只使用DeepSeek AI预测，失败则直接返回错误
-	aiPrice, aiConfidence, err := ds.predictWithDeepSeek(currentPrice, indicators, historicalData)
+// predictWithLLM 使用大模型进行股价预测，按标的选择大模型配置
+// asOf 为做出预测的时间，用于确定目标交易日
+func (ds *DataService) predictWithLLM(instrument model.Instrument, horizon int, currentPrice float64, indicators model.TechnicalIndicators, historicalData []model.StockData, asOf time.Time) (*PredictionResult, error) {
+	client, err := ds.llmClientFor(instrument.Code)
 	if err != nil {
-		return 0, 0 // 返回错误的标志值
+		return nil, err
 	}
 
-	log.Printf("DeepSeek AI预测成功: 价格=%.2f, 置信度=%.2f", aiPrice, aiConfidence)
-	return aiPrice, aiConfidence
-}
-
-// predictWithDeepSeek 使用DeepSeek AI进行股价预测
-func (ds *DataService) predictWithDeepSeek(currentPrice float64, indicators model.TechnicalIndicators, historicalData []model.StockData) (float64, float64, error) {
-	// 构建专业的金融分析提示词
-	prompt := ds.buildAnalysisPrompt(currentPrice, indicators, historicalData)
-
-	// 构建请求
-	request := DeepSeekRequest{
-		Model: "deepseek-chat",
-		Messages: []DeepSeekMessage{
-			{
-				Role:    "system",
-				Content: "你是一个专业的股票分析师和量化交易专家，具有丰富的中国股市经验和深度的技术分析能力。你需要基于提供的技术指标和市场数据，给出专业的价格预测。请以JSON格式返回结果，包含预测价格和置信度。",
-			},
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-		MaxTokens:   1000,
-		Temperature: 0.3, // 较低的随机性，更稳定的结果
-		Stream:      false,
-	}
-
-	// 创建带超时的Context
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-
-	// 发送请求到DeepSeek API
-	resp, err := ds.httpClient.R().
-		SetContext(ctx).
-		SetHeader("Authorization", "Bearer "+ds.deepSeekKey).
-		SetHeader("Content-Type", "application/json").
-		SetBody(request).
-		Post(ds.deepSeekURL)
-
+	// 按 A/B 分组选择提示词模板，构建专业的金融分析提示词
+	promptTemplate := ds.prompts.Select(instrument.Code, asOf)
+	targetDate := ds.calendar.AddTradingDays(asOf, horizon).In(ds.calendar.Location()).Format("2006-01-02")
+	systemPrompt, prompt, err := promptTemplate.Render(newPromptData(instrument, horizon, currentPrice, indicators, historicalData, targetDate))
 	if err != nil {
-		return 0, 0, fmt.Errorf("请求DeepSeek API失败: %v", err)
+		return nil, err
 	}
 
-	if resp.StatusCode() != 200 {
-		return 0, 0, fmt.Errorf("DeepSeek API返回错误: %d, 响应: %s", resp.StatusCode(), resp.String())
+	messages := []llm.Message{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: prompt},
 	}
-
-	// 解析响应
-	var deepSeekResp DeepSeekResponse
-	if err := json.Unmarshal(resp.Body(), &deepSeekResp); err != nil {
-		return 0, 0, fmt.Errorf("解析DeepSeek响应失败: %v", err)
+	trace := &LLMTrace{
+		Profile:       client.Name(),
+		Model:         client.Model(),
+		PromptVersion: promptTemplate.ID(),
+		SystemPrompt:  systemPrompt,
+		Prompt:        prompt,
 	}
 
-	if len(deepSeekResp.Choices) == 0 {
-		return 0, 0, fmt.Errorf("DeepSeek响应中没有选择项")
-	}
+	// 回复不符合格式或超出合理范围时，附上拒绝原因要求模型修正
+	var reasons []string
+	for attempt := 1; attempt <= ds.llmMaxAttempts; attempt++ {
+		request := client.NewRequest(messages...)
+		request.ResponseFormat = client.JSONResponse(llmPredictionSchemaName, llmPredictionSchema)
 
-	// 解析AI的预测结果
-	result, err := ds.parseAIPrediction(deepSeekResp.Choices[0].Message.Content)
-	if err != nil {
-		return 0, 0, fmt.Errorf("解析AI预测结果失败: %v", err)
-	}
+		started := time.Now()
+		body, err := ds.sendLLMRequest(client, request)
+		trace.Latency += time.Since(started)
+		trace.Attempts = attempt
+		if err != nil {
+			return nil, err
+		}
 
-	log.Printf("DeepSeek AI预测结果: %+v", result)
-	return result.PredictedPrice, result.Confidence, nil
-}
+		response, err := llm.ParseResponse(body)
+		if err != nil {
+			return nil, err
+		}
+		trace.addUsage(response.Usage)
 
-// buildAnalysisPrompt 构建分析提示词
-func (ds *DataService) buildAnalysisPrompt(currentPrice float64, indicators model.TechnicalIndicators, historicalData []model.StockData) string {
-	// 历史数据简要
-	historyInfo := ""
-	if historicalData != nil && len(historicalData) > 0 {
-		recentDays := len(historicalData)
-		if recentDays > 10 {
-			recentDays = 10 // 只取最近10天数据
+		content := response.Content()
+		result, err := parseLLMPrediction(content)
+		if err == nil {
+			err = validateLLMPrediction(result, instrument, currentPrice, horizon)
 		}
-		historyInfo = fmt.Sprintf("最近%d天的价格走势:", recentDays)
-		for i := len(historicalData) - recentDays; i < len(historicalData); i++ {
-			data := historicalData[i]
-			historyInfo += fmt.Sprintf("\n- %s: 开盘%.2f, 最高%.2f, 最低%.2f, 收盘%.2f",
-				data.Date.Format("01-02"), data.Open, data.High, data.Low, data.Close)
+		if err == nil {
+			log.Printf("大模型预测结果 [%s/%s] 第%d次, 耗时%dms, tokens=%d: 价格=%.2f, 方向=%s",
+				client.Name(), client.Model(), attempt, trace.Latency.Milliseconds(), trace.Usage.TotalTokens, result.PredictedPrice, result.Direction)
+			trace.Reasoning = result.Reasoning
+			trace.Direction = result.Direction
+			trace.KeyFactors = result.KeyFactors
+			result.LLMTrace = trace
+			return result, nil
 		}
-		historyInfo += "\n\n"
-	}
-
-	prompt := fmt.Sprintf(`作为一名专业的股票分析师，请你基于以下数据对中国股票指数进行明日价格预测：
-
-**当前价格**: %.2f
-
-**技术指标**:
-- 5日移动平均线(MA5): %.2f
-- 20日移动平均线(MA20): %.2f
-- 相对强弱指数(RSI): %.2f
-- 波动率(Volatility): %.2f%%
-- 趋势指标(Trend): %.2f%%
 
-%s**分析要求**:
-1. 请综合考虑技术指标的信号意义
-2. MA5与MA20的位置关系反映短期趋势
-3. RSI数值判断超买超卖情况（<30超卖，>70超买）
-4. 波动率反映市场风险程度
-5. 趋势指标显示整体方向
-
-**输出格式**:
-请以下列JSON格式返回预测结果：
-{
-  "predicted_price": 明日预测价格(数值),
-  "confidence": 置信度(0-100之间的数值),
-  "reasoning": "预测理由和分析过程"
-}
-
-注意：预测价格应该在当前价格的±5%%范围内，置信度基于技术指标的一致性评定。`,
-		currentPrice,
-		indicators.MA5,
-		indicators.MA20,
-		indicators.RSI,
-		indicators.Volatility,
-		indicators.Trend,
-		historyInfo)
+		ds.recordLLMRejection(client, instrument.Code, horizon, attempt, err, content)
+		reasons = append(reasons, err.Error())
+		messages = append(messages, llm.Message{Role: "assistant", Content: content}, correctiveMessage(err))
+	}
 
-	return prompt
+	return nil, fmt.Errorf("大模型回复连续 %d 次未通过校验: %s", ds.llmMaxAttempts, strings.Join(reasons, "; "))
 }
 
-// parseAIPrediction 解析AI预测结果
-func (ds *DataService) parseAIPrediction(content string) (*PredictionResult, error) {
-	// 尝试直接解析JSON
-	var result PredictionResult
-	if err := json.Unmarshal([]byte(content), &result); err == nil {
-		// 验证结果合理性
-		if result.PredictedPrice > 0 && result.Confidence >= 0 && result.Confidence <= 100 {
-			return &result, nil
-		}
+// sendLLMRequest 发送请求并返回原始响应体
+// 录制模式下保存请求和响应，回放模式下只从录制文件读取，不访问网络
+func (ds *DataService) sendLLMRequest(client *llm.Client, request llm.ChatRequest) ([]byte, error) {
+	if ds.llmRecorder.mode == LLMModeReplay {
+		return ds.llmRecorder.load(request)
 	}
 
-	// 如果JSON解析失败，尝试提取JSON块
-	start := strings.Index(content, "{")
-	end := strings.LastIndex(content, "}")
-	if start != -1 && end != -1 && end > start {
-		jsonStr := content[start : end+1]
-		if err := json.Unmarshal([]byte(jsonStr), &result); err == nil {
-			if result.PredictedPrice > 0 && result.Confidence >= 0 && result.Confidence <= 100 {
-				return &result, nil
-			}
-		}
+	body, err := client.Send(context.Background(), request)
+	if err != nil {
+		return nil, err
 	}
 
-	// 如果都失败，尝试从文本中提取数字
-	priceMatches := strings.Split(content, "predicted_price")
-	confidenceMatches := strings.Split(content, "confidence")
-
-	if len(priceMatches) > 1 && len(confidenceMatches) > 1 {
-		// 简单提取数字
-		priceStr := strings.Fields(strings.Split(priceMatches[1], ",")[0])[0]
-		priceStr = strings.Trim(priceStr, ":,\" ")
-		confidenceStr := strings.Fields(strings.Split(confidenceMatches[1], ",")[0])[0]
-		confidenceStr = strings.Trim(confidenceStr, ":,\" ")
-
-		if price, err := strconv.ParseFloat(priceStr, 64); err == nil {
-			if confidence, err := strconv.ParseFloat(confidenceStr, 64); err == nil {
-				return &PredictionResult{
-					PredictedPrice: price,
-					Confidence:     confidence,
-					Reasoning:      "从文本中提取的预测结果",
-				}, nil
-			}
+	if ds.llmRecorder.mode == LLMModeRecord {
+		if err := ds.llmRecorder.save(request, body); err != nil {
+			log.Printf("⚠️ %v", err)
 		}
 	}
 
-	return nil, fmt.Errorf("无法解析AI预测结果: %s", content)
+	return body, nil
 }
 
 // 删除了fallbackPredict函数 - 不再使用传统预测算法
 
-// GetPredictionData 获取预测数据
-func (ds *DataService) GetPredictionData(indexCode string) (*model.StockIndex, error) {
+// GetPredictionData 获取指定预测周期的预测数据
+func (ds *DataService) GetPredictionData(indexCode string, horizon int) (*model.StockIndex, error) {
 	// 优先从数据库获取今日预测数据
 	if ds.db != nil {
-		if record, err := ds.db.GetTodayPrediction(indexCode); err == nil && record != nil {
+		if record, err := ds.db.GetTodayPrediction(indexCode, ds.currentTradeDate(), horizon); err == nil && record != nil {
 			log.Printf("📊 从数据库获取今日预测: %s", indexCode)
 			return ds.db.ConvertPredictionToStockIndex(record), nil
 		}
 	}
 
 	// 数据库中没有，尝试从日常预测缓存获取
-	if dailyPredictions, predictTime, ok := ds.GetDailyPredictions(); ok {
+	if dailyPredictions, predictTime, ok := ds.GetDailyPredictions(horizon); ok {
 		if prediction, exists := dailyPredictions[indexCode]; exists {
 			log.Printf("📊 从日常预测缓存获取 %s (预测时间: %s)", indexCode, predictTime.Format("2006-01-02 15:04:05"))
 			return prediction, nil
@@ -847,14 +540,14 @@ func (ds *DataService) GetPredictionData(indexCode string) (*model.StockIndex, e
 
 	// 都没有，则实时计算（作为回退机制）
 	log.Printf("⚠️ 数据库和缓存中未找到 %s，使用实时预测", indexCode)
-	return ds.generateSinglePrediction(indexCode)
+	return ds.generateSinglePrediction(indexCode, horizon)
 }
 
-// GetAllPredictions 获取所有预测数据
-func (ds *DataService) GetAllPredictions() (map[string]*model.StockIndex, error) {
+// GetAllPredictions 获取所有指数指定预测周期的预测数据
+func (ds *DataService) GetAllPredictions(horizon int) (map[string]*model.StockIndex, error) {
 	// 优先从数据库获取今日所有预测数据
 	if ds.db != nil {
-		if records, err := ds.db.GetAllTodayPredictions(); err == nil && len(records) > 0 {
+		if records, err := ds.db.GetAllTodayPredictions(ds.currentTradeDate(), horizon); err == nil && len(records) > 0 {
 			log.Printf("📊 从数据库获取所有今日预测, 数量: %d", len(records))
 			result := make(map[string]*model.StockIndex)
 			for code, record := range records {
@@ -865,7 +558,7 @@ func (ds *DataService) GetAllPredictions() (map[string]*model.StockIndex, error)
 	}
 
 	// 数据库中没有，尝试从日常预测缓存获取
-	if dailyPredictions, predictTime, ok := ds.GetDailyPredictions(); ok {
+	if dailyPredictions, predictTime, ok := ds.GetDailyPredictions(horizon); ok {
 		log.Printf("📊 从日常预测缓存获取所有指数 (预测时间: %s)", predictTime.Format("2006-01-02 15:04:05"))
 		return dailyPredictions, nil
 	}
@@ -874,8 +567,9 @@ func (ds *DataService) GetAllPredictions() (map[string]*model.StockIndex, error)
 	log.Printf("⚠️ 数据库和缓存为空，使用实时预测")
 	predictions := make(map[string]*model.StockIndex)
 
-	for code := range StockIndices {
-		prediction, err := ds.generateSinglePrediction(code)
+	for _, instrument := range ds.registry.List(false) {
+		code := instrument.Code
+		prediction, err := ds.generateSinglePrediction(code, horizon)
 		if err != nil {
 			log.Printf("获取预测数据失败 %s: %v", code, err)
 			continue
@@ -889,7 +583,7 @@ func (ds *DataService) GetAllPredictions() (map[string]*model.StockIndex, error)
 
 // GetHistoryData 获取历史数据
 func (ds *DataService) GetHistoryData(indexCode string, period string) ([]model.HistoryData, error) {
-	index, exists := StockIndices[indexCode]
+	index, exists := ds.registry.Get(indexCode)
 	if !exists {
 		return nil, fmt.Errorf("指数不存在: %s", indexCode)
 	}
@@ -916,7 +610,7 @@ func (ds *DataService) GetHistoryData(indexCode string, period string) ([]model.
 
 // GetIndexInfo 获取指数基本信息
 func (ds *DataService) GetIndexInfo(indexCode string) (*model.IndexInfo, error) {
-	index, exists := StockIndices[indexCode]
+	index, exists := ds.registry.Get(indexCode)
 	if !exists {
 		return nil, fmt.Errorf("指数不存在: %s", indexCode)
 	}
@@ -934,16 +628,20 @@ func (ds *DataService) GetIndexInfo(indexCode string) (*model.IndexInfo, error)
 	change := currentPrice - yesterdayClose
 	changePercent := (change / yesterdayClose) * 100
 
+	now := time.Now()
 	return &model.IndexInfo{
 		Code:          index.Code,
 		Name:          index.Name,
 		Symbol:        index.Symbol,
 		Market:        index.Market,
-		Price:         math.Round(currentPrice*100) / 100,
-		Change:        math.Round(change*100) / 100,
+		Price:         roundPrice(currentPrice, index),
+		Change:        roundPrice(change, index),
 		ChangePercent: math.Round(changePercent*100) / 100,
 		Volume:        currentStockData.Volume,
-		Timestamp:     time.Now().UTC().Format(time.RFC3339),
+		Timestamp:     currentStockData.Date.UTC().Format(time.RFC3339),
+		MarketState:   ds.calendar.MarketState(now),
+		QuoteAge:      int64(now.Sub(currentStockData.Date).Seconds()),
+		Stale:         ds.marketData.QuoteStale(currentStockData, now),
 	}, nil
 }
 
@@ -951,7 +649,8 @@ func (ds *DataService) GetIndexInfo(indexCode string) (*model.IndexInfo, error)
 func (ds *DataService) GetAllIndicesInfo() (map[string]*model.IndexInfo, error) {
 	indicesInfo := make(map[string]*model.IndexInfo)
 
-	for code := range StockIndices {
+	for _, instrument := range ds.registry.List(false) {
+		code := instrument.Code
 		info, err := ds.GetIndexInfo(code)
 		if err != nil {
 			log.Printf("获取指数信息失败 %s: %v", code, err)
@@ -963,39 +662,32 @@ func (ds *DataService) GetAllIndicesInfo() (map[string]*model.IndexInfo, error)
 	return indicesInfo, nil
 }
 
-// GetDataSourceStatus 获取数据源状态
+// GetDataSourceStatus 获取数据源状态（返回后台探测的快照，不发起网络请求）
 func (ds *DataService) GetDataSourceStatus() *model.DataSourceStatus {
 	status := &model.DataSourceStatus{
-		Recommendation: "使用智能模拟数据",
+		Providers: ds.healthMonitor.Snapshot(),
+		CheckedAt: time.Now().UTC().Format(time.RFC3339),
 	}
 
-	// 测试Yahoo Finance网站连接
-	resp, err := ds.httpClient.R().Get("https://finance.yahoo.com")
-	if err != nil {
-		status.YahooFinanceWebsite.Status = "error"
-		status.YahooFinanceWebsite.Error = err.Error()
-	} else if resp.StatusCode() == 200 {
-		status.YahooFinanceWebsite.Status = "connected"
-	} else if resp.StatusCode() == 429 {
-		status.YahooFinanceWebsite.Status = "rate_limited"
-		status.YahooFinanceWebsite.Error = "请求频率过高，被限制访问"
-	} else {
-		status.YahooFinanceWebsite.Status = "error"
-		status.YahooFinanceWebsite.Error = fmt.Sprintf("HTTP %d", resp.StatusCode())
+	// 按优先级选出第一个可用的数据源
+	activeStatus := ""
+	for _, provider := range status.Providers {
+		if provider.Status == "healthy" || provider.Status == "degraded" {
+			status.Active = provider.Name
+			activeStatus = provider.Status
+			break
+		}
 	}
 
-	// 测试yfinance（模拟）
-	status.YFinance.Status = "no_data"
-	status.YFinance.Error = "无法获取数据"
-	status.YFinance.TestSymbol = "AAPL"
-
-	// 根据状态给出建议
-	if status.YFinance.Status == "working" {
-		status.Recommendation = "yfinance正常工作，可以获取真实数据"
-	} else if status.YahooFinanceWebsite.Status == "rate_limited" {
-		status.Recommendation = "Yahoo Finance API被限制，建议使用智能模拟数据"
-	} else {
-		status.Recommendation = "网络连接问题，使用智能模拟数据"
+	switch {
+	case status.Active == "":
+		status.Recommendation = "所有行情数据源均不可用，请检查网络连接"
+	case status.Active != status.Providers[0].Name:
+		status.Recommendation = fmt.Sprintf("首选数据源 %s 异常，已切换至 %s", status.Providers[0].Name, status.Active)
+	case activeStatus == "degraded":
+		status.Recommendation = fmt.Sprintf("数据源 %s 最近出现失败，请关注", status.Active)
+	default:
+		status.Recommendation = fmt.Sprintf("数据源 %s 工作正常", status.Active)
 	}
 
 	return status
@@ -1035,6 +727,38 @@ func (ds *DataService) setCache(key string, data interface{}, duration time.Dura
 	}
 }
 
+// startCacheEviction 定期删除过期的缓存项，避免只写不读的缓存键（如按K线日期区分的指标序列）一直占用内存
+func (ds *DataService) startCacheEviction(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if removed := ds.evictExpiredCache(time.Now()); removed > 0 {
+				log.Printf("🗑️ 已清理 %d 个过期缓存", removed)
+			}
+		case <-ds.probeStop:
+			return
+		}
+	}
+}
+
+// evictExpiredCache 删除在 now 之前过期的缓存项，返回删除的数量
+func (ds *DataService) evictExpiredCache(now time.Time) int {
+	ds.cacheMutex.Lock()
+	defer ds.cacheMutex.Unlock()
+
+	removed := 0
+	for key, item := range ds.cache {
+		if now.After(item.ExpiresAt) {
+			delete(ds.cache, key)
+			removed++
+		}
+	}
+	return removed
+}
+
 // ClearCache 清除缓存
 func (ds *DataService) ClearCache() {
 	ds.cacheMutex.Lock()
@@ -1078,9 +802,12 @@ func (ds *DataService) startDailyScheduler() {
 			nextRun = time.Date(now.Year(), now.Month(), now.Day(), 15, 10, 0, 0, shanghaiLoc)
 		}
 
-		// 检查是否是工作日（周一到周五）
-		for !ds.isWeekday(nextRun) {
-			nextRun = nextRun.AddDate(0, 0, 1)
+		// 跳过周末、节假日和调休上班的周末
+		if !ds.calendar.IsTradingDay(nextRun) {
+			nextRun = ds.calendar.NextTradingDay(nextRun)
+		}
+		if !ds.calendar.Covers(nextRun) {
+			log.Printf("⚠️ 交易日历未覆盖 %d 年，仅按周末判断交易日，请及时更新节假日数据", nextRun.Year())
 		}
 
 		duration := nextRun.Sub(now)
@@ -1103,48 +830,53 @@ func (ds *DataService) startDailyScheduler() {
 	}
 }
 
-// isWeekday 检查是否是工作日（周一到周五，使用上海时区）
-func (ds *DataService) isWeekday(t time.Time) bool {
-	// 确保使用上海时区进行判断
-	shanghaiLoc, _ := time.LoadLocation("Asia/Shanghai")
-	localTime := t.In(shanghaiLoc)
-	weekday := localTime.Weekday()
-	return weekday != time.Saturday && weekday != time.Sunday
-}
-
 // performDailyPrediction 执行每日预测任务
 func (ds *DataService) performDailyPrediction() {
 	log.Printf("🤖 开始执行每日预测任务...")
 	start := time.Now()
 
-	// 首先验证昨天的预测结果
-	ds.validatePreviousPredictions()
+	// 首先验证目标交易日已收盘的预测结果（包括之前遗漏的记录）
+	ds.validatePendingPredictions()
+
+	// 非交易日行情不会变化，只刷新内存缓存，不写入数据库
+	isTradingDay := ds.calendar.IsTradingDay(time.Now())
+	if !isTradingDay {
+		log.Printf("📅 今天不是交易日，预测结果不保存到数据库")
+	}
 
-	newPredictions := make(map[string]*model.StockIndex)
+	newPredictions := make(map[int]map[string]*model.StockIndex)
+	for _, horizon := range PredictionHorizons {
+		newPredictions[horizon] = make(map[string]*model.StockIndex)
+	}
 	successCount := 0
 	failedCount := 0
 
 	// 逐个预测每个指数
-	for indexCode := range StockIndices {
-		log.Printf("📊 正在预测 %s...", indexCode)
-
-		prediction, err := ds.generateSinglePrediction(indexCode)
-		if err != nil {
-			log.Printf("❌ %s 预测失败: %v", indexCode, err)
-			failedCount++
-			// 即使某个指数预测失败，也继续其他指数
-			continue
-		}
-
-		newPredictions[indexCode] = prediction
-		successCount++
-		log.Printf("✅ %s 预测成功: 当前=%.2f, 预测=%.2f, 置信度=%.1f%%",
-			indexCode, prediction.Current, prediction.Predicted, prediction.Confidence)
+	for _, instrument := range ds.registry.List(false) {
+		indexCode := instrument.Code
+		for _, horizon := range PredictionHorizons {
+			log.Printf("📊 正在预测 %s (%s)...", indexCode, horizonLabel(horizon))
+
+			prediction, err := ds.generateSinglePrediction(indexCode, horizon)
+			if err != nil {
+				log.Printf("❌ %s (%s) 预测失败: %v", indexCode, horizonLabel(horizon), err)
+				failedCount++
+				// 即使某个指数预测失败，也继续其他指数
+				continue
+			}
 
-		// 保存到数据库
-		if ds.db != nil {
-			if err := ds.db.SavePrediction(prediction); err != nil {
-				log.Printf("⚠️ 保存预测数据到数据库失败 %s: %v", indexCode, err)
+			newPredictions[horizon][indexCode] = prediction
+			successCount++
+			log.Printf("✅ %s (%s) 预测成功: 当前=%.2f, 预测=%.2f, 置信度=%.1f%%",
+				indexCode, horizonLabel(horizon), prediction.Current, prediction.Predicted, prediction.Confidence)
+
+			// 保存到数据库
+			if ds.db != nil && isTradingDay {
+				tradeDate, _ := time.Parse("2006-01-02", prediction.TradeDate)
+				targetDate, _ := time.Parse("2006-01-02", prediction.TargetDate)
+				if err := ds.db.SavePrediction(prediction, tradeDate, targetDate); err != nil {
+					log.Printf("⚠️ 保存预测数据到数据库失败 %s: %v", indexCode, err)
+				}
 			}
 		}
 
@@ -1166,60 +898,181 @@ func (ds *DataService) performDailyPrediction() {
 	ds.ClearCache()
 }
 
-// validatePreviousPredictions 验证昨天的预测结果
-func (ds *DataService) validatePreviousPredictions() {
+// validatePendingPredictions 验证所有目标交易日已收盘但尚未验证的预测
+// 以 historical_data 中目标交易日的收盘价为准，缺少时先从数据源同步日K线
+func (ds *DataService) validatePendingPredictions() {
 	if ds.db == nil {
 		return
 	}
 
-	// 计算昨天的日期
-	yesterday := time.Now().UTC().AddDate(0, 0, -1).Truncate(24 * time.Hour)
-
-	// 获取昨天的预测记录
-	records, err := ds.db.GetHistoricalPredictionsForDate(yesterday)
+	records, err := ds.db.GetPendingPredictions()
 	if err != nil {
-		log.Printf("❌ 获取昨天预测记录失败: %v", err)
+		log.Printf("❌ 获取待验证预测记录失败: %v", err)
 		return
 	}
 
-	// 遍历每个预测记录，验证其准确性
+	// 最近一个已收盘交易日（数据库中按UTC零点保存）
+	lastClosed := toDateUTC(ds.calendar.LastClosedTradingDay(time.Now()), ds.calendar.Location())
+	synced := make(map[string]bool)
+	validatedCount := 0
+
 	for _, record := range records {
-		// 获取当前价格（实际的第二天价格）
-		currentStockData, err := ds.GetCurrentStockData(StockIndices[record.IndexCode].Symbol)
+		targetDate := toDateUTC(ds.calendar.AddTradingDays(record.PredictionDate, 1), ds.calendar.Location())
+		if record.TargetDate != nil {
+			targetDate = *record.TargetDate
+		}
+		if targetDate.After(lastClosed) {
+			continue // 目标交易日尚未收盘
+		}
+
+		bar, err := ds.db.GetHistoricalClose(record.IndexCode, targetDate)
 		if err != nil {
-			log.Printf("❌ 获取 %s 当前价格失败: %v", record.IndexCode, err)
+			log.Printf("❌ 获取 %s %s 收盘价失败: %v", record.IndexCode, targetDate.Format("2006-01-02"), err)
 			continue
 		}
 
-		currentPrice := currentStockData.Close
+		// 数据库中没有目标交易日的K线时，同步一次日K线后重试
+		if bar == nil && !synced[record.IndexCode] {
+			synced[record.IndexCode] = true
+			if err := ds.syncDailyBars(record.IndexCode, targetDate); err != nil {
+				log.Printf("❌ 同步 %s 日K线失败: %v", record.IndexCode, err)
+				continue
+			}
+			if bar, err = ds.db.GetHistoricalClose(record.IndexCode, targetDate); err != nil {
+				log.Printf("❌ 获取 %s %s 收盘价失败: %v", record.IndexCode, targetDate.Format("2006-01-02"), err)
+				continue
+			}
+		}
+
+		if bar == nil {
+			log.Printf("⚠️ %s 缺少 %s 的收盘价（可能停牌），暂不验证", record.IndexCode, targetDate.Format("2006-01-02"))
+			continue
+		}
+
+		actualPrice := bar.Close
+		actualChange := actualPrice - record.CurrentPrice
+		actualChangePercent := 0.0
+		if record.CurrentPrice > 0 {
+			actualChangePercent = actualChange / record.CurrentPrice * 100
+		}
 
 		// 判断预测是否正确
 		// 预测正确的定义：预测涨跌方向与实际涨跌方向一致
 		predictedDirection := record.PredictedPrice - record.CurrentPrice
-		actualDirection := currentPrice - record.CurrentPrice
+		isCorrect := (predictedDirection * actualChange) > 0
+
+		// 区间覆盖：实际收盘价是否落在80%/95%预测区间内
+		covered80, covered95 := intervalCoverage(record, actualPrice)
+
+		// 已移出注册表的标的按两位小数取整
+		instrument, _ := ds.registry.Lookup(record.IndexCode)
+		validation := model.PredictionValidation{
+			IsCorrect:           isCorrect,
+			ActualPrice:         roundPrice(actualPrice, instrument),
+			ActualChange:        roundPrice(actualChange, instrument),
+			ActualChangePercent: round2(actualChangePercent),
+			Covered80:           covered80,
+			Covered95:           covered95,
+		}
+		if err := ds.db.UpdatePredictionValidation(record.ID, validation); err != nil {
+			log.Printf("❌ 更新 %s 预测验证结果失败: %v", record.IndexCode, err)
+			continue
+		}
 
-		isCorrect := (predictedDirection * actualDirection) > 0
+		validatedCount++
+		log.Printf("✅ 验证 %s %s 的预测: 预测价格=%.2f, %s收盘=%.2f, 预测%s",
+			record.IndexCode, record.PredictionDate.Format("2006-01-02"), record.PredictedPrice,
+			targetDate.Format("2006-01-02"), actualPrice, map[bool]string{true: "正确", false: "错误"}[isCorrect])
+	}
 
-		// 更新数据库中的预测记录
-		if err := ds.db.UpdatePredictionAccuracy(record.ID, isCorrect); err != nil {
-			log.Printf("❌ 更新 %s 预测准确性失败: %v", record.IndexCode, err)
-			continue
+	if validatedCount > 0 {
+		log.Printf("📋 本次共验证 %d 条预测记录", validatedCount)
+	}
+}
+
+// backfillPredictionTargets 为缺少目标交易日的旧预测记录补全交易日和目标交易日
+// 旧记录的预测日期即做出预测的交易日，目标为其下一个交易日
+func (ds *DataService) backfillPredictionTargets() {
+	if ds.db == nil {
+		return
+	}
+
+	records, err := ds.db.GetPredictionsWithoutTarget()
+	if err != nil {
+		log.Printf("❌ 查询缺少目标交易日的预测记录失败: %v", err)
+		return
+	}
+
+	for _, record := range records {
+		horizon := record.Horizon
+		if horizon <= 0 {
+			horizon = 1
 		}
 
-		log.Printf("✅ 验证 %s 预测结果: 预测价格=%.2f, 实际价格=%.2f, 预测%s",
-			record.IndexCode, record.PredictedPrice, currentPrice, map[bool]string{true: "正确", false: "错误"}[isCorrect])
+		tradeDate := toDateUTC(record.PredictionDate, time.UTC)
+		if record.TradeDate != nil {
+			tradeDate = *record.TradeDate
+		}
+		targetDate := toDateUTC(ds.calendar.AddTradingDays(tradeDate, horizon), ds.calendar.Location())
+
+		if err := ds.db.UpdatePredictionTarget(record.ID, tradeDate, targetDate, horizon); err != nil {
+			log.Printf("❌ 补全 %s 预测目标交易日失败: %v", record.IndexCode, err)
+		}
+	}
+
+	if len(records) > 0 {
+		log.Printf("📅 已为 %d 条旧预测记录补全目标交易日", len(records))
 	}
 }
 
-// generateSinglePrediction 生成单个指数的预测（专用于定时任务）
-func (ds *DataService) generateSinglePrediction(indexCode string) (*model.StockIndex, error) {
-	index, exists := StockIndices[indexCode]
+// syncDailyBars 从数据源同步日K线到数据库，覆盖从 since 到最近一个交易日
+func (ds *DataService) syncDailyBars(indexCode string, since time.Time) error {
+	instrument, exists := ds.registry.Lookup(indexCode)
+	if !exists {
+		return fmt.Errorf("%s 已不在标的注册表中", indexCode)
+	}
+
+	// 按交易日数估算需要的K线条数，多取几条以防数据源日期偏差
+	count := len(ds.calendar.TradingDaysBetween(since, time.Now())) + 5
+	if count > maxKLineCount {
+		count = maxKLineCount
+	}
+
+	bars, err := ds.marketData.FetchDailyBars(&instrument, count)
+	if err != nil {
+		return err
+	}
+
+	return ds.db.SaveHistoricalData(instrument.Code, instrument.Name, bars)
+}
+
+// predictionHistoryCount 线上预测获取的日K线数：约一个月，且不少于指标快照中所有指标所需的K线数（如 MACD 需要 34 根）
+// 回测的默认历史窗口与之一致
+func predictionHistoryCount() int {
+	return max(predictionHistoryBars, indicators.SnapshotRequired())
+}
+
+// currentTradeDate 当前的预测交易日（上海时区的日期，按UTC零点表示），与保存预测时的 trade_date 一致
+func (ds *DataService) currentTradeDate() time.Time {
+	return toDateUTC(time.Now(), ds.calendar.Location())
+}
+
+// toDateUTC 将某时区下的日期转换为UTC零点（与数据库中的日期约定一致）
+func toDateUTC(t time.Time, location *time.Location) time.Time {
+	local := t.In(location)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// generateSinglePrediction 生成单个指数指定预测周期的预测（专用于定时任务）
+func (ds *DataService) generateSinglePrediction(indexCode string, horizon int) (*model.StockIndex, error) {
+	instrument, exists := ds.registry.Get(indexCode)
 	if !exists {
 		return nil, fmt.Errorf("指数不存在: %s", indexCode)
 	}
+	index := newStockIndex(instrument)
 
-	// 获取历史数据
-	historicalData, err := ds.GetStockData(index.Symbol, "1mo")
+	// 获取历史数据，K线数需足够计算提示词中的所有技术指标
+	historicalData, err := ds.getDailyBars(index.Symbol, predictionHistoryCount())
 	if err != nil {
 		return nil, fmt.Errorf("获取历史数据失败: %v", err)
 	}
@@ -1233,12 +1086,27 @@ func (ds *DataService) generateSinglePrediction(indexCode string) (*model.StockI
 	currentPrice := currentStockData.Close
 
 	// 计算技术指标
-	indicators := ds.CalculateTechnicalIndicators(historicalData)
+	indicators := CalculateTechnicalIndicators(instrument, historicalData)
+
+	// 预测价格、置信度和预测区间（传入历史数据）
+	result, err := ds.PredictPriceAndConfidenceWithHistory(instrument, horizon, currentPrice, indicators, historicalData)
+	if err != nil {
+		return nil, fmt.Errorf("预测失败: %v", err)
+	}
+	predictedPrice := result.PredictedPrice
+	confidence := result.Confidence
+
+	// 大模型给出的区间不一致时按历史波动率计算
+	interval := resolvePredictionInterval(result, instrument, currentPrice, indicators.Volatility, horizon)
 
-	// 预测价格和置信度（传入历史数据）
-	predictedPrice, confidence := ds.PredictPriceAndConfidenceWithHistory(currentPrice, indicators, historicalData)
-	if predictedPrice == 0 && confidence == 0 {
-		return nil, fmt.Errorf("DeepSeek AI预测失败")
+	// 个股和ETF的预测价格和区间不能超出连续涨跌停的价格范围
+	if index.PriceLimit > 0 {
+		lowerLimit, upperLimit := horizonPriceLimits(currentPrice, index.PriceLimit, horizon)
+		if predictedPrice > upperLimit || predictedPrice < lowerLimit {
+			log.Printf("⚠️ %s 预测价格 %.2f 超出涨跌停范围 [%.2f, %.2f]，已截断", indexCode, predictedPrice, lowerLimit, upperLimit)
+			predictedPrice = math.Max(lowerLimit, math.Min(upperLimit, predictedPrice))
+		}
+		clampInterval(&interval, instrument, lowerLimit, upperLimit)
 	}
 
 	// 计算预测涨跌幅（预测价格相对于当前价格的变化）
@@ -1246,23 +1114,36 @@ func (ds *DataService) generateSinglePrediction(indexCode string) (*model.StockI
 	predictedPercent := (predictedChange / currentPrice) * 100
 
 	// 更新指数信息（只保留预测涨跌比例）
-	index.Current = math.Round(currentPrice*100) / 100
-	index.Predicted = predictedPrice
-	index.Change = math.Round(predictedChange*100) / 100         // 预测涨跌金额
+	index.Current = roundPrice(currentPrice, instrument)
+	index.Predicted = roundPrice(predictedPrice, instrument)
+	index.Change = roundPrice(predictedChange, instrument)       // 预测涨跌金额
 	index.ChangePercent = math.Round(predictedPercent*100) / 100 // 预测涨跌百分比
 	index.Confidence = confidence
+	index.Model = result.Model
+	index.Interval = &interval
+	index.Ensemble = result.Ensemble
+	index.Explanation = newPredictionExplanation(indexCode, horizon, result)
+	if result.LLMTrace != nil {
+		index.PromptVersion = result.LLMTrace.PromptVersion
+	}
 	index.TechnicalIndicators = indicators
 	index.Timestamp = time.Now().UTC().Format(time.RFC3339)
 
+	// 预测以当天为基准，目标为 horizon 个交易日后的收盘价
+	now := time.Now()
+	index.Horizon = horizon
+	index.TradeDate = now.In(ds.calendar.Location()).Format("2006-01-02")
+	index.TargetDate = ds.calendar.AddTradingDays(now, horizon).In(ds.calendar.Location()).Format("2006-01-02")
+
 	return &index, nil
 }
 
-// GetDailyPredictions 获取日常预测缓存
-func (ds *DataService) GetDailyPredictions() (map[string]*model.StockIndex, time.Time, bool) {
+// GetDailyPredictions 获取指定预测周期的日常预测缓存
+func (ds *DataService) GetDailyPredictions(horizon int) (map[string]*model.StockIndex, time.Time, bool) {
 	ds.dailyMutex.RLock()
 	defer ds.dailyMutex.RUnlock()
 
-	if len(ds.dailyPredictions) == 0 {
+	if len(ds.dailyPredictions[horizon]) == 0 {
 		return nil, time.Time{}, false
 	}
 
@@ -1273,20 +1154,20 @@ func (ds *DataService) GetDailyPredictions() (map[string]*model.StockIndex, time
 
 	// 返回缓存数据的副本
 	result := make(map[string]*model.StockIndex)
-	for k, v := range ds.dailyPredictions {
+	for k, v := range ds.dailyPredictions[horizon] {
 		result[k] = v
 	}
 
 	return result, ds.dailyPredictionsTime, true
 }
 
-// GetHistoricalPredictions 获取历史预测数据
-func (ds *DataService) GetHistoricalPredictions(indexCode string, days int) ([]*model.StockIndex, error) {
+// GetHistoricalPredictions 获取指定预测周期的历史预测数据
+func (ds *DataService) GetHistoricalPredictions(indexCode string, days int, horizon int) ([]*model.StockIndex, error) {
 	if ds.db == nil {
 		return nil, fmt.Errorf("数据库未初始化")
 	}
 
-	records, err := ds.db.GetHistoricalPredictions(indexCode, days)
+	records, err := ds.db.GetHistoricalPredictions(indexCode, days, horizon)
 	if err != nil {
 		return nil, err
 	}
@@ -1304,12 +1185,12 @@ func (ds *DataService) GetHistoricalPredictions(indexCode string, days int) ([]*
 }
 
 // GetAllHistoricalPredictions 获取所有指数的历史预测数据
-func (ds *DataService) GetAllHistoricalPredictions(days int) (map[string][]*model.StockIndex, error) {
+func (ds *DataService) GetAllHistoricalPredictions(days int, horizon int) (map[string][]*model.StockIndex, error) {
 	if ds.db == nil {
 		return nil, fmt.Errorf("数据库未初始化")
 	}
 
-	recordsMap, err := ds.db.GetAllHistoricalPredictions(days)
+	recordsMap, err := ds.db.GetAllHistoricalPredictions(days, horizon)
 	if err != nil {
 		return nil, err
 	}
@@ -1330,19 +1211,106 @@ func (ds *DataService) GetAllHistoricalPredictions(days int) (map[string][]*mode
 	return results, nil
 }
 
-// RefreshDailyPredictions 手动刷新每日预测缓存（公开接口）
-func (ds *DataService) RefreshDailyPredictions() {
-	log.Printf("🔄 手动触发预测缓存刷新")
-	ds.performDailyPrediction()
+// ListInstruments 列出标的注册表（包含已停用的标的）
+func (ds *DataService) ListInstruments() []model.Instrument {
+	return ds.registry.List(true)
 }
 
-// GetPredictionStats 获取预测统计信息（预测次数和成功率）
-func (ds *DataService) GetPredictionStats() (map[string]interface{}, error) {
-	if ds.db == nil {
-		return nil, fmt.Errorf("数据库未初始化")
+// SaveInstrument 新增或更新标的
+func (ds *DataService) SaveInstrument(instrument model.Instrument) (model.Instrument, error) {
+	saved, err := ds.registry.Upsert(instrument)
+	if err != nil {
+		return model.Instrument{}, err
 	}
 
-	return ds.db.GetPredictionStats()
+	log.Printf("📋 标的已保存: %s (%s), 启用=%v", saved.Code, saved.Name, saved.Enabled)
+	return saved, nil
+}
+
+// SetInstrumentEnabled 启用或停用标的
+func (ds *DataService) SetInstrumentEnabled(code string, enabled bool) (model.Instrument, error) {
+	instrument, err := ds.registry.SetEnabled(code, enabled)
+	if err != nil {
+		return model.Instrument{}, err
+	}
+
+	log.Printf("📋 标的 %s 已%s", code, map[bool]string{true: "启用", false: "停用"}[enabled])
+	return instrument, nil
+}
+
+// SearchSecurities 搜索证券：先匹配标的注册表，再通过支持搜索的数据源补充
+func (ds *DataService) SearchSecurities(query string) ([]model.SecurityMatch, error) {
+	matches := ds.registry.Search(query)
+	seen := make(map[string]bool)
+	for _, match := range matches {
+		seen[match.Code] = true
+	}
+
+	// 依次尝试支持搜索的数据源，使用第一个成功的结果
+	var errs []string
+	for _, provider := range ds.marketData.Providers() {
+		searcher, ok := provider.(SecuritySearcher)
+		if !ok {
+			continue
+		}
+
+		remote, err := searcher.SearchSecurities(query)
+		if err != nil {
+			log.Printf("⚠️ %s 搜索证券失败: %v", provider.Name(), err)
+			errs = append(errs, fmt.Sprintf("%s: %v", provider.Name(), err))
+			continue
+		}
+
+		for _, match := range remote {
+			if seen[match.Code] {
+				continue
+			}
+			seen[match.Code] = true
+
+			// 标记是否已在注册表中
+			if instrument, exists := ds.registry.Lookup(match.Code); exists {
+				match.Registered = true
+				match.Enabled = instrument.Enabled
+			}
+			matches = append(matches, match)
+		}
+		return matches, nil
+	}
+
+	// 所有数据源都搜索失败时，注册表中有结果则只返回注册表的结果
+	if len(errs) > 0 && len(matches) == 0 {
+		return nil, fmt.Errorf("所有数据源搜索证券失败: %s", strings.Join(errs, "; "))
+	}
+	return matches, nil
+}
+
+// GetTradingDayInfo 查询指定日期的交易日信息
+func (ds *DataService) GetTradingDayInfo(date time.Time) *model.TradingDayInfo {
+	return &model.TradingDayInfo{
+		Date:            date.Format("2006-01-02"),
+		IsTradingDay:    ds.calendar.IsTradingDay(date),
+		IsHoliday:       ds.calendar.IsHoliday(date),
+		IsMakeupWorkday: ds.calendar.IsMakeupWorkday(date),
+		PrevTradingDay:  ds.calendar.PrevTradingDay(date).Format("2006-01-02"),
+		NextTradingDay:  ds.calendar.NextTradingDay(date).Format("2006-01-02"),
+		Covered:         ds.calendar.Covers(date),
+	}
+}
+
+// GetTradingCalendar 获取当前的节假日数据
+func (ds *DataService) GetTradingCalendar() calendar.HolidayFile {
+	return ds.calendar.Snapshot()
+}
+
+// UpdateTradingCalendar 更新节假日数据
+func (ds *DataService) UpdateTradingCalendar(file calendar.HolidayFile) error {
+	return ds.calendar.Update(file)
+}
+
+// RefreshDailyPredictions 手动刷新每日预测缓存（公开接口）
+func (ds *DataService) RefreshDailyPredictions() {
+	log.Printf("🔄 手动触发预测缓存刷新")
+	ds.performDailyPrediction()
 }
 
 // Stop 停止定时任务
@@ -1357,6 +1325,13 @@ func (ds *DataService) Stop() {
 		ds.timer.Stop()
 	}
 
+	// 停止健康探测、行情轮询和密钥监控
+	select {
+	case <-ds.probeStop:
+	default:
+		close(ds.probeStop)
+	}
+
 	// 关闭数据库连接
 	if ds.db != nil {
 		if err := ds.db.Close(); err != nil {
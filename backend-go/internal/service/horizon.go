@@ -0,0 +1,84 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"stock-prediction-backend/internal/model"
+	"strconv"
+	"strings"
+)
+
+// PredictionHorizons 支持的预测周期（交易日数）
+var PredictionHorizons = []int{1, 5, 20}
+
+// DefaultHorizon 默认预测周期：下一个交易日
+const DefaultHorizon = 1
+
+// indexPriceBand 指数单日预测的经验波动范围（百分比）
+const indexPriceBand = 5.0
+
+// ParseHorizon 解析预测周期参数，支持 "5d" 或 "5"，为空时返回默认周期
+func ParseHorizon(value string) (int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return DefaultHorizon, nil
+	}
+
+	horizon, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+	if err != nil {
+		return 0, fmt.Errorf("无效的预测周期: %s", value)
+	}
+
+	for _, supported := range PredictionHorizons {
+		if horizon == supported {
+			return horizon, nil
+		}
+	}
+	return 0, fmt.Errorf("不支持的预测周期: %s（支持 1d、5d、20d）", value)
+}
+
+// horizonLabel 预测周期标签，如 5d
+func horizonLabel(horizon int) string {
+	return fmt.Sprintf("%dd", horizon)
+}
+
+// horizonPriceBand 提示词中给出的预测价格范围（百分比）
+// 单日范围为指数经验值或涨跌停幅度，多日按波动率随时间平方根增长，并且不超过连续涨跌停的理论极限
+func horizonPriceBand(instrument model.Instrument, horizon int) float64 {
+	limit := priceLimitPercent(instrument)
+
+	band := indexPriceBand
+	if limit > 0 {
+		band = limit
+	}
+	band *= math.Sqrt(float64(horizon))
+
+	if limit > 0 {
+		if maxBand := (math.Pow(1+limit/100, float64(horizon)) - 1) * 100; band > maxBand {
+			band = maxBand
+		}
+	}
+	return math.Round(band*10) / 10
+}
+
+// horizonPriceLimits 连续涨跌停情况下目标交易日的价格上下限，没有涨跌幅限制时返回 0, 0
+func horizonPriceLimits(currentPrice, limitPercent float64, horizon int) (float64, float64) {
+	if limitPercent <= 0 {
+		return 0, 0
+	}
+	lower := currentPrice * math.Pow(1-limitPercent/100, float64(horizon))
+	upper := currentPrice * math.Pow(1+limitPercent/100, float64(horizon))
+	return lower, upper
+}
+
+// horizonFocus 不同预测周期在提示词中的分析侧重点
+func horizonFocus(horizon int) string {
+	switch {
+	case horizon <= 1:
+		return "短线预测：重点关注RSI超买超卖、MA5的支撑压力以及最近几天的K线形态"
+	case horizon <= 5:
+		return "周度预测：重点关注MA5与MA20的位置关系、一周内趋势能否延续，弱化单日波动的影响"
+	default:
+		return "月度预测：重点关注MA20和趋势指标反映的中期方向，结合波动率评估区间，短期噪声影响较小"
+	}
+}
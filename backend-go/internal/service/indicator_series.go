@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"stock-prediction-backend/internal/indicators"
+	"stock-prediction-backend/internal/model"
+	"strings"
+	"time"
+)
+
+// defaultIndicatorNames 未指定指标时返回的指标
+var defaultIndicatorNames = []string{"ma5", "ma20"}
+
+// indicatorSeriesCacheDuration 指标序列的缓存时间，缓存键包含最后一根K线的日期，新K线到来后自然失效
+const indicatorSeriesCacheDuration = time.Hour
+
+// ErrInstrumentNotFound 标的不存在或未启用
+var ErrInstrumentNotFound = errors.New("指数不存在")
+
+// ParseIndicatorNames 解析逗号分隔的指标名称（如 ma5,ma20,macd,rsi），去重并校验，为空时返回默认指标
+func ParseIndicatorNames(raw string) ([]string, error) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, item := range strings.Split(raw, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+
+		ind, err := indicators.Lookup(item)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[ind.Name] {
+			seen[ind.Name] = true
+			names = append(names, ind.Name)
+		}
+	}
+
+	if len(names) == 0 {
+		return append([]string(nil), defaultIndicatorNames...), nil
+	}
+	return names, nil
+}
+
+// GetIndicatorSeries 计算标的在 period 内每个交易日的技术指标，序列与 dates 逐日对齐
+// 在 period 之前多取所选指标中最大 Required 根K线用于预热，在完整区间上计算后只返回 period 内的部分，
+// 使区间开头的指标值与连续计算的结果一致；结果按标的、周期、指标集合和最后一根K线的日期缓存
+func (ds *DataService) GetIndicatorSeries(indexCode string, names []string, period string) (*model.IndicatorSeriesResponse, error) {
+	instrument, exists := ds.registry.Get(indexCode)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrInstrumentNotFound, indexCode)
+	}
+	if err := ValidatePeriod(period); err != nil {
+		return nil, err
+	}
+
+	selected := make([]indicators.Indicator, 0, len(names))
+	for _, name := range names {
+		ind, err := indicators.Lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, ind)
+	}
+
+	window := ds.getPeriodDays(period)
+	stockData, err := ds.getDailyBars(instrument.Symbol, window+indicators.MaxRequired(names...))
+	if err != nil {
+		return nil, err
+	}
+	if len(stockData) == 0 {
+		return nil, fmt.Errorf("历史数据为空: %s", indexCode)
+	}
+
+	// 只返回 period 内的K线，之前的K线只用于预热
+	offset := 0
+	if len(stockData) > window {
+		offset = len(stockData) - window
+	}
+	bars := stockData[offset:]
+
+	lastDate := bars[len(bars)-1].Date.Format("2006-01-02")
+	cacheKey := fmt.Sprintf("indicators_%s_%s_%s_%s", instrument.Code, period, strings.Join(names, ","), lastDate)
+	if cached, found := ds.getCache(cacheKey); found {
+		return cached.(*model.IndicatorSeriesResponse), nil
+	}
+
+	response := &model.IndicatorSeriesResponse{
+		IndexCode:  instrument.Code,
+		Period:     period,
+		LastDate:   lastDate,
+		Dates:      make([]string, len(bars)),
+		Indicators: make([]model.IndicatorSeries, 0, len(selected)),
+	}
+	for i, bar := range bars {
+		response.Dates[i] = bar.Date.Format("2006-01-02")
+	}
+
+	for _, ind := range selected {
+		series := ind.Compute(stockData)
+		item := model.IndicatorSeries{
+			Name:       series.Name,
+			Label:      series.Label,
+			Period:     series.Period,
+			Method:     series.Method,
+			Required:   series.Required,
+			Sufficient: series.Sufficient,
+			Lines:      make(map[string][]*float64, len(series.Lines)),
+		}
+		for line, values := range series.Lines {
+			item.Lines[line] = nullableSeries(values[offset:])
+		}
+		response.Indicators = append(response.Indicators, item)
+	}
+
+	ds.setCache(cacheKey, response, indicatorSeriesCacheDuration)
+	return response, nil
+}
+
+// nullableSeries 将序列转换为 JSON 可表示的形式，数据不足（NaN）的位置为 null，保留4位小数
+func nullableSeries(values []float64) []*float64 {
+	result := make([]*float64, len(values))
+	for i, value := range values {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+		rounded := math.Round(value*10000) / 10000
+		result[i] = &rounded
+	}
+	return result
+}
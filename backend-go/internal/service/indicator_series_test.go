@@ -0,0 +1,150 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"stock-prediction-backend/internal/calendar"
+	"stock-prediction-backend/internal/indicators"
+	"stock-prediction-backend/internal/model"
+)
+
+// fakeBarsProvider 返回固定日K线序列最后 count 根的测试数据源
+type fakeBarsProvider struct {
+	bars      []model.StockData
+	requested []int
+}
+
+func (f *fakeBarsProvider) Name() string { return "fake" }
+
+func (f *fakeBarsProvider) FetchQuote(*model.Instrument) (*model.StockData, error) {
+	return nil, fmt.Errorf("不支持")
+}
+
+func (f *fakeBarsProvider) FetchDailyBars(_ *model.Instrument, count int) ([]model.StockData, error) {
+	f.requested = append(f.requested, count)
+	if count > len(f.bars) {
+		count = len(f.bars)
+	}
+	return f.bars[len(f.bars)-count:], nil
+}
+
+func (f *fakeBarsProvider) HealthCheck() (*model.StockData, error) {
+	return nil, fmt.Errorf("不支持")
+}
+
+func TestGetIndicatorSeriesWarmup(t *testing.T) {
+	registry, err := NewInstrumentRegistry("", nil)
+	if err != nil {
+		t.Fatalf("加载标的注册表失败: %v", err)
+	}
+	tradingCalendar, err := calendar.NewTradingCalendar("")
+	if err != nil {
+		t.Fatalf("加载交易日历失败: %v", err)
+	}
+
+	var bars []model.StockData
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 200; i++ {
+		closePrice := 3000 + 50*math.Sin(float64(i)/7) + float64(i)
+		bars = append(bars, model.StockData{
+			Date: day.AddDate(0, 0, i), Open: closePrice, High: closePrice + 10, Low: closePrice - 10, Close: closePrice, Volume: 1000,
+		})
+	}
+	provider := &fakeBarsProvider{bars: bars}
+
+	ds := &DataService{
+		cache:      make(map[string]*CacheItem),
+		registry:   registry,
+		calendar:   tradingCalendar,
+		marketData: NewProviderChain([]MarketDataProvider{provider}, 0, 0, 0, nil, nil),
+	}
+
+	response, err := ds.GetIndicatorSeries("sh000001", []string{"ma20", "macd"}, "1mo")
+	if err != nil {
+		t.Fatalf("获取指标序列失败: %v", err)
+	}
+
+	// 一个月 30 根K线，加上 MACD 所需的 34 根预热K线
+	if len(provider.requested) != 1 || provider.requested[0] != 30+34 {
+		t.Errorf("请求K线数 = %v，期望 [64]", provider.requested)
+	}
+	if len(response.Dates) != 30 || response.Dates[0] != bars[170].Date.Format("2006-01-02") {
+		t.Fatalf("日期 = %d 个，从 %s 开始，期望 30 个从 %s 开始", len(response.Dates), response.Dates[0], bars[170].Date.Format("2006-01-02"))
+	}
+
+	// 区间开头的指标值与在完整序列上计算的结果一致，不再因为缺少前面的K线而为空或偏离
+	full := bars[len(bars)-64:]
+	want := map[string][]float64{
+		"ma20": indicators.SMA(indicators.Closes(full), 20)[34:],
+	}
+	dif, _, _ := indicators.MACD(indicators.Closes(full), 12, 26, 9)
+	want["macd_dif"] = dif[34:]
+
+	for _, series := range response.Indicators {
+		if !series.Sufficient {
+			t.Errorf("%s 应标记为数据充足", series.Name)
+		}
+		for line, expected := range want {
+			values, exists := series.Lines[line]
+			if !exists {
+				continue
+			}
+			if len(values) != 30 {
+				t.Fatalf("%s 长度 = %d，期望 30", line, len(values))
+			}
+			for i, value := range values {
+				if value == nil {
+					t.Fatalf("%s[%d] 为空，预热K线未生效", line, i)
+				}
+				if math.Abs(*value-expected[i]) > 1e-4 {
+					t.Errorf("%s[%d] = %v，期望 %v", line, i, *value, expected[i])
+				}
+			}
+		}
+	}
+}
+
+func TestGetIndicatorSeriesErrors(t *testing.T) {
+	registry, err := NewInstrumentRegistry("", nil)
+	if err != nil {
+		t.Fatalf("加载标的注册表失败: %v", err)
+	}
+	provider := &fakeBarsProvider{}
+	ds := &DataService{
+		cache:      make(map[string]*CacheItem),
+		registry:   registry,
+		marketData: NewProviderChain([]MarketDataProvider{provider}, 0, 0, 0, nil, nil),
+	}
+
+	if _, err := ds.GetIndicatorSeries("sh999999", []string{"ma5"}, "1mo"); !errors.Is(err, ErrInstrumentNotFound) {
+		t.Errorf("未知标的返回 %v，期望 ErrInstrumentNotFound", err)
+	}
+
+	_, err = ds.GetIndicatorSeries("sh000001", []string{"ma5"}, "2w")
+	if err == nil || errors.Is(err, ErrInstrumentNotFound) {
+		t.Errorf("不支持的周期返回 %v，期望周期校验错误", err)
+	}
+	if len(provider.requested) != 0 {
+		t.Errorf("参数无效时不应请求K线，实际请求 %v", provider.requested)
+	}
+}
+
+func TestEvictExpiredCache(t *testing.T) {
+	ds := &DataService{cache: make(map[string]*CacheItem)}
+	ds.setCache("expired", 1, time.Minute)
+	ds.setCache("fresh", 2, time.Hour)
+
+	if removed := ds.evictExpiredCache(time.Now().Add(2 * time.Minute)); removed != 1 {
+		t.Errorf("删除数量 = %d，期望 1", removed)
+	}
+	if _, exists := ds.cache["expired"]; exists {
+		t.Error("过期缓存未删除")
+	}
+	if _, found := ds.getCache("fresh"); !found {
+		t.Error("未过期的缓存不应删除")
+	}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"stock-prediction-backend/internal/config"
+	"stock-prediction-backend/internal/llm"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// initLLMClients 按配置创建大模型客户端，无效或缺少 API Key 的配置记录警告后跳过
+// 回放模式不访问网络，不要求 API Key；没有可用配置时禁用大模型预测，配置 LLM_REQUIRED 时返回错误
+func (ds *DataService) initLLMClients(cfg config.LLMConfig, httpClient *resty.Client) error {
+	ds.llmClients = make(map[string]*llm.Client)
+	for _, profile := range cfg.Profiles {
+		client, err := llm.NewClient(llm.Profile{
+			Name:        profile.Name,
+			BaseURL:     profile.BaseURL,
+			Model:       profile.Model,
+			APIKey:      profile.APIKey,
+			APIKeyFile:  profile.APIKeyFile,
+			Temperature: profile.Temperature,
+			MaxTokens:   profile.MaxTokens,
+			Timeout:     profile.Timeout,
+			Headers:     profile.Headers,
+
+			ResponseFormat: profile.ResponseFormat,
+		}, httpClient)
+		if err != nil {
+			log.Printf("⚠️ %v，已忽略", err)
+			continue
+		}
+		if !client.HasKey() && !profile.Keyless && ds.llmRecorder.mode != LLMModeReplay {
+			prefix := "LLM_" + strings.ToUpper(profile.Name) + "_"
+			log.Printf("⚠️ 大模型配置 %s 未配置 API Key（%sAPI_KEY 或 %sAPI_KEY_FILE，本地服务可设置 %sKEYLESS=true），已禁用",
+				profile.Name, prefix, prefix, prefix)
+			continue
+		}
+		ds.llmClients[profile.Name] = client
+	}
+
+	ds.llmDefault = strings.ToLower(cfg.DefaultProfile)
+	if _, exists := ds.llmClients[ds.llmDefault]; !exists {
+		ds.llmDefault = ""
+		for _, profile := range cfg.Profiles {
+			if _, exists := ds.llmClients[profile.Name]; exists {
+				ds.llmDefault = profile.Name
+				break
+			}
+		}
+		if cfg.DefaultProfile != "" && ds.llmDefault != "" {
+			log.Printf("⚠️ 默认大模型配置 %s 不可用，使用 %s", cfg.DefaultProfile, ds.llmDefault)
+		}
+	}
+
+	ds.llmMaxAttempts = cfg.MaxAttempts
+	if ds.llmMaxAttempts < 1 {
+		ds.llmMaxAttempts = 1
+	}
+
+	ds.llmIndexProfiles = make(map[string]string)
+	for code, name := range cfg.IndexProfiles {
+		name = strings.ToLower(name)
+		if _, exists := ds.llmClients[name]; !exists {
+			log.Printf("⚠️ 标的 %s 指定的大模型配置 %s 不存在，使用默认配置", code, name)
+			continue
+		}
+		ds.llmIndexProfiles[code] = name
+	}
+
+	if ds.llmDefault == "" {
+		if cfg.Required {
+			return fmt.Errorf("没有可用的大模型配置（LLM_REQUIRED=true），请配置 API Key")
+		}
+		log.Printf("⚠️ 没有可用的大模型配置，大模型预测已禁用")
+		return nil
+	}
+
+	log.Printf("🧠 大模型: %s", ds.describeLLMClients())
+	return nil
+}
+
+// llmEnabled 是否有可用的大模型配置
+func (ds *DataService) llmEnabled() bool {
+	return ds.llmDefault != ""
+}
+
+// watchLLMKeys 监控各大模型配置的 API Key 文件，密钥轮换后无需重启
+func (ds *DataService) watchLLMKeys(interval time.Duration) {
+	for _, client := range ds.llmClients {
+		go client.WatchKey(interval, ds.probeStop)
+	}
+}
+
+// describeLLMClients 大模型配置摘要，用于启动日志
+func (ds *DataService) describeLLMClients() string {
+	summary := fmt.Sprintf("默认 %s(%s)", ds.llmDefault, ds.llmClients[ds.llmDefault].Model())
+
+	codes := make([]string, 0, len(ds.llmIndexProfiles))
+	for code := range ds.llmIndexProfiles {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		name := ds.llmIndexProfiles[code]
+		summary += fmt.Sprintf(", %s=%s(%s)", code, name, ds.llmClients[name].Model())
+	}
+	return summary
+}
+
+// llmClientFor 获取标的使用的大模型客户端，未单独指定时使用默认配置
+func (ds *DataService) llmClientFor(code string) (*llm.Client, error) {
+	name, exists := ds.llmIndexProfiles[code]
+	if !exists {
+		name = ds.llmDefault
+	}
+
+	client, exists := ds.llmClients[name]
+	if !exists {
+		return nil, fmt.Errorf("没有可用的大模型配置")
+	}
+	return client, nil
+}
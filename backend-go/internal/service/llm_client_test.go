@@ -0,0 +1,198 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"stock-prediction-backend/internal/calendar"
+	"stock-prediction-backend/internal/llm"
+	"stock-prediction-backend/internal/llm/llmtest"
+	"stock-prediction-backend/internal/model"
+)
+
+// llmTestInstrument 大模型预测测试使用的指数，没有涨跌幅限制，1日预测允许 ±5%
+var llmTestInstrument = model.Instrument{
+	Code:    "sh000001",
+	Name:    "上证综指",
+	Symbol:  "000001.SS",
+	Type:    InstrumentTypeIndex,
+	LotSize: 100,
+}
+
+// llmTestAsOf 预测基准时间，固定后提示词内容不随运行日期变化，录制的响应可以稳定回放
+var llmTestAsOf = time.Date(2024, 3, 8, 15, 10, 0, 0, time.FixedZone("CST", 8*3600))
+
+// llmTestReply 符合格式且通过校验的预测回复（当前价格 3000）
+const llmTestReply = `{"predicted_price": 3015.5, "confidence": 68, "lower_80": 2990, "upper_80": 3040, "lower_95": 2970, "upper_95": 3060, "up_probability": 62, "direction": "up", "key_factors": ["MA5上穿MA20", "成交量温和放大"], "reasoning": "短期均线多头排列，量能配合，预计小幅上涨"}`
+
+// llmTestBars 截至 llmTestAsOf 的 40 根确定性日K线，收盘价在 3000 附近波动
+func llmTestBars() []model.StockData {
+	var bars []model.StockData
+	day := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	for len(bars) < 40 {
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			i := float64(len(bars))
+			closePrice := 2950 + i*1.25 + float64(len(bars)%5)*4
+			bars = append(bars, model.StockData{
+				Date:   day,
+				Open:   closePrice - 3,
+				High:   closePrice + 8,
+				Low:    closePrice - 9,
+				Close:  closePrice,
+				Volume: int64(30000000000 + len(bars)%7*1000000000),
+			})
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return bars
+}
+
+// newLLMTestService 创建只包含大模型预测所需依赖的数据服务
+func newLLMTestService(t *testing.T, mode, recordDir string, maxAttempts int, profiles ...llm.Profile) *DataService {
+	t.Helper()
+
+	tradingCalendar, err := calendar.NewTradingCalendar("")
+	if err != nil {
+		t.Fatalf("加载交易日历失败: %v", err)
+	}
+	prompts, err := NewPromptLibrary("", []string{"analysis/v1"}, PromptSplitIndex, tradingCalendar.Location())
+	if err != nil {
+		t.Fatalf("加载提示词模板失败: %v", err)
+	}
+	recorder, err := newLLMRecorder(mode, recordDir)
+	if err != nil {
+		t.Fatalf("创建录制/回放器失败: %v", err)
+	}
+
+	ds := &DataService{
+		cache:            make(map[string]*CacheItem),
+		calendar:         tradingCalendar,
+		prompts:          prompts,
+		llmRecorder:      recorder,
+		llmClients:       make(map[string]*llm.Client),
+		llmIndexProfiles: make(map[string]string),
+		llmMaxAttempts:   maxAttempts,
+	}
+	for _, profile := range profiles {
+		client, err := llm.NewClient(profile, nil)
+		if err != nil {
+			t.Fatalf("创建大模型客户端失败: %v", err)
+		}
+		ds.llmClients[profile.Name] = client
+		if ds.llmDefault == "" {
+			ds.llmDefault = profile.Name
+		}
+	}
+	return ds
+}
+
+// predictTestInstrument 使用测试K线对测试指数发起 1 日预测
+func predictTestInstrument(ds *DataService) (*PredictionResult, error) {
+	bars := llmTestBars()
+	return ds.predictWithLLM(llmTestInstrument, 1, 3000, CalculateTechnicalIndicators(llmTestInstrument, bars), bars, llmTestAsOf)
+}
+
+func TestPredictWithLLM(t *testing.T) {
+	server := llmtest.NewServer(llmtest.Reply(llmTestReply))
+	defer server.Close()
+
+	profile := server.Profile("qwen", "qwen-plus")
+	profile.ResponseFormat = llm.ResponseFormatJSONSchema
+	ds := newLLMTestService(t, LLMModeLive, "", 1, profile)
+
+	result, err := predictTestInstrument(ds)
+	if err != nil {
+		t.Fatalf("大模型预测失败: %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("请求次数 = %d，期望 1", len(requests))
+	}
+	request := requests[0]
+	if request.Model != "qwen-plus" {
+		t.Errorf("请求模型 = %s，期望 qwen-plus", request.Model)
+	}
+	if request.Temperature != profile.Temperature || request.MaxTokens != profile.MaxTokens {
+		t.Errorf("采样参数 = %v/%d，期望 %v/%d", request.Temperature, request.MaxTokens, profile.Temperature, profile.MaxTokens)
+	}
+	if format := request.ResponseFormat; format == nil || format.Type != llm.ResponseFormatJSONSchema ||
+		format.JSONSchema == nil || format.JSONSchema.Name != llmPredictionSchemaName || !format.JSONSchema.Strict {
+		t.Errorf("response_format = %+v，期望严格的 %s json_schema", format, llmPredictionSchemaName)
+	}
+	if len(request.Messages) != 2 || request.Messages[0].Role != "system" || request.Messages[1].Role != "user" {
+		t.Errorf("消息 = %+v，期望 system + user", request.Messages)
+	}
+	if got := server.Headers()[0].Get("Authorization"); got != "Bearer test-key" {
+		t.Errorf("Authorization = %q，期望 Bearer test-key", got)
+	}
+
+	if result.PredictedPrice != 3015.5 || result.Direction != DirectionUp || result.UpProbability != 62 {
+		t.Errorf("预测结果 = %.2f/%s/%.0f，期望 3015.50/up/62", result.PredictedPrice, result.Direction, result.UpProbability)
+	}
+	if result.Lower80 != 2990 || result.Upper95 != 3060 || len(result.KeyFactors) != 2 {
+		t.Errorf("预测区间或关键因素解析错误: %+v", result)
+	}
+
+	trace := result.LLMTrace
+	if trace == nil {
+		t.Fatal("缺少大模型调用记录")
+	}
+	if trace.Profile != "qwen" || trace.Model != "qwen-plus" || trace.PromptVersion != "analysis/v1" || trace.Attempts != 1 {
+		t.Errorf("调用记录 = %s/%s/%s/%d，期望 qwen/qwen-plus/analysis/v1/1", trace.Profile, trace.Model, trace.PromptVersion, trace.Attempts)
+	}
+	if trace.Usage.TotalTokens == 0 {
+		t.Error("调用记录缺少 token 用量")
+	}
+}
+
+func TestPredictWithLLMIndexProfile(t *testing.T) {
+	servers := make(map[string]*llmtest.Server)
+	var profiles []llm.Profile
+	for _, name := range []string{"deepseek", "qwen"} {
+		server := llmtest.NewServer(llmtest.Reply(llmTestReply))
+		defer server.Close()
+		servers[name] = server
+		profiles = append(profiles, server.Profile(name, name+"-model"))
+	}
+
+	ds := newLLMTestService(t, LLMModeLive, "", 1, profiles...)
+	ds.llmIndexProfiles[llmTestInstrument.Code] = "qwen"
+
+	result, err := predictTestInstrument(ds)
+	if err != nil {
+		t.Fatalf("大模型预测失败: %v", err)
+	}
+
+	if got := len(servers["deepseek"].Requests()); got != 0 {
+		t.Errorf("默认配置收到 %d 次请求，期望 0", got)
+	}
+	requests := servers["qwen"].Requests()
+	if len(requests) != 1 || requests[0].Model != "qwen-model" {
+		t.Fatalf("指定配置的请求 = %+v，期望 1 次 qwen-model 请求", requests)
+	}
+	// 未配置输出模式时使用 json_object
+	if format := requests[0].ResponseFormat; format == nil || format.Type != llm.ResponseFormatJSONObject {
+		t.Errorf("response_format = %+v，期望 json_object", format)
+	}
+	if result.LLMTrace.Profile != "qwen" {
+		t.Errorf("调用记录的配置 = %s，期望 qwen", result.LLMTrace.Profile)
+	}
+}
+
+func TestPredictWithLLMServerError(t *testing.T) {
+	server := llmtest.NewServer(func(llm.ChatRequest) (string, error) {
+		return "", fmt.Errorf("服务不可用")
+	})
+	defer server.Close()
+
+	ds := newLLMTestService(t, LLMModeLive, "", 3, server.Profile("qwen", "qwen-plus"))
+	if _, err := predictTestInstrument(ds); err == nil {
+		t.Fatal("接口返回 500 时应返回错误")
+	}
+	// 接口错误不属于校验失败，不重试
+	if got := len(server.Requests()); got != 1 {
+		t.Errorf("请求次数 = %d，期望 1", got)
+	}
+}
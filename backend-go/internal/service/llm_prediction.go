@@ -0,0 +1,249 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"stock-prediction-backend/internal/llm"
+	"stock-prediction-backend/internal/model"
+	"strings"
+	"time"
+)
+
+// 预测方向
+const (
+	DirectionUp   = "up"
+	DirectionDown = "down"
+	DirectionFlat = "flat"
+)
+
+// llmFlatChangePercent 方向为 flat 时预测价格相对当前价格允许的最大变化（百分比）
+const llmFlatChangePercent = 0.2
+
+// maxKeyFactors 关键因素的最大条数
+const maxKeyFactors = 5
+
+// LLMTrace 一次大模型预测的调用记录，包含未通过校验后的重试
+type LLMTrace struct {
+	Profile       string
+	Model         string
+	PromptVersion string
+	SystemPrompt  string
+	Prompt        string
+	Reasoning     string
+	Direction     string
+	KeyFactors    []string
+	Attempts      int
+	Latency       time.Duration
+	Usage         llm.Usage
+}
+
+// addUsage 累加一次请求的 token 用量
+func (t *LLMTrace) addUsage(usage llm.Usage) {
+	t.Usage.PromptTokens += usage.PromptTokens
+	t.Usage.CompletionTokens += usage.CompletionTokens
+	t.Usage.TotalTokens += usage.TotalTokens
+}
+
+// llmPredictionSchemaName json_schema 模式下的输出格式名称
+const llmPredictionSchemaName = "stock_prediction"
+
+// llmPredictionSchema 大模型预测回复的 JSON Schema，所有字段必填且不允许额外字段
+var llmPredictionSchema = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "predicted_price": {"type": "number", "exclusiveMinimum": 0},
+    "confidence": {"type": "number", "minimum": 0, "maximum": 100},
+    "lower_80": {"type": "number", "exclusiveMinimum": 0},
+    "upper_80": {"type": "number", "exclusiveMinimum": 0},
+    "lower_95": {"type": "number", "exclusiveMinimum": 0},
+    "upper_95": {"type": "number", "exclusiveMinimum": 0},
+    "up_probability": {"type": "number", "minimum": 0, "maximum": 100},
+    "direction": {"type": "string", "enum": ["up", "down", "flat"]},
+    "key_factors": {"type": "array", "items": {"type": "string", "minLength": 1}, "minItems": 1, "maxItems": 5},
+    "reasoning": {"type": "string", "minLength": 1}
+  },
+  "required": ["predicted_price", "confidence", "lower_80", "upper_80", "lower_95", "upper_95", "up_probability", "direction", "key_factors", "reasoning"],
+  "additionalProperties": false
+}`)
+
+// llmPredictionReply 大模型预测回复，使用指针区分缺失字段和零值
+type llmPredictionReply struct {
+	PredictedPrice *float64 `json:"predicted_price"`
+	Confidence     *float64 `json:"confidence"`
+	Lower80        *float64 `json:"lower_80"`
+	Upper80        *float64 `json:"upper_80"`
+	Lower95        *float64 `json:"lower_95"`
+	Upper95        *float64 `json:"upper_95"`
+	UpProbability  *float64 `json:"up_probability"`
+	Direction      *string  `json:"direction"`
+	KeyFactors     []string `json:"key_factors"`
+	Reasoning      *string  `json:"reasoning"`
+}
+
+// parseLLMPrediction 按 llmPredictionSchema 严格解析大模型回复
+// 回复必须是单个 JSON 对象（允许包在 Markdown 代码块中），缺少字段、存在未知字段或取值越界均返回错误
+func parseLLMPrediction(content string) (*PredictionResult, error) {
+	content = stripCodeFence(content)
+
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.DisallowUnknownFields()
+
+	var reply llmPredictionReply
+	if err := decoder.Decode(&reply); err != nil {
+		return nil, fmt.Errorf("回复不是符合格式的JSON对象: %v", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("JSON对象之后还有多余内容")
+	}
+
+	numbers := map[string]*float64{
+		"predicted_price": reply.PredictedPrice,
+		"confidence":      reply.Confidence,
+		"lower_80":        reply.Lower80,
+		"upper_80":        reply.Upper80,
+		"lower_95":        reply.Lower95,
+		"upper_95":        reply.Upper95,
+		"up_probability":  reply.UpProbability,
+	}
+	var missing []string
+	for name, value := range numbers {
+		if value == nil {
+			missing = append(missing, name)
+		} else if math.IsNaN(*value) || math.IsInf(*value, 0) {
+			return nil, fmt.Errorf("%s 不是有效数值", name)
+		}
+	}
+	if reply.Direction == nil {
+		missing = append(missing, "direction")
+	}
+	if reply.KeyFactors == nil {
+		missing = append(missing, "key_factors")
+	}
+	if reply.Reasoning == nil {
+		missing = append(missing, "reasoning")
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("缺少字段: %s", strings.Join(missing, ", "))
+	}
+
+	result := &PredictionResult{
+		PredictedPrice: *reply.PredictedPrice,
+		Confidence:     *reply.Confidence,
+		Lower80:        *reply.Lower80,
+		Upper80:        *reply.Upper80,
+		Lower95:        *reply.Lower95,
+		Upper95:        *reply.Upper95,
+		UpProbability:  *reply.UpProbability,
+		Direction:      strings.ToLower(strings.TrimSpace(*reply.Direction)),
+		Reasoning:      strings.TrimSpace(*reply.Reasoning),
+	}
+	for _, factor := range reply.KeyFactors {
+		if factor = strings.TrimSpace(factor); factor != "" {
+			result.KeyFactors = append(result.KeyFactors, factor)
+		}
+	}
+
+	switch {
+	case result.PredictedPrice <= 0:
+		return nil, fmt.Errorf("predicted_price 必须大于0: %.2f", result.PredictedPrice)
+	case result.Confidence < 0 || result.Confidence > 100:
+		return nil, fmt.Errorf("confidence 需在0-100之间: %.2f", result.Confidence)
+	case result.UpProbability < 0 || result.UpProbability > 100:
+		return nil, fmt.Errorf("up_probability 需在0-100之间: %.2f", result.UpProbability)
+	case result.Direction != DirectionUp && result.Direction != DirectionDown && result.Direction != DirectionFlat:
+		return nil, fmt.Errorf("direction 需为 up、down 或 flat: %s", *reply.Direction)
+	case len(result.KeyFactors) == 0 || len(result.KeyFactors) > maxKeyFactors:
+		return nil, fmt.Errorf("key_factors 需包含1-%d条非空内容，实际 %d 条", maxKeyFactors, len(result.KeyFactors))
+	case result.Reasoning == "":
+		return nil, fmt.Errorf("reasoning 不能为空")
+	}
+
+	return result, nil
+}
+
+// stripCodeFence 去掉包裹 JSON 的 Markdown 代码块
+func stripCodeFence(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "```") || !strings.HasSuffix(content, "```") || len(content) < 6 {
+		return content
+	}
+
+	content = strings.TrimSuffix(strings.TrimPrefix(content, "```"), "```")
+	if newline := strings.IndexByte(content, '\n'); newline >= 0 && !bytes.ContainsAny([]byte(content[:newline]), "{[") {
+		content = content[newline+1:] // 去掉语言标记，如 ```json
+	}
+	return strings.TrimSpace(content)
+}
+
+// validateLLMPrediction 检查预测是否在合理范围内：
+// 不超过提示词给出的价格范围和连续涨跌停价格，区间顺序一致，方向与预测价格和上涨概率一致
+func validateLLMPrediction(result *PredictionResult, instrument model.Instrument, currentPrice float64, horizon int) error {
+	change := (result.PredictedPrice - currentPrice) / currentPrice * 100
+
+	if band := horizonPriceBand(instrument, horizon); math.Abs(change) > band {
+		return fmt.Errorf("预测价格 %.2f 相对当前价格 %.2f 变化 %.2f%%，超出 ±%.1f%% 的范围",
+			result.PredictedPrice, currentPrice, change, band)
+	}
+
+	if lower, upper := horizonPriceLimits(currentPrice, priceLimitPercent(instrument), horizon); upper > 0 {
+		if result.PredictedPrice < roundPrice(lower, instrument) || result.PredictedPrice > roundPrice(upper, instrument) {
+			return fmt.Errorf("预测价格 %.2f 超出涨跌停价格范围 [%.2f, %.2f]", result.PredictedPrice, lower, upper)
+		}
+	}
+
+	if err := validateResultInterval(result); err != nil {
+		return err
+	}
+
+	switch result.Direction {
+	case DirectionUp:
+		if change <= 0 || result.UpProbability < 50 {
+			return fmt.Errorf("direction 为 up，但预测价格变化 %.2f%%、上涨概率 %.2f%%", change, result.UpProbability)
+		}
+	case DirectionDown:
+		if change >= 0 || result.UpProbability > 50 {
+			return fmt.Errorf("direction 为 down，但预测价格变化 %.2f%%、上涨概率 %.2f%%", change, result.UpProbability)
+		}
+	case DirectionFlat:
+		if math.Abs(change) > llmFlatChangePercent {
+			return fmt.Errorf("direction 为 flat，但预测价格变化 %.2f%% 超过 ±%.1f%%", change, llmFlatChangePercent)
+		}
+	}
+
+	return nil
+}
+
+// correctiveMessage 回复未通过校验时发送的纠正提示
+func correctiveMessage(err error) llm.Message {
+	return llm.Message{
+		Role:    "user",
+		Content: fmt.Sprintf("你上一次的回复未通过校验：%v。请修正后重新给出预测，只返回一个符合要求格式的JSON对象，不要包含其他文字。", err),
+	}
+}
+
+// recordLLMRejection 记录被拒绝的大模型回复，数据库不可用时只写日志
+func (ds *DataService) recordLLMRejection(client *llm.Client, indexCode string, horizon, attempt int, reason error, content string) {
+	log.Printf("⚠️ 大模型回复被拒绝 [%s/%s] %s %s 第%d次: %v",
+		client.Name(), client.Model(), indexCode, horizonLabel(horizon), attempt, reason)
+
+	if ds.db == nil {
+		return
+	}
+	if err := ds.db.SaveLLMRejection(&model.LLMRejection{
+		IndexCode: indexCode,
+		Horizon:   horizon,
+		Profile:   client.Name(),
+		Model:     client.Model(),
+		Attempt:   attempt,
+		Reason:    reason.Error(),
+		Content:   content,
+	}); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"stock-prediction-backend/internal/llm"
+	"stock-prediction-backend/internal/llm/llmtest"
+	"stock-prediction-backend/internal/model"
+)
+
+// llmReply 按字段生成大模型回复，值为 nil 的字段不输出
+func llmReply(fields map[string]interface{}) string {
+	var parts []string
+	for _, name := range []string{"predicted_price", "confidence", "lower_80", "upper_80", "lower_95", "upper_95", "up_probability", "direction", "key_factors", "reasoning", "extra"} {
+		value, exists := fields[name]
+		if !exists || value == nil {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			parts = append(parts, fmt.Sprintf("%q: %q", name, v))
+		case []string:
+			quoted := make([]string, len(v))
+			for i, item := range v {
+				quoted[i] = fmt.Sprintf("%q", item)
+			}
+			parts = append(parts, fmt.Sprintf("%q: [%s]", name, strings.Join(quoted, ", ")))
+		default:
+			parts = append(parts, fmt.Sprintf("%q: %v", name, v))
+		}
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// validReplyFields 符合格式的回复字段（当前价格 3000），测试用例在副本上修改
+func validReplyFields() map[string]interface{} {
+	return map[string]interface{}{
+		"predicted_price": 3015.5,
+		"confidence":      68,
+		"lower_80":        2990,
+		"upper_80":        3040,
+		"lower_95":        2970,
+		"upper_95":        3060,
+		"up_probability":  62,
+		"direction":       "up",
+		"key_factors":     []string{"MA5上穿MA20"},
+		"reasoning":       "短期均线多头排列",
+	}
+}
+
+func TestParseLLMPrediction(t *testing.T) {
+	with := func(name string, value interface{}) string {
+		fields := validReplyFields()
+		fields[name] = value
+		return llmReply(fields)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string // 为空表示期望解析成功
+	}{
+		{name: "完整回复", content: llmReply(validReplyFields())},
+		{name: "包在Markdown代码块中", content: "```json\n" + llmReply(validReplyFields()) + "\n```"},
+		{name: "方向大小写和空白", content: with("direction", " UP ")},
+		{name: "缺少字段", content: with("confidence", nil), wantErr: "缺少字段: confidence"},
+		{name: "缺少多个字段按名称排序", content: llmReply(map[string]interface{}{"predicted_price": 3000}), wantErr: "缺少字段: confidence, direction, key_factors"},
+		{name: "未知字段", content: with("extra", 1), wantErr: "unknown field"},
+		{name: "JSON之后有多余内容", content: llmReply(validReplyFields()) + " 以上是预测", wantErr: "多余内容"},
+		{name: "不是JSON", content: "预测价格为 3015.5", wantErr: "不是符合格式的JSON对象"},
+		{name: "字段类型错误", content: with("predicted_price", `"3015.5"`), wantErr: "不是符合格式的JSON对象"},
+		{name: "预测价格不为正", content: with("predicted_price", 0), wantErr: "predicted_price 必须大于0"},
+		{name: "置信度越界", content: with("confidence", 120), wantErr: "confidence 需在0-100之间"},
+		{name: "上涨概率越界", content: with("up_probability", -1), wantErr: "up_probability 需在0-100之间"},
+		{name: "方向无效", content: with("direction", "sideways"), wantErr: "direction 需为 up、down 或 flat"},
+		{name: "关键因素为空", content: with("key_factors", []string{" "}), wantErr: "key_factors 需包含1-5条非空内容"},
+		{name: "关键因素过多", content: with("key_factors", []string{"1", "2", "3", "4", "5", "6"}), wantErr: "实际 6 条"},
+		{name: "理由为空", content: with("reasoning", "  "), wantErr: "reasoning 不能为空"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseLLMPrediction(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("错误 = %v，期望包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("解析失败: %v", err)
+			}
+			if result.PredictedPrice != 3015.5 || result.Direction != DirectionUp || result.Reasoning != "短期均线多头排列" {
+				t.Errorf("解析结果 = %+v", result)
+			}
+		})
+	}
+}
+
+func TestValidateLLMPrediction(t *testing.T) {
+	mainStock := model.Instrument{Code: "sh600000", Type: InstrumentTypeStock, Board: BoardMain, LotSize: 100}
+	stStock := model.Instrument{Code: "sz000004", Type: InstrumentTypeStock, Board: BoardMain, IsST: true, LotSize: 100}
+
+	// result 生成以 price 为中心、区间顺序一致的预测
+	result := func(price, upProbability float64, direction string) *PredictionResult {
+		return &PredictionResult{
+			PredictedPrice: price,
+			Lower80:        price * 0.995,
+			Upper80:        price * 1.005,
+			Lower95:        price * 0.99,
+			Upper95:        price * 1.01,
+			UpProbability:  upProbability,
+			Direction:      direction,
+		}
+	}
+
+	tests := []struct {
+		name         string
+		instrument   model.Instrument
+		currentPrice float64
+		horizon      int
+		result       *PredictionResult
+		wantErr      string
+	}{
+		{name: "指数上涨", instrument: llmTestInstrument, currentPrice: 3000, horizon: 1, result: result(3030, 60, DirectionUp)},
+		{name: "指数下跌", instrument: llmTestInstrument, currentPrice: 3000, horizon: 1, result: result(2970, 40, DirectionDown)},
+		{name: "指数持平", instrument: llmTestInstrument, currentPrice: 3000, horizon: 1, result: result(3003, 50, DirectionFlat)},
+		{name: "指数超出1日±5%范围", instrument: llmTestInstrument, currentPrice: 3000, horizon: 1, result: result(3160, 70, DirectionUp), wantErr: "超出 ±5.0% 的范围"},
+		{name: "指数5日范围按平方根放大", instrument: llmTestInstrument, currentPrice: 3000, horizon: 5, result: result(3300, 70, DirectionUp)},
+		{name: "指数超出5日范围", instrument: llmTestInstrument, currentPrice: 3000, horizon: 5, result: result(3360, 70, DirectionUp), wantErr: "超出 ±11.2% 的范围"},
+		{name: "主板个股涨停以内", instrument: mainStock, currentPrice: 10, horizon: 1, result: result(10.9, 80, DirectionUp)},
+		{name: "主板个股超出±10%范围", instrument: mainStock, currentPrice: 10, horizon: 1, result: result(11.2, 80, DirectionUp), wantErr: "超出 ±10.0% 的范围"},
+		// 涨停价按分取整为 10.51，10.5103 未超出 ±5% 的范围但高于涨停价
+		{name: "ST个股高于按分取整的涨停价", instrument: stStock, currentPrice: 10.01, horizon: 1, result: result(10.5103, 80, DirectionUp), wantErr: "超出涨跌停价格范围 [9.51, 10.51]"},
+		{name: "区间顺序不一致", instrument: llmTestInstrument, currentPrice: 3000, horizon: 1, result: &PredictionResult{
+			PredictedPrice: 3030, Lower80: 3040, Upper80: 3050, Lower95: 2990, Upper95: 3060, UpProbability: 60, Direction: DirectionUp,
+		}, wantErr: "预测区间不一致"},
+		{name: "缺少区间", instrument: llmTestInstrument, currentPrice: 3000, horizon: 1, result: &PredictionResult{
+			PredictedPrice: 3030, UpProbability: 60, Direction: DirectionUp,
+		}, wantErr: "缺少预测区间"},
+		{name: "方向为up但价格下跌", instrument: llmTestInstrument, currentPrice: 3000, horizon: 1, result: result(2970, 60, DirectionUp), wantErr: "direction 为 up"},
+		{name: "方向为up但上涨概率低于50%", instrument: llmTestInstrument, currentPrice: 3000, horizon: 1, result: result(3030, 45, DirectionUp), wantErr: "direction 为 up"},
+		{name: "方向为down但价格上涨", instrument: llmTestInstrument, currentPrice: 3000, horizon: 1, result: result(3030, 40, DirectionDown), wantErr: "direction 为 down"},
+		{name: "方向为down但上涨概率高于50%", instrument: llmTestInstrument, currentPrice: 3000, horizon: 1, result: result(2970, 55, DirectionDown), wantErr: "direction 为 down"},
+		{name: "方向为flat但变化超过0.2%", instrument: llmTestInstrument, currentPrice: 3000, horizon: 1, result: result(3015, 50, DirectionFlat), wantErr: "direction 为 flat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLLMPrediction(tt.result, tt.instrument, tt.currentPrice, tt.horizon)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("校验失败: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("错误 = %v，期望包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPredictWithLLMCorrectiveRetry(t *testing.T) {
+	// 第一次回复方向与价格矛盾，第二次给出正确回复
+	invalid := validReplyFields()
+	invalid["direction"] = "down"
+	invalidReply := llmReply(invalid)
+
+	server := llmtest.NewServer(func(request llm.ChatRequest) (string, error) {
+		if len(request.Messages) == 2 {
+			return invalidReply, nil
+		}
+		return llmTestReply, nil
+	})
+	defer server.Close()
+
+	ds := newLLMTestService(t, LLMModeLive, "", 3, server.Profile("deepseek", "deepseek-chat"))
+	result, err := predictTestInstrument(ds)
+	if err != nil {
+		t.Fatalf("大模型预测失败: %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("请求次数 = %d，期望 2", len(requests))
+	}
+
+	// 重试时附上上一次的回复和拒绝原因
+	retry := requests[1].Messages
+	if len(retry) != 4 {
+		t.Fatalf("重试请求的消息数 = %d，期望 4", len(retry))
+	}
+	if retry[2].Role != "assistant" || retry[2].Content != invalidReply {
+		t.Errorf("重试请求的第3条消息 = %+v，期望上一次的回复", retry[2])
+	}
+	if retry[3].Role != "user" || !strings.Contains(retry[3].Content, "direction 为 down") {
+		t.Errorf("重试请求的第4条消息 = %+v，期望包含拒绝原因", retry[3])
+	}
+
+	if result.LLMTrace.Attempts != 2 {
+		t.Errorf("调用记录的请求次数 = %d，期望 2", result.LLMTrace.Attempts)
+	}
+	// token 用量累加两次请求
+	if first := len(requests[0].Messages) + len(invalidReply); result.LLMTrace.Usage.TotalTokens <= first {
+		t.Errorf("token 用量 = %d，期望累加两次请求", result.LLMTrace.Usage.TotalTokens)
+	}
+}
+
+func TestPredictWithLLMRetryExhausted(t *testing.T) {
+	server := llmtest.NewServer(llmtest.Reply(`{"predicted_price": 3015.5}`))
+	defer server.Close()
+
+	ds := newLLMTestService(t, LLMModeLive, "", 3, server.Profile("deepseek", "deepseek-chat"))
+	_, err := predictTestInstrument(ds)
+	if err == nil || !strings.Contains(err.Error(), "连续 3 次未通过校验") {
+		t.Fatalf("错误 = %v，期望连续 3 次未通过校验", err)
+	}
+	if got := len(server.Requests()); got != 3 {
+		t.Errorf("请求次数 = %d，期望 3", got)
+	}
+}
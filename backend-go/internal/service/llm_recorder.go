@@ -0,0 +1,135 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"stock-prediction-backend/internal/llm"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 大模型调用模式
+const (
+	LLMModeLive   = "live"   // 直接调用接口
+	LLMModeRecord = "record" // 调用接口并保存请求和原始响应
+	LLMModeReplay = "replay" // 只从录制文件返回响应，不访问网络
+)
+
+// LLMRecording 录制的大模型请求和原始响应
+type LLMRecording struct {
+	Hash       string        `json:"hash"`     // 请求内容的 SHA-256
+	Model      string        `json:"model"`    // 请求的模型
+	Messages   []llm.Message `json:"messages"` // 请求的提示词
+	Response   string        `json:"response"` // 接口返回的原始响应体
+	RecordedAt string        `json:"recorded_at"`
+}
+
+// llmRecorder 大模型请求的录制与回放，每个请求保存为 dir 下以请求哈希命名的 JSON 文件
+type llmRecorder struct {
+	mode string
+	dir  string
+	mu   sync.Mutex
+}
+
+// newLLMRecorder 创建录制/回放器，mode 为空时直接调用接口
+func newLLMRecorder(mode, dir string) (*llmRecorder, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case "":
+		mode = LLMModeLive
+	case LLMModeLive, LLMModeRecord, LLMModeReplay:
+	default:
+		return nil, fmt.Errorf("未知的大模型调用模式: %s（支持 live、record、replay）", mode)
+	}
+
+	if mode != LLMModeLive {
+		if dir == "" {
+			return nil, fmt.Errorf("%s 模式需要配置录制目录", mode)
+		}
+		log.Printf("📼 大模型调用模式: %s，录制目录: %s", mode, dir)
+	}
+
+	return &llmRecorder{mode: mode, dir: dir}, nil
+}
+
+// promptHash 计算请求内容的哈希，模型、提示词、采样参数和输出模式相同的请求视为同一请求
+func promptHash(request llm.ChatRequest) string {
+	content, _ := json.Marshal(struct {
+		Model       string        `json:"model"`
+		Messages    []llm.Message `json:"messages"`
+		MaxTokens   int           `json:"max_tokens"`
+		Temperature float64       `json:"temperature"`
+
+		ResponseFormat *llm.ResponseFormat `json:"response_format,omitempty"`
+	}{request.Model, request.Messages, request.MaxTokens, request.Temperature, request.ResponseFormat})
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// path 录制文件路径
+func (r *llmRecorder) path(hash string) string {
+	return filepath.Join(r.dir, hash+".json")
+}
+
+// load 读取录制的响应，回放模式下没有录制时返回错误
+func (r *llmRecorder) load(request llm.ChatRequest) ([]byte, error) {
+	hash := promptHash(request)
+
+	content, err := os.ReadFile(r.path(hash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("回放模式下没有录制的响应: %s", hash)
+		}
+		return nil, fmt.Errorf("读取录制文件失败 %s: %v", hash, err)
+	}
+
+	var recording LLMRecording
+	if err := json.Unmarshal(content, &recording); err != nil {
+		return nil, fmt.Errorf("解析录制文件失败 %s: %v", hash, err)
+	}
+
+	log.Printf("📼 回放大模型响应: %s", hash[:12])
+	return []byte(recording.Response), nil
+}
+
+// save 保存请求和原始响应，先写临时文件再重命名，避免并发预测时读到不完整的文件
+func (r *llmRecorder) save(request llm.ChatRequest, response []byte) error {
+	hash := promptHash(request)
+	recording := LLMRecording{
+		Hash:       hash,
+		Model:      request.Model,
+		Messages:   request.Messages,
+		Response:   string(response),
+		RecordedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	content, err := json.MarshalIndent(recording, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化录制内容失败: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return fmt.Errorf("创建录制目录失败: %v", err)
+	}
+
+	tmp := r.path(hash) + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return fmt.Errorf("写入录制文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, r.path(hash)); err != nil {
+		return fmt.Errorf("写入录制文件失败: %v", err)
+	}
+
+	log.Printf("📼 已录制大模型响应: %s", hash[:12])
+	return nil
+}
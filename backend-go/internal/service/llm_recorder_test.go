@@ -0,0 +1,119 @@
+package service
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"stock-prediction-backend/internal/llm"
+	"stock-prediction-backend/internal/llm/llmtest"
+)
+
+// updateLLMRecordings 重新录制 testdata/llm_recordings 中的响应: go test ./internal/service -run TestLLMReplay -update-llm-recordings
+var updateLLMRecordings = flag.Bool("update-llm-recordings", false, "使用假服务重新录制大模型响应")
+
+// llmTestRecordDir 测试在包目录下运行，与 LLM_RECORD_DIR 的默认值指向同一相对路径
+const llmTestRecordDir = "testdata/llm_recordings"
+
+// llmReplayProfile 回放测试使用的大模型配置，接口地址指向收到请求即判定失败的服务
+func llmReplayProfile(t *testing.T, model string) llm.Profile {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("回放模式不应访问网络: %s", r.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+
+	return llm.Profile{
+		Name:        "deepseek",
+		BaseURL:     server.URL,
+		Model:       model,
+		Temperature: 0.3,
+		MaxTokens:   1000,
+	}
+}
+
+func TestLLMReplay(t *testing.T) {
+	if *updateLLMRecordings {
+		server := llmtest.NewServer(llmtest.Reply(llmTestReply))
+		defer server.Close()
+
+		ds := newLLMTestService(t, LLMModeRecord, llmTestRecordDir, 1, server.Profile("deepseek", "deepseek-chat"))
+		if _, err := predictTestInstrument(ds); err != nil {
+			t.Fatalf("录制失败: %v", err)
+		}
+	}
+
+	ds := newLLMTestService(t, LLMModeReplay, llmTestRecordDir, 1, llmReplayProfile(t, "deepseek-chat"))
+	result, err := predictTestInstrument(ds)
+	if err != nil {
+		t.Fatalf("回放失败（提示词或请求参数变化后需使用 -update-llm-recordings 重新录制）: %v", err)
+	}
+
+	if result.PredictedPrice != 3015.5 || result.Direction != DirectionUp || result.Confidence != 68 {
+		t.Errorf("回放结果 = %.2f/%s/%.0f，期望 3015.50/up/68", result.PredictedPrice, result.Direction, result.Confidence)
+	}
+	if result.LLMTrace == nil || result.LLMTrace.Model != "deepseek-chat" || result.LLMTrace.Attempts != 1 {
+		t.Errorf("回放调用记录 = %+v", result.LLMTrace)
+	}
+}
+
+func TestLLMReplayMiss(t *testing.T) {
+	// 模型不同则请求哈希不同，录制目录中没有对应的响应
+	ds := newLLMTestService(t, LLMModeReplay, llmTestRecordDir, 3, llmReplayProfile(t, "unrecorded-model"))
+
+	_, err := predictTestInstrument(ds)
+	if err == nil {
+		t.Fatal("回放模式下没有录制的响应时应返回错误")
+	}
+	if !strings.Contains(err.Error(), "没有录制的响应") {
+		t.Errorf("错误 = %v，期望提示没有录制的响应", err)
+	}
+}
+
+func TestLLMRecord(t *testing.T) {
+	server := llmtest.NewServer(llmtest.Reply(llmTestReply))
+	defer server.Close()
+
+	dir := t.TempDir()
+	profile := server.Profile("deepseek", "deepseek-chat")
+	ds := newLLMTestService(t, LLMModeRecord, dir, 1, profile)
+	if _, err := predictTestInstrument(ds); err != nil {
+		t.Fatalf("录制失败: %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("请求次数 = %d，期望 1", len(requests))
+	}
+	hash := promptHash(requests[0])
+
+	content, err := os.ReadFile(filepath.Join(dir, hash+".json"))
+	if err != nil {
+		t.Fatalf("录制文件不存在: %v", err)
+	}
+	var recording LLMRecording
+	if err := json.Unmarshal(content, &recording); err != nil {
+		t.Fatalf("录制文件格式错误: %v", err)
+	}
+	if recording.Hash != hash || recording.Model != "deepseek-chat" || len(recording.Messages) != 2 {
+		t.Errorf("录制内容 = %s/%s/%d条消息，期望 %s/deepseek-chat/2条消息", recording.Hash, recording.Model, len(recording.Messages), hash)
+	}
+	if !strings.Contains(recording.Response, "chatcmpl-fake") {
+		t.Errorf("录制的应是原始响应体，实际: %s", recording.Response)
+	}
+
+	// 同一请求可以从录制目录回放，不再访问接口
+	replay := newLLMTestService(t, LLMModeReplay, dir, 1, profile)
+	if _, err := predictTestInstrument(replay); err != nil {
+		t.Fatalf("回放录制的响应失败: %v", err)
+	}
+	if got := len(server.Requests()); got != 1 {
+		t.Errorf("回放后请求次数 = %d，期望仍为 1", got)
+	}
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"stock-prediction-backend/internal/calendar"
+	"stock-prediction-backend/internal/config"
+	"stock-prediction-backend/internal/model"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// MarketDataProvider 行情数据源接口
+// 各数据源优先使用标的注册表中配置的专用代码，未配置时从统一代码（如 000001.SS）推导
+type MarketDataProvider interface {
+	// Name 数据源名称
+	Name() string
+	// FetchQuote 获取最新报价（包含昨收价）
+	FetchQuote(instrument *model.Instrument) (*model.StockData, error)
+	// FetchDailyBars 获取最近 count 个交易日的日K线，按日期升序
+	FetchDailyBars(instrument *model.Instrument, count int) ([]model.StockData, error)
+	// HealthCheck 探测数据源可用性，返回探测到的报价
+	HealthCheck() (*model.StockData, error)
+}
+
+// healthProbeInstrument 健康探测使用的标的（上证综指）
+var healthProbeInstrument = &model.Instrument{
+	Code:    "sh000001",
+	Name:    "上证综指",
+	Symbol:  "000001.SS",
+	Type:    InstrumentTypeIndex,
+	LotSize: 100,
+}
+
+// sharesPerHand 行情接口以“手”为单位返回成交量时每手的股数，与标的的交易单位 LotSize 无关
+const sharesPerHand = 100
+
+// exchangeLocation 行情接口返回的时间所在时区（北京时间）
+var exchangeLocation = time.FixedZone("CST", 8*3600)
+
+// SecuritySearcher 支持按代码或拼音搜索证券的数据源
+type SecuritySearcher interface {
+	SearchSecurities(query string) ([]model.SecurityMatch, error)
+}
+
+// MinuteBarProvider 支持分钟K线的数据源
+type MinuteBarProvider interface {
+	// FetchMinuteBars 获取最近 count 根 interval 分钟K线，按时间升序，Date 为K线结束时间
+	FetchMinuteBars(instrument *model.Instrument, interval, count int) ([]model.StockData, error)
+}
+
+// ProviderChain 按顺序故障转移的数据源链
+// 某个数据源出错或返回过期数据时，自动尝试下一个数据源
+type ProviderChain struct {
+	providers          []MarketDataProvider
+	quoteMaxAge        time.Duration
+	barsMaxAge         time.Duration
+	sessionQuoteMaxAge time.Duration             // 交易时段内报价的最大延迟
+	calendar           *calendar.TradingCalendar // 可选，判断是否处于交易时段
+	monitor            *ProviderHealthMonitor    // 可选，记录每次调用的结果
+}
+
+// NewProviderChain 创建数据源链
+func NewProviderChain(providers []MarketDataProvider, quoteMaxAge, barsMaxAge, sessionQuoteMaxAge time.Duration, tradingCalendar *calendar.TradingCalendar, monitor *ProviderHealthMonitor) *ProviderChain {
+	return &ProviderChain{
+		providers:          providers,
+		quoteMaxAge:        quoteMaxAge,
+		barsMaxAge:         barsMaxAge,
+		sessionQuoteMaxAge: sessionQuoteMaxAge,
+		calendar:           tradingCalendar,
+		monitor:            monitor,
+	}
+}
+
+// NewMarketDataProviders 根据配置创建数据源列表（按优先级排序）
+func NewMarketDataProviders(cfg *config.Config, httpClient *resty.Client) []MarketDataProvider {
+	var providers []MarketDataProvider
+	for _, name := range cfg.MarketData.Providers {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "tencent":
+			providers = append(providers, NewTencentProvider(httpClient, tencentQuoteURL, tencentKLineURL, tencentSearchURL))
+		case "sina":
+			providers = append(providers, NewSinaProvider(httpClient, sinaQuoteURL, sinaKLineURL))
+		default:
+			log.Printf("⚠️ 未知的行情数据源: %s，已忽略", name)
+		}
+	}
+
+	if len(providers) == 0 {
+		log.Printf("⚠️ 未配置有效的行情数据源，使用默认腾讯财经")
+		providers = append(providers, NewTencentProvider(httpClient, tencentQuoteURL, tencentKLineURL, tencentSearchURL))
+	}
+
+	return providers
+}
+
+// Name 数据源名称
+func (c *ProviderChain) Name() string {
+	names := make([]string, 0, len(c.providers))
+	for _, p := range c.providers {
+		names = append(names, p.Name())
+	}
+	return strings.Join(names, ">")
+}
+
+// Providers 返回链中的数据源
+func (c *ProviderChain) Providers() []MarketDataProvider {
+	return c.providers
+}
+
+// FetchQuote 依次尝试各数据源获取报价
+// 交易时段内所有数据源的报价都延迟时返回错误；休市期间所有数据源都过期时使用最新的一份
+func (c *ProviderChain) FetchQuote(instrument *model.Instrument) (*model.StockData, error) {
+	return c.fetchQuote(instrument, time.Now())
+}
+
+// fetchQuote 以 now 作为当前时间依次尝试各数据源获取报价
+func (c *ProviderChain) fetchQuote(instrument *model.Instrument, now time.Time) (*model.StockData, error) {
+	var errs []string
+	var stale *model.StockData
+	var staleFrom string
+
+	for _, p := range c.providers {
+		start := time.Now()
+		quote, err := p.FetchQuote(instrument)
+		// 交易时段内的延迟报价与请求失败一样计入数据源的失败次数
+		if err == nil && c.SessionStale(quote, now) {
+			err = fmt.Errorf("交易时段内报价延迟，行情时间 %s", quote.Date.Format("2006-01-02 15:04:05"))
+		}
+		c.record(p.Name(), time.Since(start), quote, err)
+		if err != nil {
+			log.Printf("⚠️ 数据源 %s 获取报价失败 %s: %v", p.Name(), instrument.Code, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", p.Name(), err))
+			continue
+		}
+
+		if c.QuoteStale(quote, now) {
+			log.Printf("⚠️ 数据源 %s 报价已过期 %s: %s", p.Name(), instrument.Code, quote.Date.Format("2006-01-02 15:04:05"))
+			if stale == nil || quote.Date.After(stale.Date) {
+				stale, staleFrom = quote, p.Name()
+			}
+			continue
+		}
+
+		return quote, nil
+	}
+
+	// 休市期间所有数据源都过期时（最后一个交易日距今较久），使用最新的一份数据
+	if stale != nil {
+		log.Printf("⚠️ 所有数据源报价均已过期，使用 %s 的数据 %s", staleFrom, instrument.Code)
+		return stale, nil
+	}
+
+	return nil, fmt.Errorf("所有数据源获取报价失败: %s", strings.Join(errs, "; "))
+}
+
+// QuoteStale 报价时间是否落后超过 quoteMaxAge，即休市期间所有数据源都过期时返回的兜底报价
+func (c *ProviderChain) QuoteStale(quote *model.StockData, now time.Time) bool {
+	return c.quoteMaxAge > 0 && now.Sub(quote.Date) > c.quoteMaxAge
+}
+
+// SessionStale 交易时段内报价时间是否落后超过 sessionQuoteMaxAge
+// 时段刚开始时报价仍是上一时段的最后时间，时段开始后超过 sessionQuoteMaxAge 才开始检查
+func (c *ProviderChain) SessionStale(quote *model.StockData, now time.Time) bool {
+	if c.calendar == nil || c.sessionQuoteMaxAge <= 0 {
+		return false
+	}
+
+	start, inSession := c.calendar.SessionStart(now)
+	if !inSession || now.Sub(start) <= c.sessionQuoteMaxAge {
+		return false
+	}
+	return now.Sub(quote.Date) > c.sessionQuoteMaxAge
+}
+
+// FetchDailyBars 依次尝试各数据源获取日K线
+func (c *ProviderChain) FetchDailyBars(instrument *model.Instrument, count int) ([]model.StockData, error) {
+	var errs []string
+	var stale []model.StockData
+	var staleFrom string
+
+	for _, p := range c.providers {
+		start := time.Now()
+		bars, err := p.FetchDailyBars(instrument, count)
+		c.record(p.Name(), time.Since(start), nil, err)
+		if err != nil {
+			log.Printf("⚠️ 数据源 %s 获取日K线失败 %s: %v", p.Name(), instrument.Code, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", p.Name(), err))
+			continue
+		}
+
+		if len(bars) == 0 {
+			errs = append(errs, fmt.Sprintf("%s: 数据为空", p.Name()))
+			continue
+		}
+
+		last := bars[len(bars)-1].Date
+		if c.barsMaxAge > 0 && time.Since(last) > c.barsMaxAge {
+			log.Printf("⚠️ 数据源 %s 日K线已过期 %s: 最后交易日 %s", p.Name(), instrument.Code, last.Format("2006-01-02"))
+			if stale == nil || last.After(stale[len(stale)-1].Date) {
+				stale, staleFrom = bars, p.Name()
+			}
+			continue
+		}
+
+		return bars, nil
+	}
+
+	if stale != nil {
+		log.Printf("⚠️ 所有数据源日K线均已过期，使用 %s 的数据 %s", staleFrom, instrument.Code)
+		return stale, nil
+	}
+
+	return nil, fmt.Errorf("所有数据源获取日K线失败: %s", strings.Join(errs, "; "))
+}
+
+// FetchMinuteBars 依次尝试支持分钟K线的数据源
+func (c *ProviderChain) FetchMinuteBars(instrument *model.Instrument, interval, count int) ([]model.StockData, error) {
+	var errs []string
+	for _, p := range c.providers {
+		minuteProvider, ok := p.(MinuteBarProvider)
+		if !ok {
+			continue
+		}
+
+		start := time.Now()
+		bars, err := minuteProvider.FetchMinuteBars(instrument, interval, count)
+		c.record(p.Name(), time.Since(start), nil, err)
+		if err != nil {
+			log.Printf("⚠️ 数据源 %s 获取%d分钟K线失败 %s: %v", p.Name(), interval, instrument.Code, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", p.Name(), err))
+			continue
+		}
+
+		if len(bars) == 0 {
+			errs = append(errs, fmt.Sprintf("%s: 数据为空", p.Name()))
+			continue
+		}
+
+		return bars, nil
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("没有支持分钟K线的数据源")
+	}
+	return nil, fmt.Errorf("所有数据源获取分钟K线失败: %s", strings.Join(errs, "; "))
+}
+
+// record 记录调用结果到健康监控
+func (c *ProviderChain) record(name string, latency time.Duration, quote *model.StockData, err error) {
+	if c.monitor != nil {
+		c.monitor.Record(name, latency, quote, err)
+	}
+}
+
+// HealthCheck 返回第一个可用数据源的探测结果
+func (c *ProviderChain) HealthCheck() (*model.StockData, error) {
+	return c.FetchQuote(healthProbeInstrument)
+}
+
+// marketPrefixedSymbol 将 "000001.SS" 转换为 "sh000001" 格式（腾讯、新浪通用）
+func marketPrefixedSymbol(symbol string) (string, error) {
+	parts := strings.Split(symbol, ".")
+	if len(parts) != 2 || parts[0] == "" {
+		return "", fmt.Errorf("不支持的股票代码: %s", symbol)
+	}
+
+	switch strings.ToUpper(parts[1]) {
+	case "SS", "SH":
+		return "sh" + parts[0], nil
+	case "SZ":
+		return "sz" + parts[0], nil
+	case "BJ":
+		return "bj" + parts[0], nil
+	default:
+		return "", fmt.Errorf("不支持的交易所后缀: %s", symbol)
+	}
+}
+
+// unifiedSymbol 将市场前缀和代码转换为统一代码（如 sh + 000001 → 000001.SS），同时返回交易所名称
+func unifiedSymbol(market, code string) (string, string, error) {
+	switch strings.ToLower(market) {
+	case "sh":
+		return code + ".SS", "上海证券交易所", nil
+	case "sz":
+		return code + ".SZ", "深圳证券交易所", nil
+	case "bj":
+		return code + ".BJ", "北京证券交易所", nil
+	default:
+		return "", "", fmt.Errorf("不支持的市场: %s", market)
+	}
+}
+
+// parseFloat 解析浮点数
+func parseFloat(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "-" {
+		return 0, fmt.Errorf("空值")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// parseInt64 解析整数
+func parseInt64(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "-" {
+		return 0, fmt.Errorf("空值")
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
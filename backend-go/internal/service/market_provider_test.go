@@ -0,0 +1,447 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"stock-prediction-backend/internal/calendar"
+	"stock-prediction-backend/internal/model"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// testInstrument 测试使用的指数标的
+var testInstrument = &model.Instrument{
+	Code:    "sh000001",
+	Name:    "上证综指",
+	Symbol:  "000001.SS",
+	Type:    InstrumentTypeIndex,
+	LotSize: 100,
+}
+
+// tencentQuoteBody 构造腾讯财经报价接口的返回内容
+func tencentQuoteBody(symbol string, price, yesterdayClose float64, quoteTime time.Time) string {
+	fields := make([]string, 40)
+	fields[1] = "上证指数"
+	fields[2] = strings.TrimLeft(symbol, "shzbj")
+	fields[3] = fmt.Sprintf("%.2f", price)
+	fields[4] = fmt.Sprintf("%.2f", yesterdayClose)
+	fields[5] = fmt.Sprintf("%.2f", yesterdayClose)
+	fields[6] = "3000"
+	fields[30] = quoteTime.In(exchangeLocation).Format("20060102150405")
+	fields[33] = fmt.Sprintf("%.2f", price+10)
+	fields[34] = fmt.Sprintf("%.2f", yesterdayClose-10)
+	fields[36] = "3000"
+	return fmt.Sprintf("v_%s=\"%s\";\n", symbol, strings.Join(fields, "~"))
+}
+
+// sinaQuoteBody 构造新浪财经报价接口的返回内容
+func sinaQuoteBody(symbol string, price, yesterdayClose float64, quoteTime time.Time) string {
+	fields := make([]string, 33)
+	fields[0] = "上证指数"
+	fields[1] = fmt.Sprintf("%.2f", yesterdayClose)
+	fields[2] = fmt.Sprintf("%.2f", yesterdayClose)
+	fields[3] = fmt.Sprintf("%.2f", price)
+	fields[4] = fmt.Sprintf("%.2f", price+10)
+	fields[5] = fmt.Sprintf("%.2f", yesterdayClose-10)
+	fields[8] = "3000"
+	local := quoteTime.In(exchangeLocation)
+	fields[30] = local.Format("2006-01-02")
+	fields[31] = local.Format("15:04:05")
+	return fmt.Sprintf("var hq_str_%s=\"%s\";\n", symbol, strings.Join(fields, ","))
+}
+
+// sinaKLineBody 构造新浪财经日K线接口的返回内容，收盘价从 close 开始每天加 1
+func sinaKLineBody(start time.Time, days int, close float64) string {
+	var bars []string
+	for i := 0; i < days; i++ {
+		bars = append(bars, fmt.Sprintf(`{"day":"%s","open":"%.2f","high":"%.2f","low":"%.2f","close":"%.2f","volume":"1000"}`,
+			start.AddDate(0, 0, i).Format("2006-01-02"), close, close+1, close-1, close+float64(i)))
+	}
+	return "[" + strings.Join(bars, ",") + "]"
+}
+
+// fakeEndpoint 模拟数据源接口，status 为 0 时按 200 返回 body
+type fakeEndpoint struct {
+	status int
+	body   string
+}
+
+// newFakeServer 启动按路径前缀匹配返回内容的测试服务器，同时记录请求过的路径
+func newFakeServer(t *testing.T, endpoints map[string]fakeEndpoint) (*httptest.Server, *[]string) {
+	t.Helper()
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.RequestURI())
+		for prefix, endpoint := range endpoints {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				if endpoint.status != 0 && endpoint.status != http.StatusOK {
+					w.WriteHeader(endpoint.status)
+					return
+				}
+				fmt.Fprint(w, endpoint.body)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requested
+}
+
+func TestTencentProviderFetchQuote(t *testing.T) {
+	quoteTime := time.Date(2024, 3, 4, 15, 0, 3, 0, exchangeLocation)
+	server, requested := newFakeServer(t, map[string]fakeEndpoint{
+		"/q=sh000001": {body: tencentQuoteBody("sh000001", 3047.79, 3027.02, quoteTime)},
+	})
+
+	provider := NewTencentProvider(resty.New(), server.URL+"/", server.URL, server.URL)
+	quote, err := provider.FetchQuote(testInstrument)
+	if err != nil {
+		t.Fatalf("获取报价失败: %v", err)
+	}
+
+	if len(*requested) != 1 || (*requested)[0] != "/q=sh000001" {
+		t.Errorf("请求路径 = %v，期望 /q=sh000001", *requested)
+	}
+	if !quote.Date.Equal(quoteTime) {
+		t.Errorf("行情时间 = %v，期望 %v", quote.Date, quoteTime)
+	}
+	if !floatEqual(quote.Close, 3047.79) || !floatEqual(quote.YesterdayClose, 3027.02) {
+		t.Errorf("当前价/昨收 = %v/%v，期望 3047.79/3027.02", quote.Close, quote.YesterdayClose)
+	}
+	if quote.Volume != 300000 {
+		t.Errorf("成交量 = %d，期望按每手100股换算为 300000", quote.Volume)
+	}
+}
+
+func TestSinaProviderFetchQuote(t *testing.T) {
+	quoteTime := time.Date(2024, 3, 4, 14, 30, 0, 0, exchangeLocation)
+	server, _ := newFakeServer(t, map[string]fakeEndpoint{
+		"/list=sh000001": {body: sinaQuoteBody("sh000001", 3047.79, 3027.02, quoteTime)},
+	})
+
+	provider := NewSinaProvider(resty.New(), server.URL, server.URL)
+	quote, err := provider.FetchQuote(testInstrument)
+	if err != nil {
+		t.Fatalf("获取报价失败: %v", err)
+	}
+
+	if !quote.Date.Equal(quoteTime) {
+		t.Errorf("行情时间 = %v，期望 %v", quote.Date, quoteTime)
+	}
+	if !floatEqual(quote.Close, 3047.79) || !floatEqual(quote.YesterdayClose, 3027.02) {
+		t.Errorf("当前价/昨收 = %v/%v，期望 3047.79/3027.02", quote.Close, quote.YesterdayClose)
+	}
+	// 新浪指数成交量单位为手
+	if quote.Volume != 300000 {
+		t.Errorf("成交量 = %d，期望 300000", quote.Volume)
+	}
+}
+
+func TestTencentProviderFetchDailyBars(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "tencent_kline_index.json"))
+	if err != nil {
+		t.Fatalf("读取测试数据失败: %v", err)
+	}
+	server, requested := newFakeServer(t, map[string]fakeEndpoint{
+		"/appstock/app/fqkline/get": {body: string(body)},
+	})
+
+	provider := NewTencentProvider(resty.New(), server.URL, server.URL, server.URL)
+	bars, err := provider.FetchDailyBars(testInstrument, 3)
+	if err != nil {
+		t.Fatalf("获取日K线失败: %v", err)
+	}
+
+	// 多请求一根K线用于提供第一根的昨收价
+	if want := "/appstock/app/fqkline/get?param=sh000001,day,,,4,qfq"; len(*requested) != 1 || (*requested)[0] != want {
+		t.Errorf("请求路径 = %v，期望 %s", *requested, want)
+	}
+	if len(bars) != 3 || bars[0].YesterdayClose <= 0 {
+		t.Fatalf("日K线 = %+v，期望 3 根且第一根有昨收价", bars)
+	}
+}
+
+func TestProviderChainFetchQuote(t *testing.T) {
+	now := time.Now()
+	fresh := now.Add(-10 * time.Second)
+	stale := now.Add(-2 * time.Hour)
+	staler := now.Add(-3 * time.Hour)
+
+	tests := []struct {
+		name        string
+		tencent     fakeEndpoint
+		sina        fakeEndpoint
+		wantErr     bool
+		wantTime    time.Time
+		wantPrice   float64
+		wantFailure map[string]int // 各数据源的连续失败次数
+	}{
+		{
+			name:        "第一个数据源正常",
+			tencent:     fakeEndpoint{body: tencentQuoteBody("sh000001", 3001, 3000, fresh)},
+			sina:        fakeEndpoint{body: sinaQuoteBody("sh000001", 3002, 3000, fresh)},
+			wantTime:    fresh,
+			wantPrice:   3001,
+			wantFailure: map[string]int{"tencent": 0, "sina": 0},
+		},
+		{
+			name:        "第一个数据源HTTP错误时切换到下一个",
+			tencent:     fakeEndpoint{status: http.StatusInternalServerError},
+			sina:        fakeEndpoint{body: sinaQuoteBody("sh000001", 3002, 3000, fresh)},
+			wantTime:    fresh,
+			wantPrice:   3002,
+			wantFailure: map[string]int{"tencent": 1, "sina": 0},
+		},
+		{
+			name:        "第一个数据源返回无法解析的内容时切换到下一个",
+			tencent:     fakeEndpoint{body: "v_pv_none_match=\"1\";"},
+			sina:        fakeEndpoint{body: sinaQuoteBody("sh000001", 3002, 3000, fresh)},
+			wantTime:    fresh,
+			wantPrice:   3002,
+			wantFailure: map[string]int{"tencent": 1, "sina": 0},
+		},
+		{
+			name:        "第一个数据源过期时切换到下一个",
+			tencent:     fakeEndpoint{body: tencentQuoteBody("sh000001", 3001, 3000, stale)},
+			sina:        fakeEndpoint{body: sinaQuoteBody("sh000001", 3002, 3000, fresh)},
+			wantTime:    fresh,
+			wantPrice:   3002,
+			wantFailure: map[string]int{"tencent": 0, "sina": 0},
+		},
+		{
+			name:        "所有数据源都过期时使用最新的一份",
+			tencent:     fakeEndpoint{body: tencentQuoteBody("sh000001", 3001, 3000, staler)},
+			sina:        fakeEndpoint{body: sinaQuoteBody("sh000001", 3002, 3000, stale)},
+			wantTime:    stale,
+			wantPrice:   3002,
+			wantFailure: map[string]int{"tencent": 0, "sina": 0},
+		},
+		{
+			name:        "所有数据源都失败",
+			tencent:     fakeEndpoint{status: http.StatusBadGateway},
+			sina:        fakeEndpoint{status: http.StatusForbidden},
+			wantErr:     true,
+			wantFailure: map[string]int{"tencent": 1, "sina": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tencentServer, _ := newFakeServer(t, map[string]fakeEndpoint{"/q=": tt.tencent})
+			sinaServer, _ := newFakeServer(t, map[string]fakeEndpoint{"/list=": tt.sina})
+
+			providers := []MarketDataProvider{
+				NewTencentProvider(resty.New(), tencentServer.URL, tencentServer.URL, tencentServer.URL),
+				NewSinaProvider(resty.New(), sinaServer.URL, sinaServer.URL),
+			}
+			monitor := NewProviderHealthMonitor(providers)
+			chain := NewProviderChain(providers, time.Hour, 0, 0, nil, monitor)
+
+			quote, err := chain.FetchQuote(testInstrument)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("期望返回错误，实际得到报价 %+v", quote)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("获取报价失败: %v", err)
+				}
+				if !quote.Date.Truncate(time.Second).Equal(tt.wantTime.Truncate(time.Second)) {
+					t.Errorf("行情时间 = %v，期望 %v", quote.Date, tt.wantTime)
+				}
+				if !floatEqual(quote.Close, tt.wantPrice) {
+					t.Errorf("当前价 = %v，期望 %v", quote.Close, tt.wantPrice)
+				}
+			}
+
+			for _, status := range monitor.Snapshot() {
+				if want, ok := tt.wantFailure[status.Name]; ok && status.ConsecutiveFailures != want {
+					t.Errorf("%s 连续失败次数 = %d，期望 %d", status.Name, status.ConsecutiveFailures, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProviderChainFetchQuoteSession(t *testing.T) {
+	tradingCalendar, err := calendar.NewTradingCalendar("")
+	if err != nil {
+		t.Fatalf("创建交易日历失败: %v", err)
+	}
+	inSession := time.Date(2024, 3, 12, 10, 30, 0, 0, exchangeLocation) // 周二连续竞价
+	weekend := time.Date(2024, 3, 16, 10, 30, 0, 0, exchangeLocation)   // 周六
+
+	tests := []struct {
+		name        string
+		now         time.Time
+		tencent     time.Time
+		sina        time.Time
+		wantErr     bool
+		wantPrice   float64
+		wantStale   bool
+		wantFailure map[string]int // 各数据源的连续失败次数
+	}{
+		{
+			name:        "交易时段内第一个数据源延迟时切换到下一个",
+			now:         inSession,
+			tencent:     inSession.Add(-10 * time.Minute),
+			sina:        inSession.Add(-10 * time.Second),
+			wantPrice:   3002,
+			wantFailure: map[string]int{"tencent": 1, "sina": 0},
+		},
+		{
+			name:        "交易时段内所有数据源都延迟时返回错误",
+			now:         inSession,
+			tencent:     inSession.Add(-10 * time.Minute),
+			sina:        inSession.Add(-5 * time.Minute),
+			wantErr:     true,
+			wantFailure: map[string]int{"tencent": 1, "sina": 1},
+		},
+		{
+			name:        "休市期间使用上一交易日的报价",
+			now:         weekend,
+			tencent:     time.Date(2024, 3, 15, 15, 0, 0, 0, exchangeLocation),
+			sina:        time.Date(2024, 3, 15, 15, 0, 0, 0, exchangeLocation),
+			wantPrice:   3001,
+			wantFailure: map[string]int{"tencent": 0},
+		},
+		{
+			name:        "休市期间所有数据源都过期时使用最新的一份并标记过期",
+			now:         weekend,
+			tencent:     weekend.AddDate(0, 0, -10),
+			sina:        weekend.AddDate(0, 0, -8),
+			wantPrice:   3002,
+			wantStale:   true,
+			wantFailure: map[string]int{"tencent": 0, "sina": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tencentServer, _ := newFakeServer(t, map[string]fakeEndpoint{"/q=": {body: tencentQuoteBody("sh000001", 3001, 3000, tt.tencent)}})
+			sinaServer, _ := newFakeServer(t, map[string]fakeEndpoint{"/list=": {body: sinaQuoteBody("sh000001", 3002, 3000, tt.sina)}})
+
+			providers := []MarketDataProvider{
+				NewTencentProvider(resty.New(), tencentServer.URL, tencentServer.URL, tencentServer.URL),
+				NewSinaProvider(resty.New(), sinaServer.URL, sinaServer.URL),
+			}
+			monitor := NewProviderHealthMonitor(providers)
+			chain := NewProviderChain(providers, 96*time.Hour, 0, 2*time.Minute, tradingCalendar, monitor)
+
+			quote, err := chain.fetchQuote(testInstrument, tt.now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("期望返回错误，实际得到报价 %+v", quote)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("获取报价失败: %v", err)
+				}
+				if !floatEqual(quote.Close, tt.wantPrice) {
+					t.Errorf("当前价 = %v，期望 %v", quote.Close, tt.wantPrice)
+				}
+				if got := chain.QuoteStale(quote, tt.now); got != tt.wantStale {
+					t.Errorf("过期标记 = %v，期望 %v", got, tt.wantStale)
+				}
+			}
+
+			for _, status := range monitor.Snapshot() {
+				if want, ok := tt.wantFailure[status.Name]; ok && status.ConsecutiveFailures != want {
+					t.Errorf("%s 连续失败次数 = %d，期望 %d", status.Name, status.ConsecutiveFailures, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProviderChainFetchDailyBars(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	// 解析时跳过周末，最后一根K线是不晚于 day 的最近一个工作日
+	lastWeekday := func(day time.Time) time.Time {
+		for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			day = day.AddDate(0, 0, -1)
+		}
+		return day
+	}
+	recent := sinaKLineBody(today.AddDate(0, 0, -4), 5, 3000)
+	old := sinaKLineBody(today.AddDate(0, 0, -40), 5, 3000)
+	older := sinaKLineBody(today.AddDate(0, 0, -60), 5, 3000)
+
+	tests := []struct {
+		name     string
+		primary  fakeEndpoint
+		backup   fakeEndpoint
+		wantErr  bool
+		wantLast time.Time
+	}{
+		{
+			name:     "第一个数据源出错时切换到下一个",
+			primary:  fakeEndpoint{status: http.StatusInternalServerError},
+			backup:   fakeEndpoint{body: recent},
+			wantLast: lastWeekday(today),
+		},
+		{
+			name:     "第一个数据源返回空数据时切换到下一个",
+			primary:  fakeEndpoint{body: "[]"},
+			backup:   fakeEndpoint{body: recent},
+			wantLast: lastWeekday(today),
+		},
+		{
+			name:     "第一个数据源过期时切换到下一个",
+			primary:  fakeEndpoint{body: old},
+			backup:   fakeEndpoint{body: recent},
+			wantLast: lastWeekday(today),
+		},
+		{
+			name:     "所有数据源都过期时使用最后交易日最新的一份",
+			primary:  fakeEndpoint{body: older},
+			backup:   fakeEndpoint{body: old},
+			wantLast: lastWeekday(today.AddDate(0, 0, -36)),
+		},
+		{
+			name:    "所有数据源都失败",
+			primary: fakeEndpoint{status: http.StatusInternalServerError},
+			backup:  fakeEndpoint{body: "null"},
+			wantErr: true,
+		},
+	}
+
+	const klinePath = "/cn/api/json_v2.php/CN_MarketDataService.getKLineData"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primaryServer, _ := newFakeServer(t, map[string]fakeEndpoint{klinePath: tt.primary})
+			backupServer, _ := newFakeServer(t, map[string]fakeEndpoint{klinePath: tt.backup})
+
+			providers := []MarketDataProvider{
+				NewSinaProvider(resty.New(), primaryServer.URL, primaryServer.URL),
+				NewSinaProvider(resty.New(), backupServer.URL, backupServer.URL),
+			}
+			chain := NewProviderChain(providers, 0, 10*24*time.Hour, 0, nil, nil)
+
+			bars, err := chain.FetchDailyBars(testInstrument, 4)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("期望返回错误，实际得到 %d 根K线", len(bars))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("获取日K线失败: %v", err)
+			}
+			if len(bars) == 0 {
+				t.Fatal("日K线为空")
+			}
+			if last := bars[len(bars)-1].Date; !last.Equal(tt.wantLast) {
+				t.Errorf("最后交易日 = %v，期望 %v", last, tt.wantLast)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"stock-prediction-backend/internal/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// supportedMinuteIntervals 支持的分钟K线周期（分钟）
+var supportedMinuteIntervals = []int{1, 5, 15, 60}
+
+// minutesPerTradingDay A股每个交易日的连续竞价分钟数（9:30-11:30，13:00-15:00）
+const minutesPerTradingDay = 240
+
+// 分钟K线查询的默认和最大条数
+const (
+	defaultMinuteBarLimit = 240
+	maxMinuteBarLimit     = 1000
+)
+
+// minuteBarCacheDuration 数据库中没有分钟K线时，直接从数据源获取的结果的缓存时间
+const minuteBarCacheDuration = time.Minute
+
+// ParseMinuteInterval 解析分钟K线周期，为空时默认 5 分钟
+func ParseMinuteInterval(raw string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 5, nil
+	}
+
+	interval, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(raw), "m"))
+	if err == nil {
+		for _, supported := range supportedMinuteIntervals {
+			if interval == supported {
+				return interval, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("不支持的分钟K线周期: %s，可选 1、5、15、60", raw)
+}
+
+// parseMinuteIntervals 解析配置的分钟K线周期列表
+func parseMinuteIntervals(values []string) ([]int, error) {
+	var intervals []int
+	for _, value := range values {
+		interval, err := ParseMinuteInterval(value)
+		if err != nil {
+			return nil, err
+		}
+		intervals = append(intervals, interval)
+	}
+	return intervals, nil
+}
+
+// GetMinuteBars 获取最近 limit 根分钟K线：优先读取数据库中采集的数据，没有时直接请求数据源
+func (ds *DataService) GetMinuteBars(indexCode string, interval, limit int) (*model.MinuteBarResponse, error) {
+	instrument, exists := ds.registry.Get(indexCode)
+	if !exists {
+		return nil, fmt.Errorf("指数不存在: %s", indexCode)
+	}
+
+	if limit <= 0 {
+		limit = defaultMinuteBarLimit
+	}
+	if limit > maxMinuteBarLimit {
+		limit = maxMinuteBarLimit
+	}
+
+	response := &model.MinuteBarResponse{
+		IndexCode: instrument.Code,
+		Interval:  interval,
+		Bars:      []model.MinuteBarData{},
+	}
+
+	if ds.db != nil {
+		bars, err := ds.db.GetMinuteBars(instrument.Code, interval, limit)
+		if err != nil {
+			log.Printf("⚠️ %v", err)
+		} else if len(bars) > 0 {
+			response.Source = "database"
+			response.Bars = ds.minuteBarData(bars)
+			return response, nil
+		}
+	}
+
+	cacheKey := fmt.Sprintf("minute_bars_%s_%d_%d", instrument.Code, interval, limit)
+	if cached, found := ds.getCache(cacheKey); found {
+		return cached.(*model.MinuteBarResponse), nil
+	}
+
+	bars, err := ds.marketData.FetchMinuteBars(&instrument, interval, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(bars) > limit {
+		bars = bars[len(bars)-limit:]
+	}
+
+	response.Source = "live"
+	response.Bars = ds.minuteBarData(bars)
+	ds.setCache(cacheKey, response, minuteBarCacheDuration)
+	return response, nil
+}
+
+// minuteBarData 转换为接口返回格式，时间按北京时间输出
+func (ds *DataService) minuteBarData(bars []model.StockData) []model.MinuteBarData {
+	data := make([]model.MinuteBarData, 0, len(bars))
+	for _, bar := range bars {
+		data = append(data, model.MinuteBarData{
+			Time:   bar.Date.In(ds.calendar.Location()).Format(time.RFC3339),
+			Open:   bar.Open,
+			High:   bar.High,
+			Low:    bar.Low,
+			Close:  bar.Close,
+			Volume: bar.Volume,
+		})
+	}
+	return data
+}
+
+// startMinuteBarCollector 启动分钟K线采集：交易时段内按固定间隔拉取启用标的的分钟K线并写入数据库
+func (ds *DataService) startMinuteBarCollector(interval time.Duration) {
+	if interval <= 0 || len(ds.minuteBarIntervals) == 0 {
+		log.Printf("⚠️ 分钟K线采集已禁用")
+		return
+	}
+	if ds.db == nil {
+		log.Printf("⚠️ 数据库未初始化，分钟K线采集已禁用")
+		return
+	}
+
+	log.Printf("🕐 分钟K线采集已启动: 周期 %v 分钟，交易时段内每 %v 采集一次", ds.minuteBarIntervals, interval)
+
+	wasInSession := false
+	for {
+		now := time.Now()
+		inSession := ds.calendar.InSession(now)
+
+		// 时段结束后再采集一次，补齐每个时段的最后一根K线
+		if inSession || wasInSession {
+			ds.collectMinuteBars()
+		}
+		wasInSession = inSession
+
+		wait := interval
+		if !inSession {
+			wait = time.Until(ds.calendar.NextSessionStart(now))
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ds.probeStop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// collectMinuteBars 拉取所有启用标的的当日分钟K线并保存，最后一根未走完的K线在下次采集时更新
+func (ds *DataService) collectMinuteBars() {
+	for _, instrument := range ds.registry.List(false) {
+		for _, interval := range ds.minuteBarIntervals {
+			bars, err := ds.marketData.FetchMinuteBars(&instrument, interval, minutesPerTradingDay/interval+1)
+			if err != nil {
+				log.Printf("⚠️ 采集%d分钟K线失败 %s: %v", interval, instrument.Code, err)
+				continue
+			}
+
+			if err := ds.db.SaveMinuteBars(instrument.Code, interval, bars); err != nil {
+				log.Printf("⚠️ %v", err)
+			}
+		}
+	}
+}
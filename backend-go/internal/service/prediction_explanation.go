@@ -0,0 +1,60 @@
+package service
+
+import (
+	"fmt"
+	"stock-prediction-backend/internal/model"
+)
+
+// newPredictionExplanation 根据预测结果生成预测依据
+func newPredictionExplanation(indexCode string, horizon int, result *PredictionResult) *model.PredictionExplanation {
+	explanation := &model.PredictionExplanation{
+		IndexCode: indexCode,
+		Horizon:   horizon,
+		Model:     result.Model,
+		Reasoning: result.Reasoning,
+	}
+
+	if trace := result.LLMTrace; trace != nil {
+		explanation.Direction = trace.Direction
+		explanation.KeyFactors = trace.KeyFactors
+		explanation.LLMReasoning = trace.Reasoning
+		explanation.Profile = trace.Profile
+		explanation.LLMModel = trace.Model
+		explanation.PromptVersion = trace.PromptVersion
+		explanation.SystemPrompt = trace.SystemPrompt
+		explanation.Prompt = trace.Prompt
+		explanation.Attempts = trace.Attempts
+		explanation.LatencyMs = trace.Latency.Milliseconds()
+		explanation.PromptTokens = trace.Usage.PromptTokens
+		explanation.CompletionTokens = trace.Usage.CompletionTokens
+		explanation.TotalTokens = trace.Usage.TotalTokens
+	}
+
+	return explanation
+}
+
+// GetPredictionExplanation 获取标的今日指定预测周期的预测依据，没有预测时返回 nil
+// 优先从数据库获取，数据库不可用时使用日常预测缓存
+func (ds *DataService) GetPredictionExplanation(indexCode string, horizon int) (*model.PredictionExplanation, error) {
+	if _, exists := ds.registry.Get(indexCode); !exists {
+		return nil, fmt.Errorf("指数不存在: %s", indexCode)
+	}
+
+	if ds.db != nil {
+		record, err := ds.db.GetTodayPrediction(indexCode, ds.currentTradeDate(), horizon)
+		if err != nil {
+			return nil, err
+		}
+		if record != nil {
+			return ds.db.GetPredictionExplanation(record.ID)
+		}
+	}
+
+	if dailyPredictions, _, ok := ds.GetDailyPredictions(horizon); ok {
+		if prediction, exists := dailyPredictions[indexCode]; exists && prediction.Explanation != nil {
+			return prediction.Explanation, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"stock-prediction-backend/internal/indicators"
+	"stock-prediction-backend/internal/model"
+)
+
+// 正态分布双侧分位数
+const (
+	z80 = 1.2816
+	z95 = 1.9600
+)
+
+// minDailyVolatility 历史数据不足时使用的最小日波动率（百分比）
+const minDailyVolatility = 0.5
+
+// 预测区间来源
+const (
+	IntervalSourceLLM        = "llm"
+	IntervalSourceVolatility = "volatility"
+	IntervalSourceEnsemble   = "ensemble"
+)
+
+// volatilityInterval 根据历史日波动率计算预测区间和上涨概率
+// 假设对数收益率服从正态分布，周期波动率按交易日数的平方根放大
+func volatilityInterval(instrument model.Instrument, currentPrice, predictedPrice, dailyVolatilityPercent float64, horizon int) model.PredictionInterval {
+	dailyVolatility := math.Max(dailyVolatilityPercent, minDailyVolatility) / 100
+	return normalInterval(instrument, currentPrice, predictedPrice, dailyVolatility*math.Sqrt(float64(horizon)))
+}
+
+// normalInterval 按预测周期内对数收益率的标准差 sigma 计算预测区间和上涨概率，区间按标的最小报价单位取整
+func normalInterval(instrument model.Instrument, currentPrice, predictedPrice, sigma float64) model.PredictionInterval {
+	// 上涨概率：预测价格相对当前价格的漂移在分布中的位置
+	drift := math.Log(predictedPrice / currentPrice)
+	upProbability := 0.5 * (1 + math.Erf(drift/sigma/math.Sqrt2)) * 100
+
+	return model.PredictionInterval{
+		Lower80:       roundPrice(predictedPrice*math.Exp(-z80*sigma), instrument),
+		Upper80:       roundPrice(predictedPrice*math.Exp(z80*sigma), instrument),
+		Lower95:       roundPrice(predictedPrice*math.Exp(-z95*sigma), instrument),
+		Upper95:       roundPrice(predictedPrice*math.Exp(z95*sigma), instrument),
+		UpProbability: round2(upProbability),
+		Source:        IntervalSourceVolatility,
+	}
+}
+
+// validateResultInterval 校验大模型给出的区间是否一致：
+// lower_95 ≤ lower_80 ≤ predicted_price ≤ upper_80 ≤ upper_95，上涨概率在0-100之间
+func validateResultInterval(result *PredictionResult) error {
+	if result.Lower80 <= 0 || result.Upper80 <= 0 || result.Lower95 <= 0 || result.Upper95 <= 0 {
+		return fmt.Errorf("缺少预测区间")
+	}
+
+	if !(result.Lower95 <= result.Lower80 && result.Lower80 <= result.PredictedPrice &&
+		result.PredictedPrice <= result.Upper80 && result.Upper80 <= result.Upper95) {
+		return fmt.Errorf("预测区间不一致: 95%%[%.2f, %.2f] 80%%[%.2f, %.2f] 预测价格 %.2f",
+			result.Lower95, result.Upper95, result.Lower80, result.Upper80, result.PredictedPrice)
+	}
+
+	if result.UpProbability < 0 || result.UpProbability > 100 {
+		return fmt.Errorf("上涨概率超出范围: %.2f", result.UpProbability)
+	}
+
+	return nil
+}
+
+// resolvePredictionInterval 确定最终的预测区间：模型给出的区间一致时采用，否则按历史波动率计算
+// annualizedVolatility 为技术指标中的年化波动率（百分比）
+func resolvePredictionInterval(result *PredictionResult, instrument model.Instrument, currentPrice, annualizedVolatility float64, horizon int) model.PredictionInterval {
+	if err := validateResultInterval(result); err != nil {
+		log.Printf("⚠️ %v，改用历史波动率计算预测区间", err)
+		return volatilityInterval(instrument, currentPrice, result.PredictedPrice, indicators.DailyVolatility(annualizedVolatility), horizon)
+	}
+
+	// 统计模型的区间由波动率计算，其余视为大模型给出
+	source := IntervalSourceLLM
+	if result.IntervalSource != "" {
+		source = result.IntervalSource
+	}
+
+	return model.PredictionInterval{
+		Lower80:       roundPrice(result.Lower80, instrument),
+		Upper80:       roundPrice(result.Upper80, instrument),
+		Lower95:       roundPrice(result.Lower95, instrument),
+		Upper95:       roundPrice(result.Upper95, instrument),
+		UpProbability: round2(result.UpProbability),
+		Source:        source,
+	}
+}
+
+// clampInterval 将预测区间限制在涨跌停价格范围内
+func clampInterval(interval *model.PredictionInterval, instrument model.Instrument, lower, upper float64) {
+	clamp := func(price float64) float64 {
+		return roundPrice(math.Max(lower, math.Min(upper, price)), instrument)
+	}
+	interval.Lower80 = clamp(interval.Lower80)
+	interval.Upper80 = clamp(interval.Upper80)
+	interval.Lower95 = clamp(interval.Lower95)
+	interval.Upper95 = clamp(interval.Upper95)
+}
+
+// intervalCoverage 实际价格是否落在80%/95%区间内，没有区间时返回 nil
+func intervalCoverage(record model.PredictionRecord, actualPrice float64) (*bool, *bool) {
+	if record.Lower80 == nil || record.Upper80 == nil || record.Lower95 == nil || record.Upper95 == nil {
+		return nil, nil
+	}
+
+	covered80 := actualPrice >= *record.Lower80 && actualPrice <= *record.Upper80
+	covered95 := actualPrice >= *record.Lower95 && actualPrice <= *record.Upper95
+	return &covered80, &covered95
+}
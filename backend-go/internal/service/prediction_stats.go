@@ -0,0 +1,258 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"stock-prediction-backend/internal/model"
+	"time"
+)
+
+// confidenceBucketWidth 置信度分桶宽度（百分比）
+const confidenceBucketWidth = 10
+
+// accuracyAccumulator 误差指标累加器
+type accuracyAccumulator struct {
+	count         int
+	correct       int
+	priceSamples  int
+	absErrorSum   float64
+	sqErrorSum    float64
+	pctErrorSum   float64
+	confidenceSum float64
+
+	intervalSamples int
+	covered80       int
+	covered95       int
+	brierSamples    int
+	brierSum        float64
+}
+
+// add 累加一条已验证的预测记录
+func (a *accuracyAccumulator) add(record model.PredictionRecord) {
+	a.count++
+	a.confidenceSum += record.Confidence
+	if record.IsCorrect != nil && *record.IsCorrect {
+		a.correct++
+	}
+
+	// 旧记录没有保存实际收盘价，只参与方向统计
+	if record.ActualPrice == nil || *record.ActualPrice <= 0 {
+		return
+	}
+
+	diff := record.PredictedPrice - *record.ActualPrice
+	a.priceSamples++
+	a.absErrorSum += math.Abs(diff)
+	a.sqErrorSum += diff * diff
+	a.pctErrorSum += math.Abs(diff) / *record.ActualPrice * 100
+
+	// 区间覆盖率，只统计保存了预测区间的记录
+	if record.Covered80 != nil && record.Covered95 != nil {
+		a.intervalSamples++
+		if *record.Covered80 {
+			a.covered80++
+		}
+		if *record.Covered95 {
+			a.covered95++
+		}
+	}
+
+	// Brier分数：上涨概率与实际是否上涨之差的平方
+	if record.UpProbability != nil {
+		outcome := 0.0
+		if *record.ActualPrice > record.CurrentPrice {
+			outcome = 1
+		}
+		diff := *record.UpProbability/100 - outcome
+		a.brierSamples++
+		a.brierSum += diff * diff
+	}
+}
+
+// directionAccuracy 方向准确率（百分比）
+func (a *accuracyAccumulator) directionAccuracy() float64 {
+	if a.count == 0 {
+		return 0
+	}
+	return float64(a.correct) / float64(a.count) * 100
+}
+
+// metrics 计算误差指标
+func (a *accuracyAccumulator) metrics() model.AccuracyMetrics {
+	metrics := model.AccuracyMetrics{
+		Count:             a.count,
+		Correct:           a.correct,
+		DirectionAccuracy: round2(a.directionAccuracy()),
+		PriceSamples:      a.priceSamples,
+	}
+
+	if a.priceSamples > 0 {
+		n := float64(a.priceSamples)
+		metrics.MAE = round2(a.absErrorSum / n)
+		metrics.RMSE = round2(math.Sqrt(a.sqErrorSum / n))
+		metrics.MAPE = round2(a.pctErrorSum / n)
+	}
+
+	if a.intervalSamples > 0 {
+		n := float64(a.intervalSamples)
+		metrics.IntervalSamples = a.intervalSamples
+		metrics.Coverage80 = round2(float64(a.covered80) / n * 100)
+		metrics.Coverage95 = round2(float64(a.covered95) / n * 100)
+	}
+
+	if a.brierSamples > 0 {
+		metrics.BrierScore = math.Round(a.brierSum/float64(a.brierSamples)*10000) / 10000
+	}
+
+	return metrics
+}
+
+// round2 保留两位小数
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// confidenceBucketIndex 置信度所在的区间序号（100 归入最后一个区间）
+func confidenceBucketIndex(confidence float64) int {
+	index := int(confidence) / confidenceBucketWidth
+	if index < 0 {
+		return 0
+	}
+	if last := 100/confidenceBucketWidth - 1; index > last {
+		return last
+	}
+	return index
+}
+
+// buildPredictionStats 根据已验证的预测记录计算统计指标
+func buildPredictionStats(records []model.PredictionRecord) *model.PredictionStats {
+	overall := &accuracyAccumulator{}
+	byIndex := make(map[string]*accuracyAccumulator)
+	byHorizon := make(map[string]*accuracyAccumulator)
+	byModel := make(map[string]*accuracyAccumulator)
+	byPromptVersion := make(map[string]*accuracyAccumulator)
+	byMonth := make(map[string]*accuracyAccumulator)
+	byConfidence := make([]accuracyAccumulator, 100/confidenceBucketWidth)
+
+	for _, record := range records {
+		overall.add(record)
+
+		if byIndex[record.IndexCode] == nil {
+			byIndex[record.IndexCode] = &accuracyAccumulator{}
+		}
+		byIndex[record.IndexCode].add(record)
+
+		horizon := horizonLabel(record.Horizon)
+		if byHorizon[horizon] == nil {
+			byHorizon[horizon] = &accuracyAccumulator{}
+		}
+		byHorizon[horizon].add(record)
+
+		if byModel[record.Model] == nil {
+			byModel[record.Model] = &accuracyAccumulator{}
+		}
+		byModel[record.Model].add(record)
+
+		// 只有使用了大模型的预测（含集成预测）才有提示词版本
+		if record.PromptVersion != "" {
+			if byPromptVersion[record.PromptVersion] == nil {
+				byPromptVersion[record.PromptVersion] = &accuracyAccumulator{}
+			}
+			byPromptVersion[record.PromptVersion].add(record)
+		}
+
+		month := record.PredictionDate.Format("2006-01")
+		if byMonth[month] == nil {
+			byMonth[month] = &accuracyAccumulator{}
+		}
+		byMonth[month].add(record)
+
+		byConfidence[confidenceBucketIndex(record.Confidence)].add(record)
+	}
+
+	stats := &model.PredictionStats{
+		TotalPredictions:   int64(overall.count),
+		CorrectPredictions: int64(overall.correct),
+		SuccessRate:        round2(overall.directionAccuracy()),
+		Overall:            overall.metrics(),
+		ByIndex:            make(map[string]model.AccuracyMetrics),
+		ByHorizon:          make(map[string]model.AccuracyMetrics),
+		ByModel:            make(map[string]model.AccuracyMetrics),
+		ByPromptVersion:    make(map[string]model.AccuracyMetrics),
+		ByMonth:            make(map[string]model.AccuracyMetrics),
+		ByConfidence:       []model.ConfidenceBucket{},
+		Calibration:        []model.CalibrationPoint{},
+	}
+
+	for code, acc := range byIndex {
+		stats.ByIndex[code] = acc.metrics()
+	}
+	for horizon, acc := range byHorizon {
+		stats.ByHorizon[horizon] = acc.metrics()
+	}
+	for name, acc := range byModel {
+		stats.ByModel[name] = acc.metrics()
+	}
+	for version, acc := range byPromptVersion {
+		stats.ByPromptVersion[version] = acc.metrics()
+	}
+	for month, acc := range byMonth {
+		stats.ByMonth[month] = acc.metrics()
+	}
+
+	for i := range byConfidence {
+		acc := &byConfidence[i]
+		if acc.count == 0 {
+			continue
+		}
+
+		lower := float64(i * confidenceBucketWidth)
+		upper := lower + confidenceBucketWidth
+		bucketRange := fmt.Sprintf("%.0f-%.0f", lower, upper)
+
+		stats.ByConfidence = append(stats.ByConfidence, model.ConfidenceBucket{
+			Range:           bucketRange,
+			Min:             lower,
+			Max:             upper,
+			AccuracyMetrics: acc.metrics(),
+		})
+
+		// 校准：置信度80%的预测应有约80%的方向准确率
+		meanConfidence := acc.confidenceSum / float64(acc.count)
+		stats.Calibration = append(stats.Calibration, model.CalibrationPoint{
+			Range:            bucketRange,
+			Count:            acc.count,
+			MeanConfidence:   round2(meanConfidence),
+			ObservedAccuracy: round2(acc.directionAccuracy()),
+			CalibrationGap:   round2(acc.directionAccuracy() - meanConfidence),
+		})
+	}
+
+	return stats
+}
+
+// GetPredictionStats 获取预测准确性统计，可按指数代码、预测周期和预测日期区间筛选（零值表示不限）
+func (ds *DataService) GetPredictionStats(indexCode string, horizon int, from, to time.Time) (*model.PredictionStats, error) {
+	if ds.db == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+
+	records, err := ds.db.GetValidatedPredictions(indexCode, horizon, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := buildPredictionStats(records)
+	stats.Index = indexCode
+	if horizon > 0 {
+		stats.Horizon = horizonLabel(horizon)
+	}
+	if !from.IsZero() {
+		stats.From = from.Format("2006-01-02")
+	}
+	if !to.IsZero() {
+		stats.To = to.Format("2006-01-02")
+	}
+
+	return stats, nil
+}
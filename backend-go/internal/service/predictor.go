@@ -0,0 +1,142 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"stock-prediction-backend/internal/config"
+	"stock-prediction-backend/internal/model"
+	"strings"
+	"time"
+)
+
+// ModelDeepSeek 引入多厂商大模型之前大模型预测的名称，配置中仍可使用，等同于 llm
+const ModelDeepSeek = "deepseek"
+
+// 预测模型名称
+const (
+	ModelLLM              = "llm"
+	ModelRandomWalk       = "random_walk"
+	ModelEMADrift         = "ema_drift"
+	ModelLinearRegression = "linear_regression"
+	ModelARIMAGARCH       = "arima_garch"
+	ModelMeanReversion    = "mean_reversion"
+	ModelMomentum         = "momentum"
+	ModelEnsemble         = "ensemble"
+)
+
+// PredictionInput 预测模型的输入数据
+type PredictionInput struct {
+	Instrument   model.Instrument
+	Horizon      int // 预测周期（交易日数）
+	CurrentPrice float64
+	Indicators   model.TechnicalIndicators
+	History      []model.StockData // 日K线，按日期升序
+	AsOf         time.Time         // 做出预测的时间，零值表示当前时间
+}
+
+// Predictor 预测模型接口
+// 返回目标交易日的预测收盘价、置信度，以及可选的预测区间和上涨概率
+type Predictor interface {
+	// Name 模型名称
+	Name() string
+	// Predict 对 input.Horizon 个交易日后的收盘价做出预测
+	Predict(input PredictionInput) (*PredictionResult, error)
+}
+
+// PredictorChain 按顺序回退的预测模型链
+// 主模型失败时（如大模型接口不可用），自动尝试下一个模型
+type PredictorChain struct {
+	predictors []Predictor
+}
+
+// NewPredictorChain 创建预测模型链
+func NewPredictorChain(predictors []Predictor) *PredictorChain {
+	return &PredictorChain{predictors: predictors}
+}
+
+// NewPredictors 根据配置创建预测模型列表（按优先级排序）
+func NewPredictors(cfg *config.Config, ds *DataService) []Predictor {
+	var predictors []Predictor
+	for _, name := range cfg.Prediction.Models {
+		if predictor := newPredictor(strings.ToLower(strings.TrimSpace(name)), cfg, ds); predictor != nil {
+			predictors = append(predictors, predictor)
+			continue
+		}
+		log.Printf("⚠️ 预测模型 %s 未知或未启用，已忽略", name)
+	}
+
+	if len(predictors) == 0 {
+		log.Printf("⚠️ 未配置有效的预测模型，使用默认大模型（如已启用）并以随机游走兜底")
+		if ds.llmEnabled() {
+			predictors = append(predictors, &llmPredictor{ds: ds})
+		}
+		predictors = append(predictors, randomWalkPredictor{})
+	}
+
+	return predictors
+}
+
+// newPredictor 按名称创建预测模型，未知名称或大模型未启用时返回 nil
+func newPredictor(name string, cfg *config.Config, ds *DataService) Predictor {
+	switch name {
+	case ModelLLM, ModelDeepSeek:
+		if !ds.llmEnabled() {
+			return nil
+		}
+		return &llmPredictor{ds: ds}
+	case ModelEnsemble:
+		return newEnsemblePredictor(cfg, ds)
+	default:
+		return newStatisticalPredictor(name)
+	}
+}
+
+// Name 模型链名称
+func (c *PredictorChain) Name() string {
+	names := make([]string, 0, len(c.predictors))
+	for _, p := range c.predictors {
+		names = append(names, p.Name())
+	}
+	return strings.Join(names, ">")
+}
+
+// Predict 依次尝试各模型，返回第一个成功的预测结果，结果中记录产生预测的模型
+func (c *PredictorChain) Predict(input PredictionInput) (*PredictionResult, error) {
+	var errs []string
+
+	for _, p := range c.predictors {
+		result, err := p.Predict(input)
+		if err == nil && result.PredictedPrice <= 0 {
+			err = fmt.Errorf("预测价格无效: %.2f", result.PredictedPrice)
+		}
+		if err != nil {
+			log.Printf("⚠️ 预测模型 %s 预测失败 %s: %v", p.Name(), input.Instrument.Code, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", p.Name(), err))
+			continue
+		}
+
+		result.Model = p.Name()
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("所有预测模型均失败: %s", strings.Join(errs, "; "))
+}
+
+// llmPredictor 基于 OpenAI 兼容大模型的预测，按标的使用配置的大模型
+type llmPredictor struct {
+	ds *DataService
+}
+
+// Name 模型名称
+func (p *llmPredictor) Name() string {
+	return ModelLLM
+}
+
+// Predict 调用大模型进行预测
+func (p *llmPredictor) Predict(input PredictionInput) (*PredictionResult, error) {
+	asOf := input.AsOf
+	if asOf.IsZero() {
+		asOf = time.Now()
+	}
+	return p.ds.predictWithLLM(input.Instrument, input.Horizon, input.CurrentPrice, input.Indicators, input.History, asOf)
+}
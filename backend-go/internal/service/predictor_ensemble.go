@@ -0,0 +1,226 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"stock-prediction-backend/internal/config"
+	"stock-prediction-backend/internal/model"
+	"strings"
+)
+
+// ensembleShrinkage 成员误差样本较少时，向所有成员平均误差收缩的强度（等效样本数）
+const ensembleShrinkage = 5
+
+// minEnsembleMSE 误差均方的下限，避免个别成员的权重趋于无穷
+const minEnsembleMSE = 0.01
+
+// ensemblePredictor 集成预测：同时运行大模型和统计模型，
+// 按各成员近期已验证预测的误差加权平均（权重与误差均方成反比）
+type ensemblePredictor struct {
+	ds                  *DataService
+	members             []Predictor
+	weightWindow        int
+	divergenceThreshold float64
+}
+
+// ensembleMemberResult 单个成员的预测结果
+type ensembleMemberResult struct {
+	name     string
+	result   *PredictionResult
+	interval model.PredictionInterval
+	errors   []float64 // 近期已验证预测的百分比误差
+}
+
+// newEnsemblePredictor 根据配置创建集成预测，没有有效成员时返回 nil
+func newEnsemblePredictor(cfg *config.Config, ds *DataService) Predictor {
+	var members []Predictor
+	for _, name := range cfg.Prediction.EnsembleMembers {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == ModelEnsemble {
+			continue
+		}
+		if member := newPredictor(name, cfg, ds); member != nil {
+			members = append(members, member)
+			continue
+		}
+		log.Printf("⚠️ 集成预测成员 %s 未知或未启用，已忽略", name)
+	}
+
+	if len(members) == 0 {
+		log.Printf("⚠️ 集成预测没有有效成员")
+		return nil
+	}
+
+	return &ensemblePredictor{
+		ds:                  ds,
+		members:             members,
+		weightWindow:        cfg.Prediction.EnsembleWeightWindow,
+		divergenceThreshold: cfg.Prediction.EnsembleDivergence,
+	}
+}
+
+// Name 模型名称
+func (p *ensemblePredictor) Name() string {
+	return ModelEnsemble
+}
+
+// Predict 运行所有成员并加权合成预测价格、置信度和预测区间，部分成员失败时使用其余成员
+func (p *ensemblePredictor) Predict(input PredictionInput) (*PredictionResult, error) {
+	var members []ensembleMemberResult
+	var errs []string
+
+	for _, member := range p.members {
+		result, err := member.Predict(input)
+		if err == nil && result.PredictedPrice <= 0 {
+			err = fmt.Errorf("预测价格无效: %.2f", result.PredictedPrice)
+		}
+		if err != nil {
+			log.Printf("⚠️ 集成成员 %s 预测失败 %s: %v", member.Name(), input.Instrument.Code, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", member.Name(), err))
+			continue
+		}
+
+		members = append(members, ensembleMemberResult{
+			name:     member.Name(),
+			result:   result,
+			interval: resolvePredictionInterval(result, input.Instrument, input.CurrentPrice, input.Indicators.Volatility, input.Horizon),
+			errors:   p.recentErrors(input, member.Name()),
+		})
+	}
+
+	if len(members) == 0 {
+		return nil, fmt.Errorf("所有集成成员均失败: %s", strings.Join(errs, "; "))
+	}
+
+	errorLists := make([][]float64, len(members))
+	for i, member := range members {
+		errorLists[i] = member.errors
+	}
+	weights, rmses := ensembleWeights(errorLists)
+
+	combined := &PredictionResult{IntervalSource: IntervalSourceEnsemble}
+	breakdown := &model.EnsembleBreakdown{Members: make([]model.EnsembleMember, 0, len(members))}
+	var summary []string
+
+	for i, member := range members {
+		w := weights[i]
+		combined.PredictedPrice += w * member.result.PredictedPrice
+		combined.Confidence += w * member.result.Confidence
+		combined.Lower80 += w * member.interval.Lower80
+		combined.Upper80 += w * member.interval.Upper80
+		combined.Lower95 += w * member.interval.Lower95
+		combined.Upper95 += w * member.interval.Upper95
+		combined.UpProbability += w * member.interval.UpProbability
+
+		breakdown.Members = append(breakdown.Members, model.EnsembleMember{
+			Model:         member.name,
+			Predicted:     roundPrice(member.result.PredictedPrice, input.Instrument),
+			ChangePercent: round2((member.result.PredictedPrice - input.CurrentPrice) / input.CurrentPrice * 100),
+			Confidence:    round2(member.result.Confidence),
+			Weight:        math.Round(w*10000) / 10000,
+			Samples:       len(member.errors),
+			RMSE:          round2(rmses[i]),
+		})
+		summary = append(summary, fmt.Sprintf("%s=%.2f(权重%.2f)", member.name, member.result.PredictedPrice, w))
+	}
+
+	combined.PredictedPrice = roundPrice(combined.PredictedPrice, input.Instrument)
+	combined.Confidence = round2(combined.Confidence)
+	p.markDivergence(breakdown, members, weights, input)
+
+	combined.Ensemble = breakdown
+	for _, member := range members {
+		if member.result.LLMTrace != nil {
+			combined.LLMTrace = member.result.LLMTrace
+		}
+	}
+	combined.Reasoning = "集成预测: " + strings.Join(summary, ", ")
+	return combined, nil
+}
+
+// recentErrors 获取成员在该标的和预测周期上最近的已验证误差，没有数据库时返回空
+func (p *ensemblePredictor) recentErrors(input PredictionInput, name string) []float64 {
+	if p.ds == nil || p.ds.db == nil || p.weightWindow <= 0 {
+		return nil
+	}
+
+	errors, err := p.ds.db.GetMemberErrors(input.Instrument.Code, input.Horizon, name, p.weightWindow)
+	if err != nil {
+		log.Printf("⚠️ %v", err)
+		return nil
+	}
+	return errors
+}
+
+// markDivergence 计算大模型相对统计模型加权价格的偏离，超过阈值时标记分歧
+func (p *ensemblePredictor) markDivergence(breakdown *model.EnsembleBreakdown, members []ensembleMemberResult, weights []float64, input PredictionInput) {
+	llmPrice := 0.0
+	baseline, baselineWeight := 0.0, 0.0
+	for i, member := range members {
+		if member.name == ModelLLM {
+			llmPrice = member.result.PredictedPrice
+			continue
+		}
+		baseline += weights[i] * member.result.PredictedPrice
+		baselineWeight += weights[i]
+	}
+	if llmPrice <= 0 || baselineWeight <= 0 {
+		return
+	}
+	baseline /= baselineWeight
+
+	divergence := round2((llmPrice - baseline) / baseline * 100)
+	threshold := p.divergenceThreshold * math.Sqrt(float64(input.Horizon))
+	breakdown.LLMDivergence = &divergence
+	breakdown.Divergent = p.divergenceThreshold > 0 && math.Abs(divergence) > threshold
+
+	if breakdown.Divergent {
+		log.Printf("⚠️ %s %s 大模型预测 %.2f 与统计模型 %.2f 偏离 %.2f%%（阈值 %.2f%%）",
+			input.Instrument.Code, horizonLabel(input.Horizon), llmPrice, baseline, divergence, threshold)
+	}
+}
+
+// ensembleWeights 根据各成员的近期百分比误差计算归一化权重和误差均方根
+// 误差均方向所有有样本成员的平均值收缩，样本越少越接近平均；都没有样本时等权
+func ensembleWeights(errorLists [][]float64) ([]float64, []float64) {
+	n := len(errorLists)
+	mses := make([]float64, n)
+	rmses := make([]float64, n)
+
+	prior, withSamples := 0.0, 0
+	for i, errors := range errorLists {
+		if len(errors) == 0 {
+			continue
+		}
+		for _, e := range errors {
+			mses[i] += e * e
+		}
+		mses[i] /= float64(len(errors))
+		rmses[i] = math.Sqrt(mses[i])
+		prior += mses[i]
+		withSamples++
+	}
+
+	weights := make([]float64, n)
+	if withSamples == 0 {
+		for i := range weights {
+			weights[i] = 1 / float64(n)
+		}
+		return weights, rmses
+	}
+	prior /= float64(withSamples)
+
+	total := 0.0
+	for i, errors := range errorLists {
+		samples := float64(len(errors))
+		mse := (samples*mses[i] + ensembleShrinkage*prior) / (samples + ensembleShrinkage)
+		weights[i] = 1 / math.Max(mse, minEnsembleMSE)
+		total += weights[i]
+	}
+	for i := range weights {
+		weights[i] /= total
+	}
+
+	return weights, rmses
+}
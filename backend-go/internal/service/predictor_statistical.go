@@ -0,0 +1,313 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"stock-prediction-backend/internal/model"
+)
+
+// minReturnSamples 统计模型至少需要的日收益率个数
+const minReturnSamples = 10
+
+// newStatisticalPredictor 按名称创建统计模型，未知名称返回 nil
+func newStatisticalPredictor(name string) Predictor {
+	switch name {
+	case ModelRandomWalk:
+		return randomWalkPredictor{}
+	case ModelEMADrift:
+		return emaDriftPredictor{span: 10}
+	case ModelLinearRegression:
+		return linearRegressionPredictor{window: 20}
+	case ModelARIMAGARCH:
+		return arimaGARCHPredictor{alpha: 0.1, beta: 0.85}
+	case ModelMeanReversion:
+		return meanReversionPredictor{speed: 0.1}
+	case ModelMomentum:
+		return momentumPredictor{lookback: 10, persistence: 0.5}
+	default:
+		return nil
+	}
+}
+
+// logReturns 计算日对数收益率
+func logReturns(history []model.StockData) []float64 {
+	returns := make([]float64, 0, len(history))
+	for i := 1; i < len(history); i++ {
+		if history[i-1].Close > 0 && history[i].Close > 0 {
+			returns = append(returns, math.Log(history[i].Close/history[i-1].Close))
+		}
+	}
+	return returns
+}
+
+// meanStd 计算均值和样本标准差
+func meanStd(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	if len(values) < 2 {
+		return mean, 0
+	}
+
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)-1))
+}
+
+// statisticalResult 根据周期对数收益率的期望和标准差生成预测结果
+// 置信度取方向判断的把握程度 max(P(涨), P(跌))，区间按正态分布计算
+func statisticalResult(input PredictionInput, expectedReturn, sigma float64, reasoning string) *PredictionResult {
+	sigma = math.Max(sigma, minDailyVolatility/100)
+	predicted := input.CurrentPrice * math.Exp(expectedReturn)
+	interval := normalInterval(input.Instrument, input.CurrentPrice, predicted, sigma)
+
+	return &PredictionResult{
+		PredictedPrice: roundPrice(predicted, input.Instrument),
+		Confidence:     round2(math.Max(interval.UpProbability, 100-interval.UpProbability)),
+		Lower80:        interval.Lower80,
+		Upper80:        interval.Upper80,
+		Lower95:        interval.Lower95,
+		Upper95:        interval.Upper95,
+		UpProbability:  interval.UpProbability,
+		IntervalSource: IntervalSourceVolatility,
+		Reasoning:      reasoning,
+	}
+}
+
+// returnsOrError 获取足够数量的日收益率
+func returnsOrError(input PredictionInput) ([]float64, error) {
+	if input.CurrentPrice <= 0 {
+		return nil, fmt.Errorf("当前价格无效: %.2f", input.CurrentPrice)
+	}
+	returns := logReturns(input.History)
+	if len(returns) < minReturnSamples {
+		return nil, fmt.Errorf("历史数据不足: %d 个日收益率，至少需要 %d 个", len(returns), minReturnSamples)
+	}
+	return returns, nil
+}
+
+// randomWalkPredictor 随机游走：预测价格等于当前价格，区间由历史波动率决定
+type randomWalkPredictor struct{}
+
+// Name 模型名称
+func (randomWalkPredictor) Name() string {
+	return ModelRandomWalk
+}
+
+// Predict 随机游走预测，历史数据不足时使用最小波动率
+func (randomWalkPredictor) Predict(input PredictionInput) (*PredictionResult, error) {
+	if input.CurrentPrice <= 0 {
+		return nil, fmt.Errorf("当前价格无效: %.2f", input.CurrentPrice)
+	}
+
+	_, sigma := meanStd(logReturns(input.History))
+	return statisticalResult(input, 0, sigma*math.Sqrt(float64(input.Horizon)),
+		fmt.Sprintf("随机游走：价格不变，日波动率 %.2f%%", sigma*100)), nil
+}
+
+// emaDriftPredictor EMA漂移：以日收益率的指数移动平均作为漂移项
+type emaDriftPredictor struct {
+	span int // EMA 跨度（交易日）
+}
+
+// Name 模型名称
+func (emaDriftPredictor) Name() string {
+	return ModelEMADrift
+}
+
+// Predict EMA漂移预测
+func (p emaDriftPredictor) Predict(input PredictionInput) (*PredictionResult, error) {
+	returns, err := returnsOrError(input)
+	if err != nil {
+		return nil, err
+	}
+
+	alpha := 2 / float64(p.span+1)
+	drift := returns[0]
+	for _, r := range returns[1:] {
+		drift = alpha*r + (1-alpha)*drift
+	}
+
+	_, sigma := meanStd(returns)
+	h := float64(input.Horizon)
+	return statisticalResult(input, drift*h, sigma*math.Sqrt(h),
+		fmt.Sprintf("EMA漂移：%d日收益率EMA %.3f%%/日，日波动率 %.2f%%", p.span, drift*100, sigma*100)), nil
+}
+
+// linearRegressionPredictor 线性回归：对最近 window 个交易日的对数价格做时间回归，按斜率外推
+type linearRegressionPredictor struct {
+	window int
+}
+
+// Name 模型名称
+func (linearRegressionPredictor) Name() string {
+	return ModelLinearRegression
+}
+
+// Predict 线性回归预测，区间使用回归残差的标准差
+func (p linearRegressionPredictor) Predict(input PredictionInput) (*PredictionResult, error) {
+	if _, err := returnsOrError(input); err != nil {
+		return nil, err
+	}
+
+	history := input.History
+	if len(history) > p.window {
+		history = history[len(history)-p.window:]
+	}
+
+	var xs, ys []float64
+	for i, bar := range history {
+		if bar.Close > 0 {
+			xs = append(xs, float64(i))
+			ys = append(ys, math.Log(bar.Close))
+		}
+	}
+
+	xMean, _ := meanStd(xs)
+	yMean, _ := meanStd(ys)
+	var sxy, sxx float64
+	for i := range xs {
+		sxy += (xs[i] - xMean) * (ys[i] - yMean)
+		sxx += (xs[i] - xMean) * (xs[i] - xMean)
+	}
+	if sxx == 0 {
+		return nil, fmt.Errorf("回归样本不足")
+	}
+	slope := sxy / sxx
+
+	// 残差反映价格偏离趋势线的日常波动
+	var ssr float64
+	for i := range xs {
+		residual := ys[i] - (yMean + slope*(xs[i]-xMean))
+		ssr += residual * residual
+	}
+	residualStd := math.Sqrt(ssr / float64(len(xs)-2))
+
+	h := float64(input.Horizon)
+	return statisticalResult(input, slope*h, residualStd*math.Sqrt(h),
+		fmt.Sprintf("线性回归：%d日对数价格斜率 %.3f%%/日，残差标准差 %.2f%%", len(xs), slope*100, residualStd*100)), nil
+}
+
+// arimaGARCHPredictor 简化的 AR(1)+GARCH(1,1)：
+// 收益率均值按 AR(1) 回归到长期均值，方差按固定参数的 GARCH(1,1) 递推
+type arimaGARCHPredictor struct {
+	alpha float64 // GARCH 冲击系数
+	beta  float64 // GARCH 持续系数
+}
+
+// Name 模型名称
+func (arimaGARCHPredictor) Name() string {
+	return ModelARIMAGARCH
+}
+
+// Predict AR(1)+GARCH(1,1) 预测
+func (p arimaGARCHPredictor) Predict(input PredictionInput) (*PredictionResult, error) {
+	returns, err := returnsOrError(input)
+	if err != nil {
+		return nil, err
+	}
+
+	mean, std := meanStd(returns)
+
+	// AR(1)：r_t - μ = φ(r_{t-1} - μ) + ε_t，φ 截断在 (-0.99, 0.99) 保证平稳
+	var num, den float64
+	for i := 1; i < len(returns); i++ {
+		num += (returns[i] - mean) * (returns[i-1] - mean)
+		den += (returns[i-1] - mean) * (returns[i-1] - mean)
+	}
+	phi := 0.0
+	if den > 0 {
+		phi = math.Max(-0.99, math.Min(0.99, num/den))
+	}
+
+	// GARCH(1,1)：σ²_t = ω + α·ε²_{t-1} + β·σ²_{t-1}，ω 使长期方差等于样本方差
+	longRunVar := std * std
+	omega := longRunVar * (1 - p.alpha - p.beta)
+	variance := longRunVar
+	for i := 1; i < len(returns); i++ {
+		shock := returns[i] - mean - phi*(returns[i-1]-mean)
+		variance = omega + p.alpha*shock*shock + p.beta*variance
+	}
+
+	// 逐日累加期望收益和条件方差
+	last := returns[len(returns)-1] - mean
+	expectedReturn, totalVar := 0.0, 0.0
+	for k := 1; k <= input.Horizon; k++ {
+		last *= phi
+		expectedReturn += mean + last
+		totalVar += variance
+		variance = omega + (p.alpha+p.beta)*variance
+	}
+
+	return statisticalResult(input, expectedReturn, math.Sqrt(totalVar),
+		fmt.Sprintf("AR(1)+GARCH(1,1)：φ=%.3f，条件日波动率 %.2f%%", phi, math.Sqrt(totalVar/float64(input.Horizon))*100)), nil
+}
+
+// meanReversionPredictor 均值回归：价格按固定速度向20日均线回归
+type meanReversionPredictor struct {
+	speed float64 // 每个交易日回归的比例
+}
+
+// Name 模型名称
+func (meanReversionPredictor) Name() string {
+	return ModelMeanReversion
+}
+
+// Predict 均值回归预测，h 日后剩余偏离为 (1-speed)^h
+func (p meanReversionPredictor) Predict(input PredictionInput) (*PredictionResult, error) {
+	returns, err := returnsOrError(input)
+	if err != nil {
+		return nil, err
+	}
+
+	mean := input.Indicators.MA20
+	if !input.Indicators.Sufficient("ma20") || mean <= 0 {
+		return nil, fmt.Errorf("K线数不足，缺少20日均线")
+	}
+
+	reverted := 1 - math.Pow(1-p.speed, float64(input.Horizon))
+	expectedReturn := reverted * math.Log(mean/input.CurrentPrice)
+
+	_, sigma := meanStd(returns)
+	return statisticalResult(input, expectedReturn, sigma*math.Sqrt(float64(input.Horizon)),
+		fmt.Sprintf("均值回归：向MA20 %.2f 回归 %.0f%%，日波动率 %.2f%%", mean, reverted*100, sigma*100)), nil
+}
+
+// momentumPredictor 动量：最近 lookback 个交易日的平均收益按一定比例延续
+type momentumPredictor struct {
+	lookback    int     // 动量回看交易日数
+	persistence float64 // 动量延续比例
+}
+
+// Name 模型名称
+func (momentumPredictor) Name() string {
+	return ModelMomentum
+}
+
+// Predict 动量预测
+func (p momentumPredictor) Predict(input PredictionInput) (*PredictionResult, error) {
+	returns, err := returnsOrError(input)
+	if err != nil {
+		return nil, err
+	}
+
+	recent := returns
+	if len(recent) > p.lookback {
+		recent = recent[len(recent)-p.lookback:]
+	}
+	momentum, _ := meanStd(recent)
+
+	_, sigma := meanStd(returns)
+	h := float64(input.Horizon)
+	return statisticalResult(input, p.persistence*momentum*h, sigma*math.Sqrt(h),
+		fmt.Sprintf("动量：%d日平均收益 %.3f%%/日，延续 %.0f%%", len(recent), momentum*100, p.persistence*100)), nil
+}
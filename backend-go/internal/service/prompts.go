@@ -0,0 +1,280 @@
+package service
+
+import (
+	"embed"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"stock-prediction-backend/internal/indicators"
+	"stock-prediction-backend/internal/model"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed data/prompts
+var defaultPrompts embed.FS
+
+// 提示词 A/B 分组方式
+const (
+	PromptSplitIndex = "index" // 按标的分组，同一标的始终使用同一模板
+	PromptSplitDay   = "day"   // 按交易日分组，同一天所有标的使用同一模板
+)
+
+// promptHistoryDays 提示词中列出的最近交易日K线数
+const promptHistoryDays = 10
+
+// PromptData 提示词模板的数据
+type PromptData struct {
+	InstrumentType string // 标的类型名称，如 中国股票指数
+	Code           string
+	Name           string
+	Symbol         string
+
+	Horizon      int    // 预测周期（交易日数）
+	TargetDate   string // 目标交易日
+	HorizonFocus string // 该预测周期的分析侧重点
+
+	CurrentPrice float64
+	Indicators   model.TechnicalIndicators
+	History      []PromptBar // 最近的日K线，按日期升序
+	bars         []model.StockData
+
+	PriceBand  float64 // 预测价格允许的变化范围（百分比）
+	PriceLimit float64 // 每日涨跌幅限制（百分比），0 表示没有限制
+	LimitLower float64 // 连续跌停后的价格
+	LimitUpper float64 // 连续涨停后的价格
+
+	FlatChangePercent float64 // direction 为 flat 时允许的最大变化（百分比）
+	MaxKeyFactors     int     // key_factors 的最大条数
+}
+
+// Latest 在模板中按名称选择技术指标，如 {{range .Latest "macd" "kdj"}}，名称未知时渲染失败
+func (d PromptData) Latest(names ...string) ([]indicators.Value, error) {
+	return indicators.Latest(d.bars, names...)
+}
+
+// PromptBar 提示词中的一根日K线
+type PromptBar struct {
+	Date  string // MM-DD
+	Open  float64
+	High  float64
+	Low   float64
+	Close float64
+}
+
+// PromptTemplate 一个版本的提示词模板，文件中需定义 system 和 user 两个模板
+type PromptTemplate struct {
+	Name    string
+	Version string
+	tmpl    *template.Template
+}
+
+// ID 模板标识，如 analysis/v1，随预测记录保存
+func (t *PromptTemplate) ID() string {
+	return t.Name + "/" + t.Version
+}
+
+// Render 渲染系统提示词和用户提示词
+func (t *PromptTemplate) Render(data PromptData) (string, string, error) {
+	var system, user strings.Builder
+	if err := t.tmpl.ExecuteTemplate(&system, "system", data); err != nil {
+		return "", "", fmt.Errorf("渲染提示词 %s 失败: %v", t.ID(), err)
+	}
+	if err := t.tmpl.ExecuteTemplate(&user, "user", data); err != nil {
+		return "", "", fmt.Errorf("渲染提示词 %s 失败: %v", t.ID(), err)
+	}
+	return strings.TrimSpace(system.String()), strings.TrimSpace(user.String()), nil
+}
+
+// promptVariant A/B 实验中的一个模板及其权重
+type promptVariant struct {
+	template *PromptTemplate
+	weight   int
+}
+
+// PromptLibrary 提示词模板库，按配置的权重和分组方式为每次预测选择模板
+type PromptLibrary struct {
+	templates map[string]*PromptTemplate
+	variants  []promptVariant
+	total     int
+	split     string
+	location  *time.Location
+}
+
+// NewPromptLibrary 加载内置模板和 dir 下的模板（<名称>/<版本>.tmpl，同名同版本时覆盖内置模板），
+// variants 为参与实验的模板及权重，如 analysis/v1:50,analysis/v2:50，权重省略时为 1
+func NewPromptLibrary(dir string, variants []string, split string, location *time.Location) (*PromptLibrary, error) {
+	library := &PromptLibrary{
+		templates: make(map[string]*PromptTemplate),
+		split:     strings.ToLower(strings.TrimSpace(split)),
+		location:  location,
+	}
+
+	switch library.split {
+	case "":
+		library.split = PromptSplitIndex
+	case PromptSplitIndex, PromptSplitDay:
+	default:
+		return nil, fmt.Errorf("未知的提示词分组方式: %s（支持 index、day）", split)
+	}
+
+	builtin, err := fs.Sub(defaultPrompts, "data/prompts")
+	if err != nil {
+		return nil, err
+	}
+	if err := library.load(builtin); err != nil {
+		return nil, fmt.Errorf("加载内置提示词模板失败: %v", err)
+	}
+
+	if dir != "" {
+		if _, err := os.Stat(dir); err == nil {
+			if err := library.load(os.DirFS(dir)); err != nil {
+				return nil, fmt.Errorf("加载提示词模板目录 %s 失败: %v", dir, err)
+			}
+		} else if os.IsNotExist(err) {
+			log.Printf("⚠️ 提示词模板目录不存在: %s，使用内置模板", dir)
+		} else {
+			return nil, fmt.Errorf("读取提示词模板目录失败: %v", err)
+		}
+	}
+
+	for _, item := range variants {
+		id, weightValue, hasWeight := strings.Cut(strings.TrimSpace(item), ":")
+		tmpl, exists := library.templates[id]
+		if !exists {
+			return nil, fmt.Errorf("提示词模板不存在: %s", id)
+		}
+
+		weight := 1
+		if hasWeight {
+			weight, err = strconv.Atoi(weightValue)
+			if err != nil || weight <= 0 {
+				return nil, fmt.Errorf("提示词模板 %s 的权重无效: %s", id, weightValue)
+			}
+		}
+
+		library.variants = append(library.variants, promptVariant{template: tmpl, weight: weight})
+		library.total += weight
+	}
+	if len(library.variants) == 0 {
+		return nil, fmt.Errorf("没有配置参与预测的提示词模板")
+	}
+
+	return library, nil
+}
+
+// load 加载文件系统中 <名称>/<版本>.tmpl 格式的模板
+func (l *PromptLibrary) load(fsys fs.FS) error {
+	files, err := fs.Glob(fsys, "*/*.tmpl")
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+
+		name := path.Dir(file)
+		version := strings.TrimSuffix(path.Base(file), ".tmpl")
+		tmpl, err := template.New(file).Option("missingkey=error").Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("解析提示词模板 %s 失败: %v", file, err)
+		}
+		for _, required := range []string{"system", "user"} {
+			if tmpl.Lookup(required) == nil {
+				return fmt.Errorf("提示词模板 %s 缺少 %s 定义", file, required)
+			}
+		}
+
+		prompt := &PromptTemplate{Name: name, Version: version, tmpl: tmpl}
+		if _, exists := l.templates[prompt.ID()]; exists {
+			log.Printf("⚠️ 提示词模板 %s 覆盖内置版本", prompt.ID())
+		}
+		l.templates[prompt.ID()] = prompt
+	}
+
+	return nil
+}
+
+// Select 为标的在 asOf 这一天的预测选择模板，相同的标的（或交易日）总是得到相同的模板
+func (l *PromptLibrary) Select(code string, asOf time.Time) *PromptTemplate {
+	if len(l.variants) == 1 {
+		return l.variants[0].template
+	}
+
+	key := code
+	if l.split == PromptSplitDay {
+		key = asOf.In(l.location).Format("2006-01-02")
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+
+	bucket := int(h.Sum32() % uint32(l.total))
+	for _, variant := range l.variants {
+		if bucket < variant.weight {
+			return variant.template
+		}
+		bucket -= variant.weight
+	}
+	return l.variants[len(l.variants)-1].template
+}
+
+// Describe 模板库摘要，用于启动日志
+func (l *PromptLibrary) Describe() string {
+	ids := make([]string, 0, len(l.templates))
+	for id := range l.templates {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	active := make([]string, 0, len(l.variants))
+	for _, variant := range l.variants {
+		active = append(active, fmt.Sprintf("%s(%d)", variant.template.ID(), variant.weight))
+	}
+	return fmt.Sprintf("可用 %s，使用 %s，按%s分组", strings.Join(ids, ", "), strings.Join(active, ", "), l.split)
+}
+
+// newPromptData 构建提示词模板的数据
+func newPromptData(instrument model.Instrument, horizon int, currentPrice float64, indicators model.TechnicalIndicators, historicalData []model.StockData, targetDate string) PromptData {
+	data := PromptData{
+		InstrumentType:    instrumentTypeName(instrument.Type),
+		Code:              instrument.Code,
+		Name:              instrument.Name,
+		Symbol:            instrument.Symbol,
+		Horizon:           horizon,
+		TargetDate:        targetDate,
+		HorizonFocus:      horizonFocus(horizon),
+		CurrentPrice:      currentPrice,
+		Indicators:        indicators,
+		bars:              historicalData,
+		PriceBand:         horizonPriceBand(instrument, horizon),
+		PriceLimit:        priceLimitPercent(instrument),
+		FlatChangePercent: llmFlatChangePercent,
+		MaxKeyFactors:     maxKeyFactors,
+	}
+	data.LimitLower, data.LimitUpper = horizonPriceLimits(currentPrice, data.PriceLimit, horizon)
+
+	recent := historicalData
+	if len(recent) > promptHistoryDays {
+		recent = recent[len(recent)-promptHistoryDays:]
+	}
+	for _, bar := range recent {
+		data.History = append(data.History, PromptBar{
+			Date:  bar.Date.Format("01-02"),
+			Open:  bar.Open,
+			High:  bar.High,
+			Low:   bar.Low,
+			Close: bar.Close,
+		})
+	}
+
+	return data
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"log"
+	"math"
+	"sort"
+	"stock-prediction-backend/internal/model"
+	"sync"
+	"time"
+)
+
+const (
+	// latencyWindow 每个数据源保留的最近延迟样本数
+	latencyWindow = 100
+	// downFailureThreshold 连续失败达到该次数视为不可用
+	downFailureThreshold = 3
+)
+
+// providerHealth 单个数据源的健康统计
+type providerHealth struct {
+	lastSuccess         time.Time
+	lastError           string
+	lastErrorAt         time.Time
+	consecutiveFailures int
+	lastQuoteTime       time.Time
+	latencies           []time.Duration // 环形缓冲区
+	next                int
+}
+
+// ProviderHealthMonitor 行情数据源健康监控
+// 同时记录后台探测和真实请求的结果，查询时直接返回内存快照
+type ProviderHealthMonitor struct {
+	mu    sync.RWMutex
+	order []string
+	stats map[string]*providerHealth
+}
+
+// NewProviderHealthMonitor 创建健康监控
+func NewProviderHealthMonitor(providers []MarketDataProvider) *ProviderHealthMonitor {
+	m := &ProviderHealthMonitor{
+		stats: make(map[string]*providerHealth),
+	}
+	for _, p := range providers {
+		m.order = append(m.order, p.Name())
+		m.stats[p.Name()] = &providerHealth{}
+	}
+	return m
+}
+
+// Record 记录一次数据源调用结果，quote 为空表示该次调用不是报价请求
+func (m *ProviderHealthMonitor) Record(name string, latency time.Duration, quote *model.StockData, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, exists := m.stats[name]
+	if !exists {
+		h = &providerHealth{}
+		m.stats[name] = h
+		m.order = append(m.order, name)
+	}
+
+	if len(h.latencies) < latencyWindow {
+		h.latencies = append(h.latencies, latency)
+	} else {
+		h.latencies[h.next] = latency
+	}
+	h.next = (h.next + 1) % latencyWindow
+
+	if err != nil {
+		h.lastError = err.Error()
+		h.lastErrorAt = time.Now()
+		h.consecutiveFailures++
+		return
+	}
+
+	h.lastSuccess = time.Now()
+	h.consecutiveFailures = 0
+	if quote != nil {
+		h.lastQuoteTime = quote.Date
+	}
+}
+
+// Snapshot 返回所有数据源的健康状态（按数据源优先级排序）
+func (m *ProviderHealthMonitor) Snapshot() []model.ProviderStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []model.ProviderStatus
+	for _, name := range m.order {
+		h := m.stats[name]
+
+		status := model.ProviderStatus{
+			Name:                name,
+			Status:              providerStatusText(h),
+			LastError:           h.lastError,
+			ConsecutiveFailures: h.consecutiveFailures,
+			Samples:             len(h.latencies),
+		}
+
+		if !h.lastSuccess.IsZero() {
+			status.LastSuccess = h.lastSuccess.UTC().Format(time.RFC3339)
+		}
+		if !h.lastErrorAt.IsZero() {
+			status.LastErrorAt = h.lastErrorAt.UTC().Format(time.RFC3339)
+		}
+		if !h.lastQuoteTime.IsZero() {
+			status.LastQuoteTime = h.lastQuoteTime.UTC().Format(time.RFC3339)
+			status.DataFreshnessSeconds = math.Round(time.Since(h.lastQuoteTime).Seconds())
+		}
+
+		if len(h.latencies) > 0 {
+			sorted := make([]time.Duration, len(h.latencies))
+			copy(sorted, h.latencies)
+			sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+			status.LatencyP50Ms = latencyPercentileMs(sorted, 50)
+			status.LatencyP90Ms = latencyPercentileMs(sorted, 90)
+			status.LatencyP99Ms = latencyPercentileMs(sorted, 99)
+		}
+
+		result = append(result, status)
+	}
+
+	return result
+}
+
+// providerStatusText 根据统计判断数据源状态
+func providerStatusText(h *providerHealth) string {
+	switch {
+	case len(h.latencies) == 0:
+		return "unknown"
+	case h.consecutiveFailures >= downFailureThreshold:
+		return "down"
+	case h.consecutiveFailures > 0:
+		return "degraded"
+	default:
+		return "healthy"
+	}
+}
+
+// latencyPercentileMs 计算已排序延迟样本的百分位（毫秒，最近秩法）
+func latencyPercentileMs(sorted []time.Duration, percentile float64) float64 {
+	rank := int(math.Ceil(percentile/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	ms := float64(sorted[rank]) / float64(time.Millisecond)
+	return math.Round(ms*100) / 100
+}
+
+// startHealthProbes 启动后台健康探测，按固定间隔探测每个数据源
+func (ds *DataService) startHealthProbes(interval time.Duration) {
+	if interval <= 0 {
+		log.Printf("⚠️ 数据源健康探测已禁用")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		ds.probeProviders()
+
+		select {
+		case <-ticker.C:
+		case <-ds.probeStop:
+			return
+		}
+	}
+}
+
+// probeProviders 逐个探测数据源并记录结果
+func (ds *DataService) probeProviders() {
+	for _, p := range ds.marketData.Providers() {
+		start := time.Now()
+		quote, err := p.HealthCheck()
+		ds.healthMonitor.Record(p.Name(), time.Since(start), quote, err)
+
+		if err != nil {
+			log.Printf("⚠️ 数据源 %s 健康探测失败: %v", p.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"stock-prediction-backend/internal/model"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	sinaQuoteURL = "http://hq.sinajs.cn"
+	sinaKLineURL = "https://quotes.sina.cn"
+)
+
+// SinaProvider 新浪财经行情数据源
+type SinaProvider struct {
+	httpClient *resty.Client
+	quoteURL   string
+	klineURL   string
+}
+
+// NewSinaProvider 创建新浪财经数据源
+func NewSinaProvider(httpClient *resty.Client, quoteURL, klineURL string) *SinaProvider {
+	return &SinaProvider{
+		httpClient: httpClient,
+		quoteURL:   strings.TrimRight(quoteURL, "/"),
+		klineURL:   strings.TrimRight(klineURL, "/"),
+	}
+}
+
+// Name 数据源名称
+func (p *SinaProvider) Name() string {
+	return "sina"
+}
+
+// HealthCheck 探测新浪财经报价接口
+func (p *SinaProvider) HealthCheck() (*model.StockData, error) {
+	return p.FetchQuote(healthProbeInstrument)
+}
+
+// FetchQuote 获取新浪财经实时报价
+func (p *SinaProvider) FetchQuote(instrument *model.Instrument) (*model.StockData, error) {
+	sinaSymbol, err := providerSymbol(instrument, p.Name(), marketPrefixedSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/list=%s", p.quoteURL, sinaSymbol)
+
+	// 新浪接口要求携带 Referer，否则返回 403
+	resp, err := p.httpClient.R().
+		SetHeader("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36").
+		SetHeader("Referer", "https://finance.sina.com.cn").
+		Get(url)
+
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("HTTP错误: %d", resp.StatusCode())
+	}
+
+	// 新浪个股成交量单位为股，指数成交量单位为手
+	lotMultiplier := int64(1)
+	if instrument.Type == InstrumentTypeIndex {
+		lotMultiplier = sharesPerHand
+	}
+
+	data, err := parseSinaResponse(resp.String(), sinaSymbol, lotMultiplier)
+	if err != nil {
+		return nil, fmt.Errorf("解析数据失败: %v", err)
+	}
+
+	return data, nil
+}
+
+// parseSinaResponse 解析新浪财经返回的数据
+func parseSinaResponse(body, symbol string, lotMultiplier int64) (*model.StockData, error) {
+	// 格式: var hq_str_sh000001="上证指数,今开,昨收,当前价,最高,最低,买一,卖一,成交量,成交额,...,日期,时间,00,";
+	start := fmt.Sprintf("hq_str_%s=\"", symbol)
+	startIdx := strings.Index(body, start)
+	if startIdx == -1 {
+		return nil, fmt.Errorf("未找到数据")
+	}
+
+	startIdx += len(start)
+	endIdx := strings.Index(body[startIdx:], "\"")
+	if endIdx == -1 {
+		return nil, fmt.Errorf("数据格式错误")
+	}
+
+	fields := strings.Split(body[startIdx:startIdx+endIdx], ",")
+
+	// 新浪财经数据字段说明:
+	// 0: 名称  1: 今开  2: 昨收  3: 当前价  4: 最高  5: 最低
+	// 8: 成交量  9: 成交额  30: 日期  31: 时间
+	if len(fields) < 32 {
+		return nil, fmt.Errorf("数据字段不足")
+	}
+
+	currentPrice, err := parseFloat(fields[3])
+	if err != nil {
+		return nil, fmt.Errorf("解析当前价失败: %v", err)
+	}
+
+	yesterdayClose, err := parseFloat(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("解析昨收价失败: %v", err)
+	}
+
+	todayOpen, err := parseFloat(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("解析开盘价失败: %v", err)
+	}
+
+	todayHigh, err := parseFloat(fields[4])
+	if err != nil {
+		todayHigh = currentPrice
+	}
+
+	todayLow, err := parseFloat(fields[5])
+	if err != nil {
+		todayLow = currentPrice
+	}
+
+	volume, err := parseInt64(fields[8])
+	if err != nil {
+		volume = 0
+	}
+
+	volume *= lotMultiplier
+
+	quoteTime, err := time.ParseInLocation("2006-01-02 15:04:05", fields[30]+" "+fields[31], exchangeLocation)
+	if err != nil {
+		return nil, fmt.Errorf("解析行情时间失败: %v", err)
+	}
+
+	return &model.StockData{
+		Date:           quoteTime,
+		Open:           todayOpen,
+		High:           todayHigh,
+		Low:            todayLow,
+		Close:          currentPrice,
+		YesterdayClose: yesterdayClose,
+		Volume:         volume,
+	}, nil
+}
+
+// sinaKLineBar 新浪财经K线数据项
+type sinaKLineBar struct {
+	Day    string `json:"day"`
+	Open   string `json:"open"`
+	High   string `json:"high"`
+	Low    string `json:"low"`
+	Close  string `json:"close"`
+	Volume string `json:"volume"`
+}
+
+// FetchDailyBars 获取新浪财经日K线数据
+func (p *SinaProvider) FetchDailyBars(instrument *model.Instrument, count int) ([]model.StockData, error) {
+	sinaSymbol, err := providerSymbol(instrument, p.Name(), marketPrefixedSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	// scale=240 表示日线（240分钟），多取一根K线只用于提供第一根K线的昨收价
+	url := fmt.Sprintf("%s/cn/api/json_v2.php/CN_MarketDataService.getKLineData?symbol=%s&scale=240&ma=no&datalen=%d",
+		p.klineURL, sinaSymbol, count+1)
+
+	resp, err := p.httpClient.R().
+		SetHeader("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36").
+		SetHeader("Referer", "https://finance.sina.com.cn").
+		Get(url)
+
+	if err != nil {
+		return nil, fmt.Errorf("请求K线失败: %v", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("HTTP错误: %d", resp.StatusCode())
+	}
+
+	data, err := parseSinaKLineResponse(resp.Body())
+	if err != nil {
+		return nil, fmt.Errorf("解析K线数据失败: %v", err)
+	}
+
+	return data, nil
+}
+
+// parseSinaKLineResponse 解析新浪财经日K线JSON
+// 接口不返回昨收价，第一根有效K线只用于提供下一根的昨收价，不出现在结果中
+func parseSinaKLineResponse(body []byte) ([]model.StockData, error) {
+	var bars []sinaKLineBar
+	if err := json.Unmarshal(body, &bars); err != nil {
+		return nil, fmt.Errorf("JSON格式错误: %v", err)
+	}
+
+	var data []model.StockData
+	var prevClose float64
+	for _, bar := range bars {
+		// 日期按UTC零点保存，与数据库中的日期约定一致
+		date, err := time.Parse("2006-01-02", bar.Day)
+		if err != nil {
+			continue
+		}
+
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			continue
+		}
+
+		open, err1 := parseFloat(bar.Open)
+		closePrice, err2 := parseFloat(bar.Close)
+		high, err3 := parseFloat(bar.High)
+		low, err4 := parseFloat(bar.Low)
+		volume, err5 := parseFloat(bar.Volume)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil {
+			continue
+		}
+
+		// 成交量为0表示停牌或休市，不是有效交易日
+		if volume <= 0 || closePrice <= 0 {
+			continue
+		}
+
+		if prevClose > 0 {
+			data = append(data, model.StockData{
+				Date:           date,
+				Open:           open,
+				High:           high,
+				Low:            low,
+				Close:          closePrice,
+				YesterdayClose: prevClose,
+				Volume:         int64(volume), // 新浪K线成交量单位为股
+			})
+		}
+		prevClose = closePrice
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("K线数据为空")
+	}
+
+	return data, nil
+}
+
+// FetchMinuteBars 获取新浪财经分钟K线数据
+func (p *SinaProvider) FetchMinuteBars(instrument *model.Instrument, interval, count int) ([]model.StockData, error) {
+	sinaSymbol, err := providerSymbol(instrument, p.Name(), marketPrefixedSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	// scale 为K线周期（分钟）
+	url := fmt.Sprintf("%s/cn/api/json_v2.php/CN_MarketDataService.getKLineData?symbol=%s&scale=%d&ma=no&datalen=%d",
+		p.klineURL, sinaSymbol, interval, count)
+
+	resp, err := p.httpClient.R().
+		SetHeader("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36").
+		SetHeader("Referer", "https://finance.sina.com.cn").
+		Get(url)
+
+	if err != nil {
+		return nil, fmt.Errorf("请求分钟K线失败: %v", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("HTTP错误: %d", resp.StatusCode())
+	}
+
+	data, err := parseSinaMinuteResponse(resp.Body())
+	if err != nil {
+		return nil, fmt.Errorf("解析分钟K线数据失败: %v", err)
+	}
+
+	return data, nil
+}
+
+// parseSinaMinuteResponse 解析新浪财经分钟K线JSON，day 字段格式为 "2024-01-02 10:35:00"
+func parseSinaMinuteResponse(body []byte) ([]model.StockData, error) {
+	var bars []sinaKLineBar
+	if err := json.Unmarshal(body, &bars); err != nil {
+		return nil, fmt.Errorf("JSON格式错误: %v", err)
+	}
+
+	var data []model.StockData
+	for _, bar := range bars {
+		barTime, err := time.ParseInLocation("2006-01-02 15:04:05", bar.Day, exchangeLocation)
+		if err != nil {
+			continue
+		}
+
+		open, err1 := parseFloat(bar.Open)
+		closePrice, err2 := parseFloat(bar.Close)
+		high, err3 := parseFloat(bar.High)
+		low, err4 := parseFloat(bar.Low)
+		volume, err5 := parseFloat(bar.Volume)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil || closePrice <= 0 {
+			continue
+		}
+
+		data = append(data, model.StockData{
+			Date:   barTime,
+			Open:   open,
+			High:   high,
+			Low:    low,
+			Close:  closePrice,
+			Volume: int64(volume), // 新浪K线成交量单位为股
+		})
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("K线数据为空")
+	}
+
+	return data, nil
+}
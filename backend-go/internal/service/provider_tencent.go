@@ -0,0 +1,483 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"stock-prediction-backend/internal/model"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	tencentQuoteURL  = "http://sqt.gtimg.cn"
+	tencentKLineURL  = "https://web.ifzq.gtimg.cn"
+	tencentSearchURL = "https://smartbox.gtimg.cn"
+)
+
+// TencentProvider 腾讯财经行情数据源
+type TencentProvider struct {
+	httpClient *resty.Client
+	quoteURL   string
+	klineURL   string
+	searchURL  string
+}
+
+// NewTencentProvider 创建腾讯财经数据源
+func NewTencentProvider(httpClient *resty.Client, quoteURL, klineURL, searchURL string) *TencentProvider {
+	return &TencentProvider{
+		httpClient: httpClient,
+		quoteURL:   strings.TrimRight(quoteURL, "/"),
+		klineURL:   strings.TrimRight(klineURL, "/"),
+		searchURL:  strings.TrimRight(searchURL, "/"),
+	}
+}
+
+// Name 数据源名称
+func (p *TencentProvider) Name() string {
+	return "tencent"
+}
+
+// HealthCheck 探测腾讯财经报价接口
+func (p *TencentProvider) HealthCheck() (*model.StockData, error) {
+	return p.FetchQuote(healthProbeInstrument)
+}
+
+// FetchQuote 获取腾讯财经实时报价
+func (p *TencentProvider) FetchQuote(instrument *model.Instrument) (*model.StockData, error) {
+	tencentSymbol, err := providerSymbol(instrument, p.Name(), marketPrefixedSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	// 腾讯财经实时数据API
+	url := fmt.Sprintf("%s/q=%s", p.quoteURL, tencentSymbol)
+
+	resp, err := p.httpClient.R().
+		SetHeader("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36").
+		SetHeader("Referer", "http://gu.qq.com").
+		Get(url)
+
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("HTTP错误: %d", resp.StatusCode())
+	}
+
+	// 解析腾讯财经返回的数据格式
+	// 格式: v_sh000001="1~上证指数~000001~3000.00~2990.00~3010.00~1000000~...";
+	data, err := parseTencentResponse(resp.String(), tencentSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("解析数据失败: %v", err)
+	}
+
+	return data, nil
+}
+
+// FetchDailyBars 获取腾讯财经日K线数据
+func (p *TencentProvider) FetchDailyBars(instrument *model.Instrument, count int) ([]model.StockData, error) {
+	tencentSymbol, err := providerSymbol(instrument, p.Name(), marketPrefixedSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	// 腾讯财经日K线接口（前复权）
+	// 格式: https://web.ifzq.gtimg.cn/appstock/app/fqkline/get?param=sh000001,day,,,320,qfq
+	// 多取一根K线，只用于提供第一根K线的昨收价
+	url := fmt.Sprintf("%s/appstock/app/fqkline/get?param=%s,day,,,%d,qfq", p.klineURL, tencentSymbol, count+1)
+
+	resp, err := p.httpClient.R().
+		SetHeader("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36").
+		SetHeader("Referer", "http://gu.qq.com").
+		Get(url)
+
+	if err != nil {
+		return nil, fmt.Errorf("请求K线失败: %v", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("HTTP错误: %d", resp.StatusCode())
+	}
+
+	data, err := parseTencentKLineResponse(resp.Body(), tencentSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("解析K线数据失败: %v", err)
+	}
+
+	log.Printf("腾讯财经日K线 %s: %d 条", tencentSymbol, len(data))
+	return data, nil
+}
+
+// FetchMinuteBars 获取腾讯财经分钟K线数据
+func (p *TencentProvider) FetchMinuteBars(instrument *model.Instrument, interval, count int) ([]model.StockData, error) {
+	tencentSymbol, err := providerSymbol(instrument, p.Name(), marketPrefixedSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	// 腾讯财经分钟K线接口（不复权）
+	// 格式: https://web.ifzq.gtimg.cn/appstock/app/kline/mkline?param=sh000001,m5,,320
+	url := fmt.Sprintf("%s/appstock/app/kline/mkline?param=%s,m%d,,%d", p.klineURL, tencentSymbol, interval, count)
+
+	resp, err := p.httpClient.R().
+		SetHeader("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36").
+		SetHeader("Referer", "http://gu.qq.com").
+		Get(url)
+
+	if err != nil {
+		return nil, fmt.Errorf("请求分钟K线失败: %v", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("HTTP错误: %d", resp.StatusCode())
+	}
+
+	data, err := parseTencentMinuteResponse(resp.Body(), tencentSymbol, interval)
+	if err != nil {
+		return nil, fmt.Errorf("解析分钟K线数据失败: %v", err)
+	}
+
+	return data, nil
+}
+
+// parseTencentMinuteResponse 解析腾讯财经分钟K线JSON
+func parseTencentMinuteResponse(body []byte, symbol string, interval int) ([]model.StockData, error) {
+	var resp tencentKLineResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("JSON格式错误: %v", err)
+	}
+
+	if resp.Code != 0 {
+		return nil, fmt.Errorf("接口返回错误: code=%d, msg=%s", resp.Code, resp.Msg)
+	}
+
+	symbolData, exists := resp.Data[symbol]
+	if !exists {
+		return nil, fmt.Errorf("未找到数据")
+	}
+
+	raw, exists := symbolData[fmt.Sprintf("m%d", interval)]
+	if !exists {
+		return nil, fmt.Errorf("未找到%d分钟K线数据", interval)
+	}
+
+	// 每行格式: [时间(yyyyMMddHHmm), 开盘, 收盘, 最高, 最低, 成交量(手), ...]
+	var rows [][]interface{}
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, fmt.Errorf("K线格式错误: %v", err)
+	}
+
+	var data []model.StockData
+	for _, row := range rows {
+		if len(row) < 6 {
+			continue
+		}
+
+		fields := make([]string, 6)
+		for i := 0; i < 6; i++ {
+			str, ok := row[i].(string)
+			if !ok {
+				break
+			}
+			fields[i] = str
+		}
+
+		barTime, err := time.ParseInLocation("200601021504", fields[0], exchangeLocation)
+		if err != nil {
+			continue
+		}
+
+		open, err1 := parseFloat(fields[1])
+		closePrice, err2 := parseFloat(fields[2])
+		high, err3 := parseFloat(fields[3])
+		low, err4 := parseFloat(fields[4])
+		volume, err5 := parseFloat(fields[5])
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil || closePrice <= 0 {
+			continue
+		}
+
+		data = append(data, model.StockData{
+			Date:   barTime,
+			Open:   open,
+			High:   high,
+			Low:    low,
+			Close:  closePrice,
+			Volume: int64(volume) * sharesPerHand, // 腾讯返回的是手数，需要转换为股数
+		})
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("K线数据为空")
+	}
+
+	return data, nil
+}
+
+// tencentKLineResponse 腾讯财经K线接口响应结构
+type tencentKLineResponse struct {
+	Code int                                   `json:"code"`
+	Msg  string                                `json:"msg"`
+	Data map[string]map[string]json.RawMessage `json:"data"`
+}
+
+// parseTencentKLineResponse 解析腾讯财经日K线JSON
+// 接口不返回昨收价，第一根有效K线只用于提供下一根的昨收价，不出现在结果中
+func parseTencentKLineResponse(body []byte, symbol string) ([]model.StockData, error) {
+	var resp tencentKLineResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("JSON格式错误: %v", err)
+	}
+
+	if resp.Code != 0 {
+		return nil, fmt.Errorf("接口返回错误: code=%d, msg=%s", resp.Code, resp.Msg)
+	}
+
+	symbolData, exists := resp.Data[symbol]
+	if !exists {
+		return nil, fmt.Errorf("未找到数据")
+	}
+
+	// 个股返回前复权序列 qfqday，指数返回 day
+	raw, exists := symbolData["qfqday"]
+	if !exists {
+		raw, exists = symbolData["day"]
+	}
+	if !exists {
+		return nil, fmt.Errorf("未找到日K线数据")
+	}
+
+	// 每行格式: [日期, 开盘, 收盘, 最高, 最低, 成交量(手), ...]，行尾可能附带除权信息对象
+	var rows [][]interface{}
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, fmt.Errorf("K线格式错误: %v", err)
+	}
+
+	var data []model.StockData
+	var prevClose float64
+	for _, row := range rows {
+		if len(row) < 6 {
+			continue
+		}
+
+		fields := make([]string, 6)
+		for i := 0; i < 6; i++ {
+			str, ok := row[i].(string)
+			if !ok {
+				break
+			}
+			fields[i] = str
+		}
+
+		// 日期按UTC零点保存，与数据库中的日期约定一致
+		date, err := time.Parse("2006-01-02", fields[0])
+		if err != nil {
+			continue
+		}
+
+		// 跳过周末等非交易日
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			continue
+		}
+
+		open, err1 := parseFloat(fields[1])
+		closePrice, err2 := parseFloat(fields[2])
+		high, err3 := parseFloat(fields[3])
+		low, err4 := parseFloat(fields[4])
+		volume, err5 := parseFloat(fields[5])
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil {
+			continue
+		}
+
+		// 成交量为0表示停牌或休市，不是有效交易日
+		if volume <= 0 || closePrice <= 0 {
+			continue
+		}
+
+		if prevClose > 0 {
+			data = append(data, model.StockData{
+				Date:           date,
+				Open:           open,
+				High:           high,
+				Low:            low,
+				Close:          closePrice,
+				YesterdayClose: prevClose,
+				Volume:         int64(volume) * sharesPerHand, // 腾讯返回的是手数，需要转换为股数
+			})
+		}
+		prevClose = closePrice
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("K线数据为空")
+	}
+
+	return data, nil
+}
+
+// parseTencentResponse 解析腾讯财经返回的数据
+func parseTencentResponse(body, symbol string) (*model.StockData, error) {
+	// 查找数据行
+	// 格式: v_sh000001="数据内容";
+	start := fmt.Sprintf("v_%s=\"", symbol)
+	startIdx := strings.Index(body, start)
+	if startIdx == -1 {
+		return nil, fmt.Errorf("未找到数据")
+	}
+
+	startIdx += len(start)
+	endIdx := strings.Index(body[startIdx:], "\"")
+	if endIdx == -1 {
+		return nil, fmt.Errorf("数据格式错误")
+	}
+
+	dataStr := body[startIdx : startIdx+endIdx]
+	fields := strings.Split(dataStr, "~")
+
+	// 腾讯财经数据字段说明:
+	// 0: 未知  1: 名称  2: 代码  3: 当前价  4: 昨收  5: 今开
+	// 6: 成交量  7: 外盘  8: 内盘  ...  30: 行情时间
+	if len(fields) < 37 {
+		return nil, fmt.Errorf("数据字段不足")
+	}
+
+	// 解析价格数据
+	currentPrice, err := parseFloat(fields[3])
+	if err != nil {
+		return nil, fmt.Errorf("解析当前价失败: %v", err)
+	}
+
+	yesterdayClose, err := parseFloat(fields[4])
+	if err != nil {
+		return nil, fmt.Errorf("解析昨收价失败: %v", err)
+	}
+
+	todayOpen, err := parseFloat(fields[5])
+	if err != nil {
+		return nil, fmt.Errorf("解析开盘价失败: %v", err)
+	}
+
+	todayHigh, err := parseFloat(fields[33]) // 最高价
+	if err != nil {
+		todayHigh = currentPrice
+	}
+
+	todayLow, err := parseFloat(fields[34]) // 最低价
+	if err != nil {
+		todayLow = currentPrice
+	}
+
+	volume, err := parseInt64(fields[36]) // 成交量
+	if err != nil {
+		volume = 0
+	}
+
+	// 交易所行情时间，格式: 20240102150003
+	quoteTime, err := time.ParseInLocation("20060102150405", fields[30], exchangeLocation)
+	if err != nil {
+		return nil, fmt.Errorf("解析行情时间失败: %v", err)
+	}
+
+	// 创建股票数据
+	stockData := &model.StockData{
+		Date:           quoteTime,
+		Open:           todayOpen,
+		High:           todayHigh,
+		Low:            todayLow,
+		Close:          currentPrice,
+		YesterdayClose: yesterdayClose,         // 保存昨收价
+		Volume:         volume * sharesPerHand, // 腾讯返回的是手数，需要转换为股数
+	}
+
+	log.Printf("腾讯财经数据 %s: 当前价=%.2f, 昨收=%.2f, 今开=%.2f", symbol, currentPrice, yesterdayClose, todayOpen)
+	return stockData, nil
+}
+
+// SearchSecurities 通过腾讯财经智能提示接口按代码或拼音搜索证券
+func (p *TencentProvider) SearchSecurities(query string) ([]model.SecurityMatch, error) {
+	searchURL := fmt.Sprintf("%s/s3/?v=2&t=all&q=%s", p.searchURL, url.QueryEscape(query))
+
+	resp, err := p.httpClient.R().
+		SetHeader("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36").
+		SetHeader("Referer", "http://gu.qq.com").
+		Get(searchURL)
+
+	if err != nil {
+		return nil, fmt.Errorf("请求搜索接口失败: %v", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("HTTP错误: %d", resp.StatusCode())
+	}
+
+	return parseTencentSearchResponse(resp.String())
+}
+
+// parseTencentSearchResponse 解析腾讯财经智能提示接口返回的数据
+func parseTencentSearchResponse(body string) ([]model.SecurityMatch, error) {
+	// 格式: v_hint="sz~000001~\u5e73\u5b89\u94f6\u884c~payh~GP-A^sh~000001~\u4e0a\u8bc1\u6307\u6570~szzs~ZS";
+	start := "v_hint=\""
+	startIdx := strings.Index(body, start)
+	if startIdx == -1 {
+		return nil, fmt.Errorf("未找到数据")
+	}
+
+	startIdx += len(start)
+	endIdx := strings.Index(body[startIdx:], "\"")
+	if endIdx == -1 {
+		return nil, fmt.Errorf("数据格式错误")
+	}
+
+	hint := body[startIdx : startIdx+endIdx]
+	if hint == "" || hint == "N" {
+		return nil, nil
+	}
+
+	var matches []model.SecurityMatch
+	for _, item := range strings.Split(hint, "^") {
+		// 字段: 0: 市场  1: 代码  2: 名称(unicode转义)  3: 拼音首字母  4: 类型
+		fields := strings.Split(item, "~")
+		if len(fields) < 5 {
+			continue
+		}
+
+		// 只保留沪深北A股市场，跳过港股、美股等
+		symbol, market, err := unifiedSymbol(fields[0], fields[1])
+		if err != nil {
+			continue
+		}
+
+		var instrumentType string
+		switch {
+		case strings.HasPrefix(fields[4], "GP-A"):
+			instrumentType = InstrumentTypeStock
+		case fields[4] == "ZS":
+			instrumentType = InstrumentTypeIndex
+		case strings.Contains(fields[4], "ETF"):
+			instrumentType = InstrumentTypeETF
+		default:
+			continue
+		}
+
+		name, err := strconv.Unquote(`"` + fields[2] + `"`)
+		if err != nil {
+			name = fields[2]
+		}
+
+		matches = append(matches, model.SecurityMatch{
+			Code:   strings.ToLower(fields[0] + fields[1]),
+			Name:   name,
+			Symbol: symbol,
+			Market: market,
+			Type:   instrumentType,
+			Pinyin: strings.ToLower(fields[3]),
+		})
+	}
+
+	return matches, nil
+}
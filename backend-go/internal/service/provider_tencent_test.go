@@ -0,0 +1,111 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseTencentKLineResponse(t *testing.T) {
+	type bar struct {
+		date           string
+		open, close    float64
+		high, low      float64
+		yesterdayClose float64
+		volume         int64
+	}
+
+	tests := []struct {
+		name    string
+		fixture string
+		symbol  string
+		want    []bar
+		wantErr bool
+	}{
+		{
+			name:    "指数日K线，第一根只提供昨收价",
+			fixture: "tencent_kline_index.json",
+			symbol:  "sh000001",
+			want: []bar{
+				{"2024-03-04", 3035.20, 3047.79, 3049.64, 3025.11, 3027.02, 42611034900},
+				{"2024-03-05", 3036.12, 3047.79, 3048.99, 3021.93, 3047.79, 44101726600},
+				{"2024-03-06", 3043.11, 3039.93, 3055.81, 3032.30, 3047.79, 45211201700},
+			},
+		},
+		{
+			name:    "个股前复权K线，跳过停牌日、周末和除权信息对象",
+			fixture: "tencent_kline_stock_qfq.json",
+			symbol:  "sz000001",
+			want: []bar{
+				{"2024-06-12", 10.10, 10.23, 10.25, 10.06, 10.11, 134201800},
+				{"2024-06-14", 9.33, 9.41, 9.45, 9.30, 10.23, 226584100},
+			},
+		},
+		{
+			name:    "只有一根K线时没有可用数据",
+			fixture: "tencent_kline_single.json",
+			symbol:  "sh000300",
+			wantErr: true,
+		},
+		{
+			name:    "接口返回错误码",
+			fixture: "tencent_kline_error.json",
+			symbol:  "sh000001",
+			wantErr: true,
+		},
+		{
+			name:    "代码不在返回数据中",
+			fixture: "tencent_kline_index.json",
+			symbol:  "sz399001",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatalf("读取测试数据失败: %v", err)
+			}
+
+			data, err := parseTencentKLineResponse(body, tt.symbol)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("期望返回错误，实际得到 %d 根K线", len(data))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("解析失败: %v", err)
+			}
+
+			if len(data) != len(tt.want) {
+				t.Fatalf("K线数量 = %d，期望 %d", len(data), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := data[i]
+				wantDate, _ := time.Parse("2006-01-02", want.date)
+				if !got.Date.Equal(wantDate) {
+					t.Errorf("第%d根日期 = %v，期望 %v", i, got.Date, wantDate)
+				}
+				if !floatEqual(got.Open, want.open) || !floatEqual(got.Close, want.close) ||
+					!floatEqual(got.High, want.high) || !floatEqual(got.Low, want.low) {
+					t.Errorf("第%d根OHLC = %v/%v/%v/%v，期望 %v/%v/%v/%v", i,
+						got.Open, got.High, got.Low, got.Close, want.open, want.high, want.low, want.close)
+				}
+				if !floatEqual(got.YesterdayClose, want.yesterdayClose) {
+					t.Errorf("第%d根昨收 = %v，期望 %v", i, got.YesterdayClose, want.yesterdayClose)
+				}
+				if got.Volume != want.volume {
+					t.Errorf("第%d根成交量 = %d，期望 %d", i, got.Volume, want.volume)
+				}
+			}
+		})
+	}
+}
+
+func floatEqual(a, b float64) bool {
+	const epsilon = 1e-6
+	return a-b < epsilon && b-a < epsilon
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"stock-prediction-backend/internal/model"
+	"strings"
+	"sync"
+	"time"
+)
+
+// quoteSubscriptionBuffer 每个订阅者的推送缓冲，消费过慢时丢弃新的报价
+const quoteSubscriptionBuffer = 32
+
+// QuoteSubscription 实时报价订阅
+type QuoteSubscription struct {
+	codes   map[string]bool
+	updates chan model.LiveQuote
+}
+
+// Updates 报价推送通道
+func (s *QuoteSubscription) Updates() <-chan model.LiveQuote {
+	return s.updates
+}
+
+// QuoteHub 实时报价分发中心：后台只有一个轮询任务拉取订阅标的的报价，再分发给所有订阅者
+type QuoteHub struct {
+	mu          sync.RWMutex
+	subscribers map[*QuoteSubscription]struct{}
+}
+
+// NewQuoteHub 创建报价分发中心
+func NewQuoteHub() *QuoteHub {
+	return &QuoteHub{
+		subscribers: make(map[*QuoteSubscription]struct{}),
+	}
+}
+
+// Subscribe 订阅指定标的的报价
+func (h *QuoteHub) Subscribe(codes []string) *QuoteSubscription {
+	sub := &QuoteSubscription{
+		codes:   make(map[string]bool),
+		updates: make(chan model.LiveQuote, quoteSubscriptionBuffer),
+	}
+	for _, code := range codes {
+		sub.codes[code] = true
+	}
+
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+// Unsubscribe 取消订阅
+func (h *QuoteHub) Unsubscribe(sub *QuoteSubscription) {
+	h.mu.Lock()
+	delete(h.subscribers, sub)
+	h.mu.Unlock()
+}
+
+// SubscriberCount 当前订阅者数量
+func (h *QuoteHub) SubscriberCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers)
+}
+
+// subscribedCodes 所有订阅者关注的标的（去重）
+func (h *QuoteHub) subscribedCodes() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var codes []string
+	for sub := range h.subscribers {
+		for code := range sub.codes {
+			if !seen[code] {
+				seen[code] = true
+				codes = append(codes, code)
+			}
+		}
+	}
+	return codes
+}
+
+// Publish 将报价推送给订阅了该标的的订阅者
+func (h *QuoteHub) Publish(quote model.LiveQuote) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subscribers {
+		if !sub.codes[quote.Code] {
+			continue
+		}
+		select {
+		case sub.updates <- quote:
+		default:
+			// 订阅者消费过慢，丢弃本次报价，下一次轮询会推送更新的数据
+		}
+	}
+}
+
+// newLiveQuote 根据实时行情生成推送报价
+func (ds *DataService) newLiveQuote(instrument model.Instrument, data *model.StockData) model.LiveQuote {
+	now := time.Now()
+	change := data.Close - data.YesterdayClose
+	changePercent := 0.0
+	if data.YesterdayClose > 0 {
+		changePercent = change / data.YesterdayClose * 100
+	}
+
+	return model.LiveQuote{
+		Code:           instrument.Code,
+		Name:           instrument.Name,
+		Price:          data.Close,
+		Open:           data.Open,
+		High:           data.High,
+		Low:            data.Low,
+		YesterdayClose: data.YesterdayClose,
+		Change:         roundPrice(change, instrument),
+		ChangePercent:  math.Round(changePercent*100) / 100,
+		Volume:         data.Volume,
+		Timestamp:      data.Date.UTC().Format(time.RFC3339),
+		MarketState:    ds.calendar.MarketState(now),
+		Stale:          ds.marketData.QuoteStale(data, now),
+	}
+}
+
+// SubscribeQuotes 订阅实时报价，codes 为空时订阅所有启用的标的
+// 返回订阅和各标的当前报价快照（用于连接建立后立即推送，交易时段内报价缓存由轮询任务保持最新）
+func (ds *DataService) SubscribeQuotes(codes []string) (*QuoteSubscription, []model.LiveQuote, error) {
+	var instruments []model.Instrument
+	if len(codes) == 0 {
+		instruments = ds.registry.List(false)
+	} else {
+		for _, code := range codes {
+			instrument, exists := ds.registry.Get(strings.TrimSpace(code))
+			if !exists {
+				return nil, nil, fmt.Errorf("指数不存在: %s", code)
+			}
+			instruments = append(instruments, instrument)
+		}
+	}
+
+	subscribed := make([]string, 0, len(instruments))
+	snapshot := make([]model.LiveQuote, 0, len(instruments))
+	for _, instrument := range instruments {
+		subscribed = append(subscribed, instrument.Code)
+
+		data, err := ds.GetCurrentStockData(instrument.Symbol)
+		if err != nil {
+			log.Printf("⚠️ 获取报价快照失败 %s: %v", instrument.Code, err)
+			continue
+		}
+		snapshot = append(snapshot, ds.newLiveQuote(instrument, data))
+	}
+
+	return ds.quoteHub.Subscribe(subscribed), snapshot, nil
+}
+
+// UnsubscribeQuotes 取消实时报价订阅
+func (ds *DataService) UnsubscribeQuotes(sub *QuoteSubscription) {
+	ds.quoteHub.Unsubscribe(sub)
+}
+
+// startQuotePoller 启动实时报价轮询：只在交易时段内按固定间隔拉取被订阅标的的报价，
+// 休市期间休眠到下一个交易时段开始
+func (ds *DataService) startQuotePoller(interval time.Duration) {
+	if interval <= 0 {
+		log.Printf("⚠️ 实时报价推送已禁用")
+		return
+	}
+
+	log.Printf("📶 实时报价轮询已启动，交易时段内每 %v 拉取一次", interval)
+
+	wasInSession := false
+	for {
+		now := time.Now()
+		inSession := ds.calendar.InSession(now)
+
+		// 时段结束后再拉取一次，推送收盘价
+		if inSession || wasInSession {
+			ds.pollQuotes()
+		}
+		wasInSession = inSession
+
+		wait := interval
+		if !inSession {
+			wait = time.Until(ds.calendar.NextSessionStart(now))
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ds.probeStop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// pollQuotes 拉取所有被订阅标的的报价，更新报价缓存并推送给订阅者
+func (ds *DataService) pollQuotes() {
+	for _, code := range ds.quoteHub.subscribedCodes() {
+		instrument, exists := ds.registry.Get(code)
+		if !exists {
+			continue
+		}
+
+		data, err := ds.marketData.FetchQuote(&instrument)
+		if err != nil {
+			log.Printf("⚠️ 实时报价拉取失败 %s: %v", code, err)
+			continue
+		}
+
+		ds.setCache(fmt.Sprintf("stock_data_%s", instrument.Symbol), data, ds.quoteCacheDuration(time.Now()))
+		ds.quoteHub.Publish(ds.newLiveQuote(instrument, data))
+	}
+}
@@ -0,0 +1,394 @@
+package service
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"stock-prediction-backend/internal/database"
+	"stock-prediction-backend/internal/model"
+	"strings"
+	"sync"
+)
+
+// 标的类型
+const (
+	InstrumentTypeIndex = "index"
+	InstrumentTypeStock = "stock"
+	InstrumentTypeETF   = "etf"
+)
+
+// 个股和ETF所属板块，决定涨跌幅限制
+const (
+	BoardMain    = "main"    // 主板 10%
+	BoardChiNext = "chinext" // 创业板 20%
+	BoardSTAR    = "star"    // 科创板 20%
+	BoardBSE     = "bse"     // 北交所 30%
+)
+
+// defaultInstrumentsJSON 内置的默认标的注册表
+//
+//go:embed data/instruments.json
+var defaultInstrumentsJSON []byte
+
+// InstrumentRegistry 标的注册表
+// 启动时从数据库加载；数据库为空时从配置文件（或内置默认值）加载并写入数据库
+type InstrumentRegistry struct {
+	mu          sync.RWMutex
+	instruments map[string]*model.Instrument
+	order       []string // 保持注册顺序
+	db          *database.DatabaseService
+}
+
+// NewInstrumentRegistry 创建标的注册表
+func NewInstrumentRegistry(path string, db *database.DatabaseService) (*InstrumentRegistry, error) {
+	r := &InstrumentRegistry{
+		instruments: make(map[string]*model.Instrument),
+		db:          db,
+	}
+
+	// 优先使用数据库中的注册表
+	if db != nil {
+		instruments, err := db.ListInstruments()
+		if err != nil {
+			log.Printf("⚠️ 从数据库加载标的注册表失败，使用配置文件: %v", err)
+		} else if len(instruments) > 0 {
+			for i := range instruments {
+				// 补全旧版本数据库中缺失的字段
+				if err := validateInstrument(&instruments[i]); err != nil {
+					log.Printf("⚠️ 标的配置无效 %s: %v", instruments[i].Code, err)
+				}
+				r.add(&instruments[i])
+			}
+			log.Printf("📋 从数据库加载标的注册表: %d 个标的", len(instruments))
+			return r, nil
+		}
+	}
+
+	instruments, err := loadInstrumentsFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range instruments {
+		if err := validateInstrument(&instruments[i]); err != nil {
+			return nil, err
+		}
+		r.add(&instruments[i])
+
+		// 首次启动时写入数据库，之后以数据库为准
+		if db != nil {
+			if err := db.SaveInstrument(&instruments[i]); err != nil {
+				log.Printf("⚠️ 写入标的注册表失败: %v", err)
+			}
+		}
+	}
+
+	log.Printf("📋 加载标的注册表: %d 个标的", len(instruments))
+	return r, nil
+}
+
+// loadInstrumentsFile 从JSON文件加载标的，路径为空或文件不存在时使用内置默认值
+func loadInstrumentsFile(path string) ([]model.Instrument, error) {
+	content := defaultInstrumentsJSON
+
+	if path != "" {
+		fileContent, err := os.ReadFile(path)
+		if err == nil {
+			content = fileContent
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("读取标的注册表文件失败: %v", err)
+		} else {
+			log.Printf("⚠️ 标的注册表文件不存在: %s，使用内置默认值", path)
+		}
+	}
+
+	var instruments []model.Instrument
+	if err := json.Unmarshal(content, &instruments); err != nil {
+		return nil, fmt.Errorf("解析标的注册表失败: %v", err)
+	}
+
+	return instruments, nil
+}
+
+// validateInstrument 校验并补全标的配置
+func validateInstrument(instrument *model.Instrument) error {
+	instrument.Code = strings.ToLower(strings.TrimSpace(instrument.Code))
+	instrument.Symbol = strings.ToUpper(strings.TrimSpace(instrument.Symbol))
+
+	if instrument.Code == "" || instrument.Name == "" || instrument.Symbol == "" {
+		return fmt.Errorf("标的配置不完整: code、name、symbol 均不能为空")
+	}
+
+	if _, err := marketPrefixedSymbol(instrument.Symbol); err != nil {
+		return err
+	}
+
+	// 未指定交易日历时按交易所推导
+	if instrument.Calendar == "" {
+		if strings.HasSuffix(instrument.Symbol, ".SZ") {
+			instrument.Calendar = "XSHE"
+		} else {
+			instrument.Calendar = "XSHG"
+		}
+	}
+
+	switch instrument.Type {
+	case "":
+		instrument.Type = InstrumentTypeIndex
+	case InstrumentTypeIndex, InstrumentTypeStock, InstrumentTypeETF:
+	default:
+		return fmt.Errorf("不支持的标的类型: %s", instrument.Type)
+	}
+
+	if instrument.Type != InstrumentTypeIndex && instrument.Board == "" {
+		instrument.Board = inferBoard(instrument.Symbol, instrument.Type)
+	}
+
+	// 名称以 ST 或 *ST 开头的个股适用5%涨跌幅限制，摘帽改名后重新保存时清除
+	instrument.IsST = instrument.Type == InstrumentTypeStock && isSTName(instrument.Name)
+
+	if instrument.LotSize <= 0 {
+		instrument.LotSize = 100
+	}
+
+	instrument.Pinyin = strings.ToLower(strings.TrimSpace(instrument.Pinyin))
+	return nil
+}
+
+// isSTName 证券简称是否带有风险警示标记（ST 或 *ST 前缀）
+func isSTName(name string) bool {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	return strings.HasPrefix(name, "ST") || strings.HasPrefix(name, "*ST")
+}
+
+// inferBoard 根据证券代码推导所属板块
+// ETF 按交易所代码段推导：588/589 开头为科创板ETF，159 开头为创业板ETF，与对应板块的涨跌幅限制一致
+func inferBoard(symbol, instrumentType string) string {
+	code := strings.Split(symbol, ".")[0]
+	if instrumentType == InstrumentTypeETF {
+		switch {
+		case strings.HasPrefix(code, "588") || strings.HasPrefix(code, "589"):
+			return BoardSTAR
+		case strings.HasPrefix(code, "159"):
+			return BoardChiNext
+		default:
+			return BoardMain
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(symbol, ".BJ"):
+		return BoardBSE
+	case strings.HasPrefix(code, "688") || strings.HasPrefix(code, "689"):
+		return BoardSTAR
+	case strings.HasPrefix(code, "300") || strings.HasPrefix(code, "301"):
+		return BoardChiNext
+	default:
+		return BoardMain
+	}
+}
+
+// priceLimitPercent 获取标的的涨跌幅限制（百分比），指数没有涨跌幅限制时返回0
+// 个股和ETF都按板块确定，ST 只适用于个股
+func priceLimitPercent(instrument model.Instrument) float64 {
+	switch instrument.Type {
+	case InstrumentTypeIndex:
+		return 0
+	case InstrumentTypeStock:
+		if instrument.IsST {
+			return 5
+		}
+	}
+
+	switch instrument.Board {
+	case BoardChiNext, BoardSTAR:
+		return 20
+	case BoardBSE:
+		return 30
+	default:
+		return 10
+	}
+}
+
+// priceDecimals 标的价格的小数位数：ETF 最小报价单位为 0.001 元，指数和个股为 0.01
+func priceDecimals(instrument model.Instrument) int {
+	if instrument.Type == InstrumentTypeETF {
+		return 3
+	}
+	return 2
+}
+
+// roundPrice 按标的最小报价单位取整价格
+func roundPrice(price float64, instrument model.Instrument) float64 {
+	scale := math.Pow10(priceDecimals(instrument))
+	return math.Round(price*scale) / scale
+}
+
+// instrumentTypeName 标的类型的中文名称，用于提示词
+func instrumentTypeName(instrumentType string) string {
+	switch instrumentType {
+	case InstrumentTypeStock:
+		return "A股个股"
+	case InstrumentTypeETF:
+		return "ETF基金"
+	default:
+		return "中国股票指数"
+	}
+}
+
+// add 添加标的到内存（调用方负责加锁）
+func (r *InstrumentRegistry) add(instrument *model.Instrument) {
+	if _, exists := r.instruments[instrument.Code]; !exists {
+		r.order = append(r.order, instrument.Code)
+	}
+	r.instruments[instrument.Code] = instrument
+}
+
+// Get 获取已启用的标的
+func (r *InstrumentRegistry) Get(code string) (model.Instrument, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	instrument, exists := r.instruments[code]
+	if !exists || !instrument.Enabled {
+		return model.Instrument{}, false
+	}
+	return *instrument, true
+}
+
+// FindBySymbol 根据统一代码查找已启用的标的
+func (r *InstrumentRegistry) FindBySymbol(symbol string) (model.Instrument, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, code := range r.order {
+		instrument := r.instruments[code]
+		if instrument.Symbol == symbol && instrument.Enabled {
+			return *instrument, true
+		}
+	}
+	return model.Instrument{}, false
+}
+
+// List 按注册顺序列出标的
+func (r *InstrumentRegistry) List(includeDisabled bool) []model.Instrument {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []model.Instrument
+	for _, code := range r.order {
+		instrument := r.instruments[code]
+		if instrument.Enabled || includeDisabled {
+			result = append(result, *instrument)
+		}
+	}
+	return result
+}
+
+// Upsert 新增或更新标的
+func (r *InstrumentRegistry) Upsert(instrument model.Instrument) (model.Instrument, error) {
+	if err := validateInstrument(&instrument); err != nil {
+		return model.Instrument{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.db != nil {
+		if err := r.db.SaveInstrument(&instrument); err != nil {
+			return model.Instrument{}, err
+		}
+	} else {
+		log.Printf("⚠️ 数据库不可用，标的 %s 仅在本次运行期间生效", instrument.Code)
+	}
+
+	r.add(&instrument)
+	return instrument, nil
+}
+
+// SetEnabled 启用或停用标的
+func (r *InstrumentRegistry) SetEnabled(code string, enabled bool) (model.Instrument, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	instrument, exists := r.instruments[code]
+	if !exists {
+		return model.Instrument{}, fmt.Errorf("标的不存在: %s", code)
+	}
+
+	if r.db != nil {
+		if err := r.db.SetInstrumentEnabled(code, enabled); err != nil {
+			return model.Instrument{}, err
+		}
+	}
+
+	instrument.Enabled = enabled
+	return *instrument, nil
+}
+
+// providerSymbol 获取标的在指定数据源的代码，未配置时使用 convert 从统一代码推导
+func providerSymbol(instrument *model.Instrument, provider string, convert func(string) (string, error)) (string, error) {
+	if symbol := instrument.ProviderSymbols[provider]; symbol != "" {
+		return symbol, nil
+	}
+	return convert(instrument.Symbol)
+}
+
+// newStockIndex 根据标的创建预测结果的基础信息
+func newStockIndex(instrument model.Instrument) model.StockIndex {
+	return model.StockIndex{
+		Code:       instrument.Code,
+		Name:       instrument.Name,
+		Symbol:     instrument.Symbol,
+		Market:     instrument.Market,
+		Type:       instrument.Type,
+		PriceLimit: priceLimitPercent(instrument),
+	}
+}
+
+// Search 在注册表中按代码、名称或拼音首字母搜索标的
+func (r *InstrumentRegistry) Search(query string) []model.SecurityMatch {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []model.SecurityMatch
+	for _, code := range r.order {
+		instrument := r.instruments[code]
+		if strings.Contains(instrument.Code, query) ||
+			strings.Contains(strings.ToLower(instrument.Symbol), query) ||
+			strings.Contains(instrument.Name, query) ||
+			(instrument.Pinyin != "" && strings.HasPrefix(instrument.Pinyin, query)) {
+			result = append(result, model.SecurityMatch{
+				Code:       instrument.Code,
+				Name:       instrument.Name,
+				Symbol:     instrument.Symbol,
+				Market:     instrument.Market,
+				Type:       instrument.Type,
+				Pinyin:     instrument.Pinyin,
+				Registered: true,
+				Enabled:    instrument.Enabled,
+			})
+		}
+	}
+	return result
+}
+
+// Lookup 获取标的（包含已停用的标的）
+func (r *InstrumentRegistry) Lookup(code string) (model.Instrument, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	instrument, exists := r.instruments[code]
+	if !exists {
+		return model.Instrument{}, false
+	}
+	return *instrument, true
+}
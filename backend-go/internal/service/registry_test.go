@@ -0,0 +1,201 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"stock-prediction-backend/internal/model"
+)
+
+func TestPriceLimitPercent(t *testing.T) {
+	tests := []struct {
+		name       string
+		instrument model.Instrument
+		wantBoard  string
+		wantLimit  float64
+	}{
+		{"指数", model.Instrument{Code: "sh000001", Name: "上证综指", Symbol: "000001.SS", Type: InstrumentTypeIndex}, "", 0},
+		{"主板个股", model.Instrument{Code: "sh600519", Name: "贵州茅台", Symbol: "600519.SS", Type: InstrumentTypeStock}, BoardMain, 10},
+		{"ST个股", model.Instrument{Code: "sz000004", Name: "*ST国华", Symbol: "000004.SZ", Type: InstrumentTypeStock}, BoardMain, 5},
+		{"ST前缀个股", model.Instrument{Code: "sz002024", Name: "ST易购", Symbol: "002024.SZ", Type: InstrumentTypeStock}, BoardMain, 5},
+		{"名称中间含ST的个股", model.Instrument{Code: "sh600000", Name: "浦发BEST", Symbol: "600000.SS", Type: InstrumentTypeStock}, BoardMain, 10},
+		{"摘帽后清除ST标记", model.Instrument{Code: "sz000004", Name: "国华网安", Symbol: "000004.SZ", Type: InstrumentTypeStock, IsST: true}, BoardMain, 10},
+		{"创业板个股", model.Instrument{Code: "sz300750", Name: "宁德时代", Symbol: "300750.SZ", Type: InstrumentTypeStock}, BoardChiNext, 20},
+		{"科创板个股", model.Instrument{Code: "sh688981", Name: "中芯国际", Symbol: "688981.SS", Type: InstrumentTypeStock}, BoardSTAR, 20},
+		{"北交所个股", model.Instrument{Code: "bj830799", Name: "艾融软件", Symbol: "830799.BJ", Type: InstrumentTypeStock}, BoardBSE, 30},
+		{"沪市ETF", model.Instrument{Code: "sh510300", Name: "沪深300ETF", Symbol: "510300.SS", Type: InstrumentTypeETF}, BoardMain, 10},
+		{"创业板ETF", model.Instrument{Code: "sz159915", Name: "创业板ETF", Symbol: "159915.SZ", Type: InstrumentTypeETF}, BoardChiNext, 20},
+		{"科创板ETF", model.Instrument{Code: "sh588000", Name: "科创50ETF", Symbol: "588000.SS", Type: InstrumentTypeETF}, BoardSTAR, 20},
+		{"ETF显式指定板块", model.Instrument{Code: "sz159919", Name: "沪深300ETF", Symbol: "159919.SZ", Type: InstrumentTypeETF, Board: BoardMain}, BoardMain, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instrument := tt.instrument
+			if err := validateInstrument(&instrument); err != nil {
+				t.Fatalf("校验标的失败: %v", err)
+			}
+			if instrument.Board != tt.wantBoard {
+				t.Errorf("板块 = %q，期望 %q", instrument.Board, tt.wantBoard)
+			}
+			if got := priceLimitPercent(instrument); got != tt.wantLimit {
+				t.Errorf("涨跌幅限制 = %v，期望 %v", got, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestRoundPrice(t *testing.T) {
+	index := model.Instrument{Code: "sh000001", Type: InstrumentTypeIndex}
+	stock := model.Instrument{Code: "sh600519", Type: InstrumentTypeStock}
+	etf := model.Instrument{Code: "sh510300", Type: InstrumentTypeETF}
+
+	tests := []struct {
+		name       string
+		instrument model.Instrument
+		price      float64
+		want       float64
+	}{
+		{"指数保留两位小数", index, 3012.3456, 3012.35},
+		{"个股保留两位小数", stock, 1688.004, 1688},
+		{"ETF保留三位小数", etf, 3.85349, 3.853},
+		{"ETF预测区间不丢失最小报价单位", etf, 0.8535, 0.854},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := roundPrice(tt.price, tt.instrument); got != tt.want {
+				t.Errorf("roundPrice(%v) = %v，期望 %v", tt.price, got, tt.want)
+			}
+		})
+	}
+
+	// 统计模型的区间按最小报价单位取整，ETF 的区间宽度小于 0.01 时仍可区分上下限
+	interval := normalInterval(etf, 0.853, 0.853, 0.004)
+	if interval.Lower80 >= interval.Upper80 || interval.Lower80 != 0.849 || interval.Upper80 != 0.857 {
+		t.Errorf("ETF 80%%区间 = [%v, %v]，期望 [0.849, 0.857]", interval.Lower80, interval.Upper80)
+	}
+}
+
+// fakeSearcher 只支持证券搜索的测试数据源
+type fakeSearcher struct {
+	name    string
+	matches []model.SecurityMatch
+	err     error
+	calls   int
+}
+
+func (f *fakeSearcher) Name() string { return f.name }
+
+func (f *fakeSearcher) FetchQuote(*model.Instrument) (*model.StockData, error) {
+	return nil, fmt.Errorf("不支持")
+}
+
+func (f *fakeSearcher) FetchDailyBars(*model.Instrument, int) ([]model.StockData, error) {
+	return nil, fmt.Errorf("不支持")
+}
+
+func (f *fakeSearcher) HealthCheck() (*model.StockData, error) {
+	return nil, fmt.Errorf("不支持")
+}
+
+func (f *fakeSearcher) SearchSecurities(string) ([]model.SecurityMatch, error) {
+	f.calls++
+	return f.matches, f.err
+}
+
+func TestSearchSecurities(t *testing.T) {
+	registry, err := NewInstrumentRegistry("", nil)
+	if err != nil {
+		t.Fatalf("加载标的注册表失败: %v", err)
+	}
+
+	stock := model.SecurityMatch{Code: "sh600519", Name: "贵州茅台", Symbol: "600519.SS", Type: InstrumentTypeStock}
+	index := model.SecurityMatch{Code: "sh000001", Name: "上证指数", Symbol: "000001.SS", Type: InstrumentTypeIndex}
+	failure := fmt.Errorf("HTTP错误: 502")
+
+	tests := []struct {
+		name      string
+		query     string
+		searchers []*fakeSearcher
+		wantCodes []string
+		wantCalls []int
+		wantErr   bool
+	}{
+		{
+			name:      "第一个数据源失败时使用下一个",
+			query:     "gzmt",
+			searchers: []*fakeSearcher{{name: "a", err: failure}, {name: "b", matches: []model.SecurityMatch{stock}}},
+			wantCodes: []string{"sh600519"},
+			wantCalls: []int{1, 1},
+		},
+		{
+			name:      "第一个数据源成功后不再请求其余数据源",
+			query:     "gzmt",
+			searchers: []*fakeSearcher{{name: "a", matches: []model.SecurityMatch{stock}}, {name: "b", err: failure}},
+			wantCodes: []string{"sh600519"},
+			wantCalls: []int{1, 0},
+		},
+		{
+			name:      "注册表中已有的标的不重复",
+			query:     "szzz",
+			searchers: []*fakeSearcher{{name: "a", matches: []model.SecurityMatch{index, stock}}},
+			wantCodes: []string{"sh000001", "sh600519"},
+			wantCalls: []int{1},
+		},
+		{
+			name:      "所有数据源失败时返回注册表的结果",
+			query:     "szzz",
+			searchers: []*fakeSearcher{{name: "a", err: failure}, {name: "b", err: failure}},
+			wantCodes: []string{"sh000001"},
+			wantCalls: []int{1, 1},
+		},
+		{
+			name:      "所有数据源失败且注册表没有结果时返回错误",
+			query:     "gzmt",
+			searchers: []*fakeSearcher{{name: "a", err: failure}, {name: "b", err: failure}},
+			wantErr:   true,
+			wantCalls: []int{1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providers := make([]MarketDataProvider, len(tt.searchers))
+			for i, searcher := range tt.searchers {
+				providers[i] = searcher
+			}
+			ds := &DataService{
+				registry:   registry,
+				marketData: NewProviderChain(providers, 0, 0, 0, nil, nil),
+			}
+
+			matches, err := ds.SearchSecurities(tt.query)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("期望返回错误，实际得到 %+v", matches)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("搜索失败: %v", err)
+				}
+				var codes []string
+				for _, match := range matches {
+					codes = append(codes, match.Code)
+				}
+				if fmt.Sprint(codes) != fmt.Sprint(tt.wantCodes) {
+					t.Errorf("搜索结果 = %v，期望 %v", codes, tt.wantCodes)
+				}
+				if len(matches) > 0 && matches[0].Code == "sh000001" && !matches[0].Registered {
+					t.Error("注册表中的标的应标记为已注册")
+				}
+			}
+
+			for i, searcher := range tt.searchers {
+				if searcher.calls != tt.wantCalls[i] {
+					t.Errorf("数据源 %s 请求次数 = %d，期望 %d", searcher.name, searcher.calls, tt.wantCalls[i])
+				}
+			}
+		})
+	}
+}